@@ -21,6 +21,17 @@ func TestCanonicalizeCheckpoint(t *testing.T) {
 			// JCS sorts keys lexicographically: anchored_at, root_hash, size
 			expected: `{"anchored_at":"2024-01-01T00:00:00Z","root_hash":"abc123","size":42}`,
 		},
+		{
+			name: "with extensions",
+			input: &CheckpointPayload{
+				RootHash:   "abc123",
+				Size:       42,
+				AnchoredAt: "2024-01-01T00:00:00Z",
+				Extensions: map[string][]byte{"witnesses": []byte("w1"), "key_hash": []byte{0x01, 0x02}},
+			},
+			// extensions sorted between anchored_at and root_hash, extension keys sorted, values base64-encoded
+			expected: `{"anchored_at":"2024-01-01T00:00:00Z","extensions":{"key_hash":"AQI=","witnesses":"dzE="},"root_hash":"abc123","size":42}`,
+		},
 	}
 
 	for _, tt := range tests {
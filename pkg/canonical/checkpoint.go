@@ -1,8 +1,10 @@
 package canonical
 
 // CheckpointPayload is the canonical representation of a ledger checkpoint used as the JWS signing payload.
+// Extensions carry optional named data (e.g. a witness list) that is serialized with the payload and therefore covered by the signature. Verifiers that do not understand an extension can ignore it, since the signature is checked over the canonical bytes as a whole.
 type CheckpointPayload struct {
-	RootHash   string `json:"root_hash"`
-	Size       int64  `json:"size"`
-	AnchoredAt string `json:"anchored_at"`
+	RootHash   string            `json:"root_hash"`
+	Size       int64             `json:"size"`
+	AnchoredAt string            `json:"anchored_at"`
+	Extensions map[string][]byte `json:"extensions,omitempty"`
 }
@@ -0,0 +1,92 @@
+package canonical
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	pkgjws "github.com/andrlikjirka/dp-teals/pkg/jws"
+)
+
+type staticKeyProvider struct {
+	key ed25519.PublicKey
+}
+
+func (p *staticKeyProvider) PublicKey(ctx context.Context, kid string) (ed25519.PublicKey, error) {
+	return p.key, nil
+}
+
+func signCheckpoint(t *testing.T, p *CheckpointPayload) (string, *pkgjws.Ed25519Verifier) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := pkgjws.NewEd25519Signer(priv, "server-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	canonical, err := CanonicalizeCheckpoint(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	token, err := signer.Sign(canonical)
+	if err != nil {
+		t.Fatalf("sign returned unexpected error: %v", err)
+	}
+	return token, pkgjws.NewEd25519Verifier(&staticKeyProvider{key: pub})
+}
+
+func TestCheckpointExtensions_CoveredBySignature(t *testing.T) {
+	payload := &CheckpointPayload{
+		RootHash:   "abc123",
+		Size:       42,
+		AnchoredAt: "2024-01-01T00:00:00Z",
+		Extensions: map[string][]byte{"witnesses": []byte("w1")},
+	}
+	token, verifier := signCheckpoint(t, payload)
+
+	payload.Extensions["witnesses"] = []byte("w2")
+	tampered, err := CanonicalizeCheckpoint(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := verifier.Verify(context.Background(), token, tampered); err == nil {
+		t.Error("expected signature verification to fail for a modified extension")
+	}
+}
+
+func TestCheckpointExtensions_UnknownIgnoredByCoreVerifier(t *testing.T) {
+	payload := &CheckpointPayload{
+		RootHash:   "abc123",
+		Size:       42,
+		AnchoredAt: "2024-01-01T00:00:00Z",
+		Extensions: map[string][]byte{"future_extension": []byte("opaque")},
+	}
+	token, verifier := signCheckpoint(t, payload)
+
+	// the verifier receives the exact signed bytes and checks the signature over them
+	received, err := CanonicalizeCheckpoint(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := verifier.Verify(context.Background(), token, received); err != nil {
+		t.Fatalf("expected signature verification to succeed, got: %v", err)
+	}
+
+	// a verifier that only knows the core fields still decodes them from the signed bytes
+	var core struct {
+		RootHash string `json:"root_hash"`
+		Size     int64  `json:"size"`
+	}
+	if err := json.Unmarshal(received, &core); err != nil {
+		t.Fatalf("failed to decode core fields: %v", err)
+	}
+	if core.RootHash != payload.RootHash || core.Size != payload.Size {
+		t.Errorf("core fields = (%q, %d), want (%q, %d)", core.RootHash, core.Size, payload.RootHash, payload.Size)
+	}
+}
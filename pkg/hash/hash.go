@@ -1,11 +1,18 @@
 package hash
 
 import (
+	"crypto"
 	"crypto/sha256"
 	"crypto/sha3"
+	"crypto/sha512"
+	"fmt"
+	stdhash "hash"
+
+	"golang.org/x/crypto/blake2b"
 )
 
 // Func defines the type for hash functions used in the Merkle tree.
+// A Func must not modify or retain its input after returning, and the digest it returns must not alias the input: callers such as merkle.HashLeafData reuse the input buffer for later hashes. The functions in this package all return a freshly allocated digest.
 type Func func([]byte) []byte
 
 // DefaultHashFunc uses SHA256.
@@ -13,6 +20,28 @@ func DefaultHashFunc(data []byte) []byte {
 	return SHA256HashFunc(data)
 }
 
+// FromHasher adapts a constructor of incremental hashes, e.g. sha256.New, to a Func. Each call hashes its input with a fresh hash.Hash.
+func FromHasher(newHash func() stdhash.Hash) Func {
+	return func(data []byte) []byte {
+		h := newHash()
+		h.Write(data)
+		return h.Sum(nil)
+	}
+}
+
+// DefaultHasher returns a new incremental hash of the algorithm used by DefaultHashFunc, SHA256, for callers that need to stream their input.
+func DefaultHasher() stdhash.Hash {
+	return sha256.New()
+}
+
+// FromCrypto adapts a hash algorithm of the standard crypto registry, e.g. crypto.SHA256, to a Func. It returns an error if the implementation of the algorithm isn't linked into the binary, which is done by importing its package, e.g. crypto/sha256.
+func FromCrypto(h crypto.Hash) (Func, error) {
+	if !h.Available() {
+		return nil, fmt.Errorf("hash algorithm %v is not linked into the binary", h)
+	}
+	return FromHasher(h.New), nil
+}
+
 // SHA256HashFunc uses SHA256 hash function.
 func SHA256HashFunc(data []byte) []byte {
 	h := sha256.Sum256(data)
@@ -24,3 +53,21 @@ func SHA3HashFunc(data []byte) []byte {
 	h := sha3.Sum256(data)
 	return h[:]
 }
+
+// SHA512HashFunc uses SHA-512 hash function.
+func SHA512HashFunc(data []byte) []byte {
+	h := sha512.Sum512(data)
+	return h[:]
+}
+
+// SHA384HashFunc uses SHA-384 hash function.
+func SHA384HashFunc(data []byte) []byte {
+	h := sha512.Sum384(data)
+	return h[:]
+}
+
+// Blake2b256HashFunc uses BLAKE2b-256 hash function. It is typically faster than SHA256 on large inputs, unless the CPU has SHA extensions.
+func Blake2b256HashFunc(data []byte) []byte {
+	h := blake2b.Sum256(data)
+	return h[:]
+}
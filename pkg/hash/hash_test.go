@@ -2,8 +2,11 @@ package hash
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/sha256"
 	"crypto/sha3"
+	"crypto/sha512"
+	"encoding/hex"
 	"testing"
 )
 
@@ -17,6 +20,16 @@ func sha3Bytes(b []byte) []byte {
 	return s[:]
 }
 
+func sha384Bytes(b []byte) []byte {
+	s := sha512.Sum384(b)
+	return s[:]
+}
+
+func sha512Bytes(b []byte) []byte {
+	s := sha512.Sum512(b)
+	return s[:]
+}
+
 func TestDefaultHashFunc(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -88,3 +101,195 @@ func TestSHA3HashFunc(t *testing.T) {
 		})
 	}
 }
+
+func TestSHA512HashFunc(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected []byte
+	}{
+		{
+			name:     "empty data",
+			data:     []byte{},
+			expected: sha512Bytes([]byte{}),
+		},
+		{
+			name:     "simple string",
+			data:     []byte("hello"),
+			expected: sha512Bytes([]byte("hello")),
+		},
+		{
+			name:     "binary data",
+			data:     []byte{0x00, 0x01, 0x02, 0x03},
+			expected: sha512Bytes([]byte{0x00, 0x01, 0x02, 0x03}),
+		},
+		{
+			name:     "FIPS 180-2 abc vector",
+			data:     []byte("abc"),
+			expected: mustDecodeHex("ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := SHA512HashFunc(tt.data)
+			if !bytes.Equal(result, tt.expected) {
+				t.Errorf("SHA512HashFunc() = %x, want %x", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSHA384HashFunc(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected []byte
+	}{
+		{
+			name:     "empty data",
+			data:     []byte{},
+			expected: sha384Bytes([]byte{}),
+		},
+		{
+			name:     "simple string",
+			data:     []byte("hello"),
+			expected: sha384Bytes([]byte("hello")),
+		},
+		{
+			name:     "binary data",
+			data:     []byte{0x00, 0x01, 0x02, 0x03},
+			expected: sha384Bytes([]byte{0x00, 0x01, 0x02, 0x03}),
+		},
+		{
+			name:     "FIPS 180-2 abc vector",
+			data:     []byte("abc"),
+			expected: mustDecodeHex("cb00753f45a35e8bb5a03d699ac65007272c32ab0eded1631a8b605a43ff5bed8086072ba1e7cc2358baeca134c825a7"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := SHA384HashFunc(tt.data)
+			if !bytes.Equal(result, tt.expected) {
+				t.Errorf("SHA384HashFunc() = %x, want %x", result, tt.expected)
+			}
+		})
+	}
+}
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestBlake2b256HashFunc(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected []byte
+	}{
+		{
+			name:     "empty data",
+			data:     []byte{},
+			expected: mustDecodeHex("0e5751c026e543b2e8ab2eb06099daa1d1e5df47778f7787faab45cdf12fe3a8"),
+		},
+		{
+			name:     "abc vector",
+			data:     []byte("abc"),
+			expected: mustDecodeHex("bddd813c634239723171ef3fee98579b94964e3bb1cb3e427262c8c068d52319"),
+		},
+		{
+			name:     "output length",
+			data:     bytes.Repeat([]byte("x"), 4096),
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Blake2b256HashFunc(tt.data)
+			if len(result) != 32 {
+				t.Fatalf("Blake2b256HashFunc() length = %d, want 32", len(result))
+			}
+			if tt.expected != nil && !bytes.Equal(result, tt.expected) {
+				t.Errorf("Blake2b256HashFunc() = %x, want %x", result, tt.expected)
+			}
+		})
+	}
+}
+
+func BenchmarkHashFunc_4KB(b *testing.B) {
+	leaf := bytes.Repeat([]byte{0xab}, 4096)
+	benchmarks := []struct {
+		name     string
+		hashFunc Func
+	}{
+		{"DefaultHashFunc", DefaultHashFunc},
+		{"Blake2b256HashFunc", Blake2b256HashFunc},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.SetBytes(int64(len(leaf)))
+			for b.Loop() {
+				bm.hashFunc(leaf)
+			}
+		})
+	}
+}
+
+func TestFromHasher(t *testing.T) {
+	fn := FromHasher(sha256.New)
+	for _, input := range [][]byte{nil, []byte("abc"), bytes.Repeat([]byte{0xab}, 4096)} {
+		if got, want := fn(input), SHA256HashFunc(input); !bytes.Equal(got, want) {
+			t.Errorf("FromHasher(sha256.New)(%d bytes) = %x, want %x", len(input), got, want)
+		}
+	}
+	if NameOf(fn) != "sha256" {
+		t.Errorf("NameOf(FromHasher(sha256.New)) = %q, want sha256", NameOf(fn))
+	}
+}
+
+func TestDefaultHasher(t *testing.T) {
+	input := []byte("streamed input")
+	h := DefaultHasher()
+	h.Write(input[:8])
+	h.Write(input[8:])
+	if got, want := h.Sum(nil), DefaultHashFunc(input); !bytes.Equal(got, want) {
+		t.Errorf("DefaultHasher() sum = %x, want %x", got, want)
+	}
+}
+
+func TestFromCrypto(t *testing.T) {
+	tests := []struct {
+		name string
+		hash crypto.Hash
+		want Func
+	}{
+		{"sha256", crypto.SHA256, SHA256HashFunc},
+		{"sha3-256", crypto.SHA3_256, SHA3HashFunc},
+		{"sha384", crypto.SHA384, SHA384HashFunc},
+		{"sha512", crypto.SHA512, SHA512HashFunc},
+		{"blake2b-256", crypto.BLAKE2b_256, Blake2b256HashFunc},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn, err := FromCrypto(tt.hash)
+			if err != nil {
+				t.Fatalf("FromCrypto(%v) unexpected error: %v", tt.hash, err)
+			}
+			if !Same(fn, tt.want) {
+				t.Errorf("FromCrypto(%v) computes different digests than %s", tt.hash, tt.name)
+			}
+		})
+	}
+
+	if _, err := FromCrypto(crypto.MD4); err == nil { // golang.org/x/crypto/md4 isn't imported
+		t.Error("FromCrypto(crypto.MD4) expected error for an algorithm not linked into the binary, got nil")
+	}
+}
@@ -0,0 +1,79 @@
+package hash
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// probeInput is hashed to tell hash functions apart, as Go functions can't be compared directly.
+var probeInput = []byte("dp-teals hash function probe")
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Func{
+		"blake2b-256": Blake2b256HashFunc,
+		"sha256":      SHA256HashFunc,
+		"sha3-256":    SHA3HashFunc,
+		"sha384":      SHA384HashFunc,
+		"sha512":      SHA512HashFunc,
+	}
+)
+
+// Register makes a hash function available under the given name, replacing any function previously registered under it. It is safe for concurrent use and may be called from init functions. It panics if the name is empty or fn is nil. fn must follow the contract of Func and never retain or alias its input.
+func Register(name string, fn Func) {
+	if name == "" {
+		panic("hash: Register called with empty name")
+	}
+	if fn == nil {
+		panic("hash: Register called with nil function for " + name)
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = fn
+}
+
+// ByName returns the hash function registered under the given name, e.g. "sha256", or an error if no such function is registered.
+func ByName(name string) (Func, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	fn, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown hash algorithm %q", name)
+	}
+	return fn, nil
+}
+
+// NameOf returns the name under which a hash function computing the same digests as fn is registered, or an empty string if there is none. If several names match, the alphabetically first one is returned.
+func NameOf(fn Func) string {
+	if fn == nil {
+		return ""
+	}
+	digest := fn(probeInput)
+
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if bytes.Equal(registry[name](probeInput), digest) {
+			return name
+		}
+	}
+	return ""
+}
+
+// Same reports whether the two hash functions compute the same digests, judged by their output on a fixed probe input.
+func Same(a, b Func) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return bytes.Equal(a(probeInput), b(probeInput))
+}
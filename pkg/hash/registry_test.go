@@ -0,0 +1,133 @@
+package hash
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestByName(t *testing.T) {
+	tests := []struct {
+		name      string
+		algo      string
+		expected  []byte
+		expectErr bool
+	}{
+		{"sha256", "sha256", sha256Bytes([]byte("data")), false},
+		{"sha3-256", "sha3-256", sha3Bytes([]byte("data")), false},
+		{"sha384", "sha384", sha384Bytes([]byte("data")), false},
+		{"sha512", "sha512", sha512Bytes([]byte("data")), false},
+		{"blake2b-256", "blake2b-256", Blake2b256HashFunc([]byte("data")), false},
+		{"unknown name", "md5", nil, true},
+		{"empty name", "", nil, true},
+		{"names are case sensitive", "SHA256", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn, err := ByName(tt.algo)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("ByName() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if tt.expectErr {
+				return
+			}
+			if got := fn([]byte("data")); !bytes.Equal(got, tt.expected) {
+				t.Errorf("ByName() func result = %x, want %x", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	custom := func(data []byte) []byte { return append([]byte("custom:"), data...) }
+	Register("test-custom", custom)
+
+	fn, err := ByName("test-custom")
+	if err != nil {
+		t.Fatalf("ByName() unexpected error: %v", err)
+	}
+	if got := fn([]byte("x")); !bytes.Equal(got, []byte("custom:x")) {
+		t.Errorf("ByName() func result = %q, want %q", got, "custom:x")
+	}
+}
+
+func TestRegister_Concurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			Register(fmt.Sprintf("test-concurrent-%d", i), SHA256HashFunc)
+			if _, err := ByName("sha256"); err != nil {
+				t.Errorf("ByName() unexpected error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 16; i++ {
+		if _, err := ByName(fmt.Sprintf("test-concurrent-%d", i)); err != nil {
+			t.Errorf("ByName() unexpected error: %v", err)
+		}
+	}
+}
+
+func TestRegister_Panics(t *testing.T) {
+	tests := []struct {
+		name string
+		algo string
+		fn   Func
+	}{
+		{"empty name", "", SHA256HashFunc},
+		{"nil function", "test-nil", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register() expected panic, got none")
+				}
+			}()
+			Register(tt.algo, tt.fn)
+		})
+	}
+}
+
+func TestNameOf(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   Func
+		want string
+	}{
+		{"default", DefaultHashFunc, "sha256"},
+		{"sha256", SHA256HashFunc, "sha256"},
+		{"sha3-256", SHA3HashFunc, "sha3-256"},
+		{"sha512", SHA512HashFunc, "sha512"},
+		{"blake2b-256", Blake2b256HashFunc, "blake2b-256"},
+		{"unregistered", func(data []byte) []byte { return []byte("unregistered") }, ""},
+		{"nil", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NameOf(tt.fn); got != tt.want {
+				t.Errorf("NameOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSame(t *testing.T) {
+	if !Same(DefaultHashFunc, SHA256HashFunc) {
+		t.Error("Same(DefaultHashFunc, SHA256HashFunc) = false, want true")
+	}
+	if Same(SHA256HashFunc, SHA512HashFunc) {
+		t.Error("Same(SHA256HashFunc, SHA512HashFunc) = true, want false")
+	}
+	if Same(SHA256HashFunc, nil) {
+		t.Error("Same(SHA256HashFunc, nil) = true, want false")
+	}
+}
@@ -1,37 +1,114 @@
 package logger
 
 import (
+	"context"
+	"io"
 	"log/slog"
 	"os"
+	"runtime"
 )
 
 // Logger is a wrapper around slog.Logger that provides additional functionality
 type Logger struct {
 	*slog.Logger
+	addSource bool // the handler already adds the source, see Options.AddSource
 }
 
-// New creates a new Logger instance based on the environment.
-func New(env string) *Logger {
-	var handler slog.Handler
+// Options configures a Logger created by NewWithOptions.
+type Options struct {
+	Level     slog.Level // Minimum level of emitted records
+	JSON      bool       // Emit JSON instead of text records
+	Output    io.Writer  // Destination of the records, os.Stdout if nil
+	AddSource bool       // Add the caller's source location as the "source" attribute
+}
 
+// New creates a new Logger instance based on the environment: JSON records at info level in production, text records at debug level otherwise.
+func New(env string) *Logger {
 	if env == "production" {
-		handler = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-			Level: slog.LevelInfo,
-		})
+		return NewWithOptions(Options{Level: slog.LevelInfo, JSON: true})
+	}
+	return NewWithOptions(Options{Level: slog.LevelDebug})
+}
+
+// NewWithOptions creates a new Logger with the given level, format and output.
+func NewWithOptions(opts Options) *Logger {
+	out := opts.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	handlerOpts := &slog.HandlerOptions{Level: opts.Level, AddSource: opts.AddSource}
+
+	var handler slog.Handler
+	if opts.JSON {
+		handler = slog.NewJSONHandler(out, handlerOpts)
 	} else {
-		handler = slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-			Level: slog.LevelDebug,
-		})
+		handler = slog.NewTextHandler(out, handlerOpts)
 	}
 
 	return &Logger{
-		Logger: slog.New(handler),
+		Logger:    slog.New(handler),
+		addSource: opts.AddSource,
 	}
 }
 
 // WithComponent adds a "component" field to the logger for better context in logs.
 func (l *Logger) WithComponent(name string) *Logger {
 	return &Logger{
-		Logger: l.Logger.With(slog.String("component", name)),
+		Logger:    l.Logger.With(slog.String("component", name)),
+		addSource: l.addSource,
+	}
+}
+
+// WithError adds an "error" field with the error message to the logger. A nil error adds no field.
+func (l *Logger) WithError(err error) *Logger {
+	if err == nil {
+		return l
+	}
+	return &Logger{
+		Logger:    l.Logger.With(slog.String("error", err.Error())),
+		addSource: l.addSource,
 	}
 }
+
+// WithRequestID adds a "request_id" field to the logger to correlate the logs of a single request.
+func (l *Logger) WithRequestID(id string) *Logger {
+	return &Logger{
+		Logger:    l.Logger.With(slog.String("request_id", id)),
+		addSource: l.addSource,
+	}
+}
+
+// WithSource returns a logger that adds the caller's source location as the "source" attribute to its records, like Options.AddSource, e.g. to enable it for a single component while debugging. The original logger is not modified. Attributes added to the logger with a group before are kept, but the source is then nested in that group. Loggers that already add the source, created with Options.AddSource or by WithSource, are returned unchanged, so records don't get a second "source" attribute.
+func (l *Logger) WithSource() *Logger {
+	if _, ok := l.Handler().(sourceHandler); ok || l.addSource {
+		return l
+	}
+	return &Logger{
+		Logger:    slog.New(sourceHandler{l.Handler()}),
+		addSource: true,
+	}
+}
+
+// sourceHandler adds the source location of the record's caller to every record before passing it on.
+type sourceHandler struct {
+	slog.Handler
+}
+
+// Handle adds the source attribute to the record, unless its caller is unknown, and passes it to the wrapped handler.
+func (h sourceHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		r.AddAttrs(slog.Any(slog.SourceKey, &slog.Source{Function: frame.Function, File: frame.File, Line: frame.Line}))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs returns a sourceHandler wrapping the wrapped handler with the attributes added, so derived loggers keep adding the source.
+func (h sourceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return sourceHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup returns a sourceHandler wrapping the wrapped handler with the group started, so derived loggers keep adding the source.
+func (h sourceHandler) WithGroup(name string) slog.Handler {
+	return sourceHandler{h.Handler.WithGroup(name)}
+}
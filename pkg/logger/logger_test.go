@@ -0,0 +1,144 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewWithOptions_LevelFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		level     slog.Level
+		wantDebug bool
+		wantInfo  bool
+	}{
+		{"debug", slog.LevelDebug, true, true},
+		{"info", slog.LevelInfo, false, true},
+		{"error", slog.LevelError, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := NewWithOptions(Options{Level: tt.level, Output: &buf})
+
+			log.Debug("debug message")
+			log.Info("info message")
+
+			if got := strings.Contains(buf.String(), "debug message"); got != tt.wantDebug {
+				t.Errorf("debug record emitted = %v, want %v", got, tt.wantDebug)
+			}
+			if got := strings.Contains(buf.String(), "info message"); got != tt.wantInfo {
+				t.Errorf("info record emitted = %v, want %v", got, tt.wantInfo)
+			}
+		})
+	}
+}
+
+func TestNewWithOptions_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewWithOptions(Options{Level: slog.LevelDebug, JSON: true, Output: &buf})
+
+	log.Debug("hello", slog.Int("n", 1))
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("record %q is not JSON: %v", buf.String(), err)
+	}
+	if record["level"] != "DEBUG" || record["msg"] != "hello" || record["n"] != float64(1) {
+		t.Errorf("record = %v, want level DEBUG, msg hello and n 1", record)
+	}
+}
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name       string
+		newLogger  func(buf *bytes.Buffer) *Logger
+		wantSource bool
+	}{
+		{"disabled", func(buf *bytes.Buffer) *Logger {
+			return NewWithOptions(Options{JSON: true, Output: buf})
+		}, false},
+		{"option", func(buf *bytes.Buffer) *Logger {
+			return NewWithOptions(Options{JSON: true, Output: buf, AddSource: true})
+		}, true},
+		{"WithSource", func(buf *bytes.Buffer) *Logger {
+			return NewWithOptions(Options{JSON: true, Output: buf}).WithSource().WithComponent("test")
+		}, true},
+		{"WithSource twice", func(buf *bytes.Buffer) *Logger {
+			return NewWithOptions(Options{JSON: true, Output: buf}).WithSource().WithSource()
+		}, true},
+		{"WithSource with option", func(buf *bytes.Buffer) *Logger {
+			return NewWithOptions(Options{JSON: true, Output: buf, AddSource: true}).WithComponent("test").WithSource()
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.newLogger(&buf).Info("hello")
+
+			var record map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("record %q is not a single JSON object: %v", buf.String(), err)
+			}
+			if n := strings.Count(buf.String(), `"source":`); n > 1 {
+				t.Errorf("record has %d source attributes, want at most 1: %s", n, buf.String())
+			}
+			source, ok := record["source"].(map[string]any)
+			if ok != tt.wantSource {
+				t.Fatalf("source attribute present = %v, want %v in %v", ok, tt.wantSource, record)
+			}
+			if ok && !strings.HasSuffix(source["file"].(string), "logger_test.go") {
+				t.Errorf("source file = %v, want logger_test.go", source["file"])
+			}
+		})
+	}
+}
+
+func TestWithFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		with  func(l *Logger) *Logger
+		key   string
+		value any
+	}{
+		{"component", func(l *Logger) *Logger { return l.WithComponent("ingestion") }, "component", "ingestion"},
+		{"error", func(l *Logger) *Logger { return l.WithError(errors.New("boom")) }, "error", "boom"},
+		{"nil error", func(l *Logger) *Logger { return l.WithError(nil) }, "error", nil},
+		{"request id", func(l *Logger) *Logger { return l.WithRequestID("req-1") }, "request_id", "req-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			base := NewWithOptions(Options{JSON: true, Output: &buf})
+
+			tt.with(base).Info("derived")
+			base.Info("base")
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("got %d records, want 2", len(lines))
+			}
+			var derived, original map[string]any
+			if err := json.Unmarshal([]byte(lines[0]), &derived); err != nil {
+				t.Fatalf("record %q is not JSON: %v", lines[0], err)
+			}
+			if err := json.Unmarshal([]byte(lines[1]), &original); err != nil {
+				t.Fatalf("record %q is not JSON: %v", lines[1], err)
+			}
+
+			if derived[tt.key] != tt.value {
+				t.Errorf("%s = %v, want %v", tt.key, derived[tt.key], tt.value)
+			}
+			if _, ok := original[tt.key]; ok {
+				t.Errorf("original logger has the %s field, want it unmodified", tt.key)
+			}
+		})
+	}
+}
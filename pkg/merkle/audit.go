@@ -0,0 +1,70 @@
+package merkle
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+// VerifyFullLog rebuilds the root hash from all leaf hashes of a log and compares it to the claimed root, without trusting any internal structure provided by the server.
+// The leaves are split into chunks whose size is a power of two, so every chunk is a subtree of the RFC 6962 tree. Chunk roots are computed in parallel by the given number of workers (runtime.NumCPU() if workers < 1) and then combined into the root.
+func VerifyFullLog(leafHashes [][]byte, claimedRoot []byte, hashFunc hash.Func, workers int) error {
+	n := len(leafHashes)
+	if n == 0 {
+		return errors.New("no leaf hashes provided")
+	}
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	if hashFunc == nil {
+		hashFunc = hash.DefaultHashFunc
+	}
+
+	// pick the chunk size as a power of two giving every worker a few chunks to balance the load
+	chunkSize := 1
+	for chunkSize*workers*4 < n {
+		chunkSize <<= 1
+	}
+	chunks := (n + chunkSize - 1) / chunkSize
+
+	chunkRoots := make([][]byte, chunks)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range jobs {
+				end := min((c+1)*chunkSize, n)
+				chunkRoots[c] = rootFromLeafHashes(leafHashes[c*chunkSize:end], hashFunc, DomainParams{})
+			}
+		}()
+	}
+	for c := 0; c < chunks; c++ {
+		jobs <- c
+	}
+	close(jobs)
+	wg.Wait()
+
+	// the chunks split the leaves at the same power-of-two boundaries the RFC 6962 tree uses, so their roots combine the same way the leaves do
+	root := rootFromLeafHashes(chunkRoots, hashFunc, DomainParams{})
+	if !RootsEqual(root, claimedRoot) {
+		return errors.New("computed root does not match claimed root")
+	}
+	return nil
+}
+
+// rootFromLeafHashes computes the RFC 6962 root hash over the given hashes without materializing tree nodes.
+func rootFromLeafHashes(hashes [][]byte, hashFunc hash.Func, domain DomainParams) []byte {
+	n := len(hashes)
+	if n == 1 {
+		return hashes[0]
+	}
+
+	k := largestPowerOfTwoLessThan(n)
+	left := rootFromLeafHashes(hashes[:k], hashFunc, domain)
+	right := rootFromLeafHashes(hashes[k:], hashFunc, domain)
+	return domain.HashNode(left, right, hashFunc)
+}
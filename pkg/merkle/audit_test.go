@@ -0,0 +1,68 @@
+package merkle
+
+import (
+	"fmt"
+	"testing"
+)
+
+func buildTestTree(tb testing.TB, n int) *Tree {
+	tb.Helper()
+	data := make([][]byte, n)
+	for i := range data {
+		data[i] = []byte(fmt.Sprintf("leaf%d", i))
+	}
+	tree, err := NewTree(data, nil)
+	if err != nil {
+		tb.Fatalf("Failed to create tree: %v", err)
+	}
+	return tree
+}
+
+func leafHashesOf(tree *Tree) [][]byte {
+	return tree.LeafHashes()
+}
+
+func TestVerifyFullLog(t *testing.T) {
+	for _, n := range []int{1, 2, 7, 13, 64, 1000} {
+		for _, workers := range []int{0, 1, 3, 8} {
+			t.Run(fmt.Sprintf("%d leaves, %d workers", n, workers), func(t *testing.T) {
+				tree := buildTestTree(t, n)
+				if err := VerifyFullLog(leafHashesOf(tree), tree.RootHash(), nil, workers); err != nil {
+					t.Errorf("VerifyFullLog() unexpected error: %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestVerifyFullLog_TamperedLeaf(t *testing.T) {
+	tree := buildTestTree(t, 1000)
+	hashes := leafHashesOf(tree)
+	hashes[517][0] ^= 0xFF
+
+	if err := VerifyFullLog(hashes, tree.RootHash(), nil, 4); err == nil {
+		t.Error("VerifyFullLog() expected error for tampered leaf, got nil")
+	}
+}
+
+func TestVerifyFullLog_Empty(t *testing.T) {
+	if err := VerifyFullLog(nil, []byte("root"), nil, 4); err == nil {
+		t.Error("VerifyFullLog() expected error for empty leaf set, got nil")
+	}
+}
+
+func BenchmarkVerifyFullLog(b *testing.B) {
+	tree := buildTestTree(b, 100_000)
+	hashes := leafHashesOf(tree)
+	root := tree.RootHash()
+
+	for _, workers := range []int{1, 4, 0} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for b.Loop() {
+				if err := VerifyFullLog(hashes, root, nil, workers); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package merkle
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+// BatchProof proves the inclusion of several leaves at once. Instead of a sibling path per leaf it carries the hashes of the maximal subtrees that contain none of the proven leaves, each exactly once, so siblings shared between the paths aren't repeated.
+type BatchProof struct {
+	LeafIndices []int    // Indices of the proven leaves, ascending and without duplicates
+	TreeSize    int      // Number of leaves in the tree the proof was generated for
+	Hashes      [][]byte // Hashes of the subtrees without proven leaves, in left-to-right order
+}
+
+// GenerateBatchInclusionProof generates a single inclusion proof for the leaves at the given indices. The indices are sorted and deduplicated, the resulting order is recorded in the proof's LeafIndices.
+func (t *Tree) GenerateBatchInclusionProof(indices []int) (*BatchProof, error) {
+	if len(indices) == 0 {
+		return nil, errors.New("no indices provided")
+	}
+
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	sorted := slices.Clone(indices)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+	if sorted[0] < 0 || sorted[len(sorted)-1] >= len(t.leaves) {
+		return nil, errors.New("invalid index")
+	}
+
+	var hashes [][]byte
+	t.batchPath(sorted, 0, len(t.leaves), &hashes)
+
+	return &BatchProof{LeafIndices: sorted, TreeSize: len(t.leaves), Hashes: hashes}, nil
+}
+
+// batchPath recursively collects the hashes of the subtrees without any of the given indices within the subtree over n leaves starting at start, in left-to-right order.
+func (t *Tree) batchPath(indices []int, start int, n int, hashes *[][]byte) {
+	if len(indices) == 0 { // no proven leaf below, the verifier needs the whole subtree hash
+		*hashes = append(*hashes, t.historicSubtreeHash(start, n))
+		return
+	}
+	if n == 1 { // a proven leaf, the verifier hashes it from the leaf data
+		return
+	}
+
+	k := largestPowerOfTwoLessThan(n)
+	split, _ := slices.BinarySearch(indices, start+k)
+	t.batchPath(indices[:split], start, k, hashes)
+	t.batchPath(indices[split:], start+k, n-k, hashes)
+}
+
+// VerifyBatchInclusionProof verifies that the provided leaf data is included in the Merkle Tree with the given root hash using the provided batch proof. The leaf data must be ordered like the proof's LeafIndices.
+func VerifyBatchInclusionProof(leafData [][]byte, proof *BatchProof, rootHash []byte, hashFunc hash.Func) bool {
+	if proof == nil || len(leafData) == 0 || len(leafData) != len(proof.LeafIndices) || len(rootHash) == 0 {
+		return false
+	}
+	if proof.TreeSize <= 0 {
+		return false
+	}
+	for i, index := range proof.LeafIndices { // indices must be strictly ascending and within the tree
+		if index < 0 || index >= proof.TreeSize || (i > 0 && index <= proof.LeafIndices[i-1]) {
+			return false
+		}
+	}
+
+	if hashFunc == nil {
+		hashFunc = hash.DefaultHashFunc
+	}
+
+	leafHashes := make([][]byte, len(leafData))
+	for i, d := range leafData {
+		leafHashes[i] = HashLeafData(d, hashFunc)
+	}
+
+	remaining := proof.Hashes
+	root, ok := batchRoot(proof.LeafIndices, leafHashes, 0, proof.TreeSize, &remaining, hashFunc)
+	if !ok || len(remaining) != 0 { // every proof hash must be consumed exactly once
+		return false
+	}
+	return RootsEqual(root, rootHash)
+}
+
+// batchRoot recomputes the hash of the subtree over n leaves starting at start from the proven leaf hashes, consuming the proof hashes for the subtrees without proven leaves. It reports false if the proof runs out of hashes.
+func batchRoot(indices []int, leafHashes [][]byte, start int, n int, hashes *[][]byte, hashFunc hash.Func) ([]byte, bool) {
+	if len(indices) == 0 {
+		if len(*hashes) == 0 {
+			return nil, false
+		}
+		h := (*hashes)[0]
+		*hashes = (*hashes)[1:]
+		return h, true
+	}
+	if n == 1 {
+		return leafHashes[0], true
+	}
+
+	k := largestPowerOfTwoLessThan(n)
+	split, _ := slices.BinarySearch(indices, start+k)
+	left, ok := batchRoot(indices[:split], leafHashes[:split], start, k, hashes, hashFunc)
+	if !ok {
+		return nil, false
+	}
+	right, ok := batchRoot(indices[split:], leafHashes[split:], start+k, n-k, hashes, hashFunc)
+	if !ok {
+		return nil, false
+	}
+	return HashInternalNodes(left, right, hashFunc), true
+}
@@ -0,0 +1,113 @@
+package merkle
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBatchInclusionProof(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		indices []int
+	}{
+		{"single leaf", 7, []int{3}},
+		{"all leaves", 5, []int{0, 1, 2, 3, 4}},
+		{"adjacent leaves", 16, []int{4, 5, 6, 7}},
+		{"scattered leaves", 13, []int{0, 6, 12}},
+		{"unsorted with duplicates", 10, []int{9, 2, 2, 5}},
+		{"single leaf tree", 1, []int{0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := buildTestTree(t, tt.size)
+
+			proof, err := tree.GenerateBatchInclusionProof(tt.indices)
+			if err != nil {
+				t.Fatalf("GenerateBatchInclusionProof() unexpected error: %v", err)
+			}
+
+			leafData := make([][]byte, len(proof.LeafIndices))
+			for i, index := range proof.LeafIndices {
+				leafData[i] = []byte(fmt.Sprintf("leaf%d", index))
+			}
+			if !VerifyBatchInclusionProof(leafData, proof, tree.RootHash(), nil) {
+				t.Errorf("VerifyBatchInclusionProof() = false, want true")
+			}
+
+			leafData[0] = []byte("tampered")
+			if VerifyBatchInclusionProof(leafData, proof, tree.RootHash(), nil) {
+				t.Errorf("VerifyBatchInclusionProof() with tampered leaf = true, want false")
+			}
+		})
+	}
+}
+
+func TestBatchInclusionProof_SmallerThanIndividualProofs(t *testing.T) {
+	tree := buildTestTree(t, 16)
+	indices := []int{4, 5, 6, 7}
+
+	individual := 0
+	for _, index := range indices {
+		proof, _ := tree.GenerateInclusionProof(index)
+		individual += len(proof.Siblings)
+	}
+
+	batch, err := tree.GenerateBatchInclusionProof(indices)
+	if err != nil {
+		t.Fatalf("GenerateBatchInclusionProof() unexpected error: %v", err)
+	}
+	if len(batch.Hashes)*4 > individual {
+		t.Errorf("batch proof has %d hashes, individual proofs have %d in total", len(batch.Hashes), individual)
+	}
+}
+
+func TestGenerateBatchInclusionProof_Errors(t *testing.T) {
+	tree := buildTestTree(t, 4)
+
+	tests := []struct {
+		name    string
+		indices []int
+	}{
+		{"no indices", nil},
+		{"negative index", []int{-1, 2}},
+		{"index out of range", []int{1, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tree.GenerateBatchInclusionProof(tt.indices); err == nil {
+				t.Errorf("GenerateBatchInclusionProof() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestVerifyBatchInclusionProof_Malformed(t *testing.T) {
+	tree := buildTestTree(t, 8)
+	proof, _ := tree.GenerateBatchInclusionProof([]int{1, 2})
+	leafData := [][]byte{[]byte("leaf1"), []byte("leaf2")}
+
+	tests := []struct {
+		name     string
+		leafData [][]byte
+		proof    *BatchProof
+	}{
+		{"nil proof", leafData, nil},
+		{"leaf count mismatch", leafData[:1], proof},
+		{"missing hash", leafData, &BatchProof{LeafIndices: proof.LeafIndices, TreeSize: 8, Hashes: proof.Hashes[1:]}},
+		{"extra hash", leafData, &BatchProof{LeafIndices: proof.LeafIndices, TreeSize: 8, Hashes: append(proof.Hashes[:len(proof.Hashes):len(proof.Hashes)], make([]byte, 32))}},
+		{"unsorted indices", [][]byte{[]byte("leaf2"), []byte("leaf1")}, &BatchProof{LeafIndices: []int{2, 1}, TreeSize: 8, Hashes: proof.Hashes}},
+		{"index out of range", leafData, &BatchProof{LeafIndices: []int{1, 8}, TreeSize: 8, Hashes: proof.Hashes}},
+		{"wrong tree size", leafData, &BatchProof{LeafIndices: proof.LeafIndices, TreeSize: 3, Hashes: proof.Hashes}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if VerifyBatchInclusionProof(tt.leafData, tt.proof, tree.RootHash(), nil) {
+				t.Errorf("VerifyBatchInclusionProof() = true, want false")
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package merkle
+
+import (
+	"encoding/hex"
+	"sync"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+// maxCachedLeaves bounds the number of verified leaves a CachedVerifier remembers before it starts over.
+const maxCachedLeaves = 4096
+
+// CachedVerifier verifies inclusion proofs against a stable root and remembers the leaves it has already verified, so repeated checks of the same entry skip the path computation. It tracks a single root: verifying against a different root drops the cache. Only successful verifications are cached, as a failing proof says nothing about other proofs for the same leaf.
+type CachedVerifier struct {
+	hashFunc hash.Func
+	hashName string // registered name of hashFunc, to check the proof's hash algorithm without probing the registry
+	root     string // hex of the tracked root hash
+	verified map[verifiedLeaf]struct{}
+	lock     sync.Mutex
+}
+
+// verifiedLeaf identifies a successfully verified proof in the cache of a CachedVerifier, by the leaf and the position the proof claims for it.
+type verifiedLeaf struct {
+	leafHash  string // hex of the leaf hash
+	leafIndex int
+	treeSize  int
+}
+
+// NewCachedVerifier creates a CachedVerifier using the given hash function, or the default one if nil.
+func NewCachedVerifier(hashFunc hash.Func) *CachedVerifier {
+	if hashFunc == nil {
+		hashFunc = hash.DefaultHashFunc
+	}
+	return &CachedVerifier{
+		hashFunc: hashFunc,
+		hashName: hash.NameOf(hashFunc),
+		verified: make(map[verifiedLeaf]struct{}),
+	}
+}
+
+// Verify verifies the inclusion proof like VerifyInclusionProof. If the leaf was already verified against the same root with a proof for the same leaf index and tree size, only the leaf hash is computed and the cached result is returned. The proof's hash algorithm is checked either way.
+func (v *CachedVerifier) Verify(leafData []byte, proof *InclusionProof, root []byte) bool {
+	if proof == nil || len(leafData) == 0 || len(root) == 0 {
+		return false
+	}
+	if proof.HashAlgorithm != v.hashName && checkHashAlgorithm(proof.HashAlgorithm, v.hashFunc) != nil {
+		return false
+	}
+
+	leafKey := verifiedLeaf{hex.EncodeToString(HashLeafData(leafData, v.hashFunc)), proof.LeafIndex, proof.TreeSize}
+	rootKey := hex.EncodeToString(root)
+
+	v.lock.Lock()
+	if v.root != rootKey { // the tracked root changed, results for the old one no longer apply
+		v.root = rootKey
+		clear(v.verified)
+	}
+	_, hit := v.verified[leafKey]
+	v.lock.Unlock()
+
+	if hit {
+		return true
+	}
+	if !VerifyInclusionProof(leafData, proof, root, v.hashFunc) {
+		return false
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if v.root == rootKey { // don't cache if another goroutine switched the root meanwhile
+		if len(v.verified) >= maxCachedLeaves {
+			clear(v.verified)
+		}
+		v.verified[leafKey] = struct{}{}
+	}
+	return true
+}
@@ -0,0 +1,151 @@
+package merkle
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+// countingHashFunc wraps the default hash function and counts its invocations.
+func countingHashFunc(calls *atomic.Int64) hash.Func {
+	return func(data []byte) []byte {
+		calls.Add(1)
+		return hash.DefaultHashFunc(data)
+	}
+}
+
+func TestCachedVerifier_Hit(t *testing.T) {
+	tree := buildTestTree(t, 16)
+	proof, _ := tree.GenerateInclusionProof(5)
+	leafData := []byte("leaf5")
+
+	var calls atomic.Int64
+	v := NewCachedVerifier(countingHashFunc(&calls))
+
+	if !v.Verify(leafData, proof, tree.RootHash()) {
+		t.Fatalf("Verify() = false, want true")
+	}
+	first := calls.Load()
+	if first < int64(1+len(proof.Siblings))+1 { // leaf hash for the cache key, then the full path
+		t.Errorf("first Verify() hash calls = %d, want at least %d", first, 1+len(proof.Siblings)+1)
+	}
+
+	if !v.Verify(leafData, proof, tree.RootHash()) {
+		t.Fatalf("cached Verify() = false, want true")
+	}
+	if got := calls.Load() - first; got != 1 { // only the leaf hash for the cache key
+		t.Errorf("cached Verify() hash calls = %d, want 1", got)
+	}
+}
+
+func TestCachedVerifier_RootChangeInvalidates(t *testing.T) {
+	tree := buildTestTree(t, 6)
+	oldRoot := tree.RootHash()
+	proof, _ := tree.GenerateInclusionProof(2)
+	leafData := []byte("leaf2")
+
+	var calls atomic.Int64
+	v := NewCachedVerifier(countingHashFunc(&calls))
+	if !v.Verify(leafData, proof, oldRoot) {
+		t.Fatalf("Verify() = false, want true")
+	}
+
+	if err := tree.Append([]byte("leaf6")); err != nil {
+		t.Fatalf("Append() unexpected error: %v", err)
+	}
+	if v.Verify(leafData, proof, tree.RootHash()) {
+		t.Errorf("Verify() with stale proof against new root = true, want false")
+	}
+
+	newProof, _ := tree.GenerateInclusionProof(2)
+	if !v.Verify(leafData, newProof, tree.RootHash()) {
+		t.Errorf("Verify() with new proof = false, want true")
+	}
+
+	before := calls.Load()
+	if !v.Verify(leafData, proof, oldRoot) {
+		t.Errorf("Verify() against old root = false, want true")
+	}
+	if got := calls.Load() - before; got == 1 {
+		t.Errorf("Verify() against old root was served from the cache after the root changed")
+	}
+}
+
+func TestCachedVerifier_FailuresNotCached(t *testing.T) {
+	tree := buildTestTree(t, 8)
+	proof, _ := tree.GenerateInclusionProof(3)
+	wrong, _ := tree.GenerateInclusionProof(4)
+	leafData := []byte("leaf3")
+
+	v := NewCachedVerifier(nil)
+	if v.Verify(leafData, wrong, tree.RootHash()) {
+		t.Fatalf("Verify() with wrong proof = true, want false")
+	}
+	if !v.Verify(leafData, proof, tree.RootHash()) {
+		t.Errorf("Verify() after failed attempt = false, want true")
+	}
+	if v.Verify([]byte("not a leaf"), proof, tree.RootHash()) {
+		t.Errorf("Verify() with unknown leaf = true, want false")
+	}
+}
+
+func TestCachedVerifier_Concurrent(t *testing.T) {
+	tree := buildTestTree(t, 32)
+	v := NewCachedVerifier(nil)
+
+	done := make(chan struct{})
+	for g := 0; g < 8; g++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for i := 0; i < 32; i++ {
+				proof, _ := tree.GenerateInclusionProof(i)
+				if !v.Verify([]byte(fmt.Sprintf("leaf%d", i)), proof, tree.RootHash()) {
+					t.Errorf("Verify() for leaf %d = false, want true", i)
+				}
+			}
+		}()
+	}
+	for g := 0; g < 8; g++ {
+		<-done
+	}
+}
+
+func TestCachedVerifier_HitChecksProof(t *testing.T) {
+	tree := buildTestTree(t, 8)
+	proof, _ := tree.GenerateInclusionProof(3)
+	leafData := []byte("leaf3")
+
+	var calls atomic.Int64
+	v := NewCachedVerifier(countingHashFunc(&calls))
+	if !v.Verify(leafData, proof, tree.RootHash()) {
+		t.Fatalf("Verify() = false, want true")
+	}
+
+	otherAlgorithm := *proof
+	otherAlgorithm.HashAlgorithm = "sha512"
+	tests := []struct {
+		name  string
+		proof *InclusionProof
+	}{
+		{"nil proof", nil},
+		{"different hash algorithm", &otherAlgorithm},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if v.Verify(leafData, tt.proof, tree.RootHash()) {
+				t.Error("Verify() after a cached success = true, want false")
+			}
+		})
+	}
+
+	// the cache is keyed by the claimed position, so a proof claiming another one takes the full path
+	otherPosition := *proof
+	otherPosition.LeafIndex, otherPosition.TreeSize = 5, 9
+	before := calls.Load()
+	v.Verify(leafData, &otherPosition, tree.RootHash())
+	if got := calls.Load() - before; got == 1 {
+		t.Error("Verify() with a proof for another leaf index and tree size was served from the cache")
+	}
+}
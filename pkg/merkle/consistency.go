@@ -2,55 +2,140 @@ package merkle
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
 	"errors"
+	"fmt"
 	"math/bits"
 
 	"github.com/andrlikjirka/dp-teals/pkg/hash"
 )
 
 type ConsistencyProof struct {
-	Hashes [][]byte // Hashes of the nodes needed to verify consistency
+	HashAlgorithm string   // Registered name of the tree's hash function, see hash.ByName; empty if unknown
+	Hashes        [][]byte // Hashes of the nodes needed to verify consistency
+}
+
+// MarshalBinary encodes the proof in a compact length-prefixed format: a uvarint format version (ProofFormatVersion) and a uvarint hash count, followed by each hash prefixed by its uvarint length. The hash algorithm isn't encoded.
+func (p *ConsistencyProof) MarshalBinary() ([]byte, error) {
+	buf := binary.AppendUvarint(nil, ProofFormatVersion)
+	buf = binary.AppendUvarint(buf, uint64(len(p.Hashes)))
+	for _, h := range p.Hashes {
+		buf = binary.AppendUvarint(buf, uint64(len(h)))
+		buf = append(buf, h...)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a proof produced by MarshalBinary. It rejects truncated input, input with trailing bytes, and input of an unknown format version with ErrUnsupportedProofVersion.
+func (p *ConsistencyProof) UnmarshalBinary(data []byte) error {
+	version, n := binary.Uvarint(data)
+	if n <= 0 {
+		return errors.New("invalid consistency proof: truncated format version")
+	}
+	if version != ProofFormatVersion {
+		return fmt.Errorf("invalid consistency proof: %w %d", ErrUnsupportedProofVersion, version)
+	}
+	data = data[n:]
+
+	count, n := binary.Uvarint(data)
+	if n <= 0 {
+		return errors.New("invalid consistency proof: truncated hash count")
+	}
+	data = data[n:]
+	if count > uint64(len(data)) { // every hash takes at least one byte for its length
+		return fmt.Errorf("invalid consistency proof: hash count %d exceeds input size", count)
+	}
+
+	hashes := make([][]byte, 0, count)
+	for i := uint64(0); i < count; i++ {
+		size, n := binary.Uvarint(data)
+		if n <= 0 {
+			return fmt.Errorf("invalid consistency proof: truncated length of hash %d", i)
+		}
+		data = data[n:]
+		if size > uint64(len(data)) {
+			return fmt.Errorf("invalid consistency proof: truncated hash %d", i)
+		}
+		hashes = append(hashes, bytes.Clone(data[:size]))
+		data = data[size:]
+	}
+	if len(data) != 0 {
+		return fmt.Errorf("invalid consistency proof: %d trailing bytes", len(data))
+	}
+
+	p.Hashes = hashes
+	return nil
 }
 
 // GenerateConsistencyProof generates a consistency proof for the first m leaves of the tree. It returns an error if m is invalid.
 func (t *Tree) GenerateConsistencyProof(m int) (*ConsistencyProof, error) {
+	return t.GenerateConsistencyProofContext(context.Background(), m)
+}
+
+// GenerateConsistencyProofContext generates a consistency proof like GenerateConsistencyProof, but checks the context at each level of the recursion and returns its error once it is cancelled, so a stuck request on a very large tree doesn't hold the read lock indefinitely.
+func (t *Tree) GenerateConsistencyProofContext(ctx context.Context, m int) (*ConsistencyProof, error) {
 	t.lock.RLock()
 	defer t.lock.RUnlock()
 
-	n := len(t.Leaves)
+	n := len(t.leaves)
 	if m <= 0 || m > n {
 		return nil, errors.New("invalid m: must be between 1 and the number of leaves")
 	}
-	hashes := t.subProofRecursively(m, 0, n, true)
-	return &ConsistencyProof{Hashes: hashes}, nil
+	hashes, err := t.subProofRecursively(ctx, m, 0, n, true)
+	if err != nil {
+		return nil, err
+	}
+	return &ConsistencyProof{HashAlgorithm: t.hashName, Hashes: hashes}, nil
 }
 
-// subProofRecursively generates the consistency proof recursively. It returns the hashes needed to verify that the first m leaves are consistent with the full tree.
-func (t *Tree) subProofRecursively(m int, start int, n int, b bool) [][]byte {
+// subProofRecursively generates the consistency proof recursively. It returns the hashes needed to verify that the first m leaves are consistent with the full tree, or the context error if the context is cancelled.
+func (t *Tree) subProofRecursively(ctx context.Context, m int, start int, n int, b bool) ([][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if m == n {
 		if b {
-			return [][]byte{}
+			return [][]byte{}, nil
 		}
-		return [][]byte{t.subtreeHash(start, n)}
+		return [][]byte{t.subtreeHash(start, n)}, nil
 	}
 
 	k := largestPowerOfTwoLessThan(n)
 	if m <= k {
-		proof := t.subProofRecursively(m, start, k, b)
+		proof, err := t.subProofRecursively(ctx, m, start, k, b)
+		if err != nil {
+			return nil, err
+		}
 		rightHash := t.subtreeHash(start+k, n-k)
-		return append(proof, rightHash)
+		return append(proof, rightHash), nil
+	}
+	proof, err := t.subProofRecursively(ctx, m-k, start+k, n-k, false)
+	if err != nil {
+		return nil, err
 	}
-	proof := t.subProofRecursively(m-k, start+k, n-k, false)
 	leftHash := t.subtreeHash(start, k)
-	return append(proof, leftHash)
+	return append(proof, leftHash), nil
 }
 
 func (t *Tree) subtreeHash(start int, n int) []byte {
 	if n == 1 { // if it's a leaf, return its hash directly
-		return t.Leaves[start].Hash
+		return t.leaves[start].Hash
 	}
 
-	return t.findHashTopDown(t.root, 0, len(t.Leaves), start, n) // if the subtree is not a leaf, we need to find its root hash by navigating the tree
+	return t.findHashTopDown(t.root, 0, len(t.leaves), start, n) // if the subtree is not a leaf, we need to find its root hash by navigating the tree
+}
+
+// historicSubtreeHash returns the hash of the RFC 6962 subtree over n leaves starting at start, as it appears in the tree at any earlier size. Perfect subtrees are always aligned to their size, so they exist as nodes of the current tree and are looked up directly; the remaining right-edge subtrees are recombined from their perfect parts.
+func (t *Tree) historicSubtreeHash(start int, n int) []byte {
+	if n == 1 || isPowerOfTwo(n) {
+		return t.subtreeHash(start, n)
+	}
+
+	k := largestPowerOfTwoLessThan(n)
+	left := t.subtreeHash(start, k)
+	right := t.historicSubtreeHash(start+k, n-k)
+	return t.domain.HashNode(left, right, t.hashFunc)
 }
 
 // findHashTopDown navigates the tree boundaries to locate a pre-computed hash
@@ -79,12 +164,29 @@ func largestPowerOfTwoLessThan(n int) int {
 
 // VerifyConsistencyProof verifies that the new root is consistent with the old root using the provided consistency proof.
 func VerifyConsistencyProof(m, n int, oldRoot, newRoot []byte, proof *ConsistencyProof, hashFunc hash.Func) bool {
+	return VerifyConsistencyProofWithParams(m, n, oldRoot, newRoot, proof, hashFunc, DomainParams{})
+}
+
+// VerifyConsistencyProofWithParams verifies a consistency proof like VerifyConsistencyProof for a tree built with the given domain separation prefixes, see WithDomainParams.
+func VerifyConsistencyProofWithParams(m, n int, oldRoot, newRoot []byte, proof *ConsistencyProof, hashFunc hash.Func, params DomainParams) bool {
+	if proof == nil {
+		return false
+	}
 	if hashFunc == nil {
 		hashFunc = hash.DefaultHashFunc
 	}
+	if checkHashAlgorithm(proof.HashAlgorithm, hashFunc) != nil { // a proof for another hash function can never verify
+		return false
+	}
+	hashSize := len(hashFunc(nil))
+	for _, h := range proof.Hashes { // the hashes are concatenated blindly, reject a malformed proof before combining them
+		if len(h) != hashSize {
+			return false
+		}
+	}
 
 	if m == n {
-		return bytes.Equal(oldRoot, newRoot) && len(proof.Hashes) == 0
+		return RootsEqual(oldRoot, newRoot) && len(proof.Hashes) == 0
 	}
 	if m <= 0 || m > n {
 		return false
@@ -92,7 +194,7 @@ func VerifyConsistencyProof(m, n int, oldRoot, newRoot []byte, proof *Consistenc
 
 	// the consistency proof verification process involves reconstructing the old root and the new root using the provided proof hashes
 	// helper function verifySubProof is used to do this recursively
-	computedOld, computedNew, remaining, err := verifySubProof(m, n, true, proof.Hashes, oldRoot, hashFunc)
+	computedOld, computedNew, remaining, err := verifySubProof(m, n, true, proof.Hashes, oldRoot, hashFunc, params)
 
 	if err != nil { // if there was an error during verification, the proof is invalid
 		return false
@@ -100,11 +202,11 @@ func VerifyConsistencyProof(m, n int, oldRoot, newRoot []byte, proof *Consistenc
 	if len(remaining) != 0 { // if there are any remaining hashes in the proof that were not used, the proof is invalid
 		return false
 	}
-	return bytes.Equal(computedOld, oldRoot) && bytes.Equal(computedNew, newRoot) // return true if both the computed old root and the computed new root match the provided old and new roots
+	return RootsEqual(computedOld, oldRoot) && RootsEqual(computedNew, newRoot) // return true if both the computed old root and the computed new root match the provided old and new roots
 }
 
 // verifySubProof is a helper function that recursively verifies the consistency proof. It returns the computed old root, the computed new root, any remaining proof hashes, and an error if the proof is invalid.
-func verifySubProof(m, n int, b bool, proofHashes [][]byte, oldRoot []byte, hashFunc hash.Func) ([]byte, []byte, [][]byte, error) {
+func verifySubProof(m, n int, b bool, proofHashes [][]byte, oldRoot []byte, hashFunc hash.Func, params DomainParams) ([]byte, []byte, [][]byte, error) {
 	if m == n { //zoomed in on a subtree that is perfectly identical in both trees
 		if b { // looking at the exact branch that formed the original oldRoot
 			return oldRoot, oldRoot, proofHashes, nil
@@ -120,19 +222,19 @@ func verifySubProof(m, n int, b bool, proofHashes [][]byte, oldRoot []byte, hash
 	k := largestPowerOfTwoLessThan(n) // find the split point of the current subtree to look deeper
 
 	if m <= k { // if the old tree fits entirely inside the left half of the new tree
-		oldHash, newLeft, remainingProof, err := verifySubProof(m, k, b, proofHashes, oldRoot, hashFunc) // recursively verify the left subtree
+		oldHash, newLeft, remainingProof, err := verifySubProof(m, k, b, proofHashes, oldRoot, hashFunc, params) // recursively verify the left subtree
 		if err != nil {
 			return nil, nil, nil, err
 		}
 		if len(remainingProof) == 0 {
 			return nil, nil, nil, errors.New("proof too short")
 		}
-		newRight := remainingProof[0]                                     // right side is entirely new, so the prover provides its hash directly
-		combinedNewRoot := HashInternalNodes(newLeft, newRight, hashFunc) // combine the new left and new right to get the computed new root for this subtree
-		return oldHash, combinedNewRoot, remainingProof[1:], nil          // return the computed old root, the computed new root, and the remaining proof hashes
+		newRight := remainingProof[0]                                   // right side is entirely new, so the prover provides its hash directly
+		combinedNewRoot := params.HashNode(newLeft, newRight, hashFunc) // combine the new left and new right to get the computed new root for this subtree
+		return oldHash, combinedNewRoot, remainingProof[1:], nil        // return the computed old root, the computed new root, and the remaining proof hashes
 	}
 	// if old tree was large enough that it completely filled the left half and spilled over into the right half
-	oldRight, newRight, remainingProof, err := verifySubProof(m-k, n-k, false, proofHashes, oldRoot, hashFunc) // recursively verify the right subtree
+	oldRight, newRight, remainingProof, err := verifySubProof(m-k, n-k, false, proofHashes, oldRoot, hashFunc, params) // recursively verify the right subtree
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -140,8 +242,8 @@ func verifySubProof(m, n int, b bool, proofHashes [][]byte, oldRoot []byte, hash
 		return nil, nil, nil, errors.New("proof too short")
 	}
 	leftHash := remainingProof[0] //entire left half is identical in both the old and new trees, so the prover provides its single combined hash
-	combinedOldRoot := HashInternalNodes(leftHash, oldRight, hashFunc)
-	combinedNewRoot := HashInternalNodes(leftHash, newRight, hashFunc)
+	combinedOldRoot := params.HashNode(leftHash, oldRight, hashFunc)
+	combinedNewRoot := params.HashNode(leftHash, newRight, hashFunc)
 
 	return combinedOldRoot, combinedNewRoot, remainingProof[1:], nil // return the computed old root, the computed new root, and the remaining proof hashes
 }
@@ -1,7 +1,12 @@
 package merkle
 
 import (
+	"context"
+	"errors"
+	"reflect"
 	"testing"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
 )
 
 // TestLargestPowerOfTwoLessThan ensures the bitwise math exactly matches RFC 6962 split boundaries
@@ -53,6 +58,33 @@ func TestGenerateConsistencyProof_Errors(t *testing.T) {
 	}
 }
 
+func TestGenerateConsistencyProofContext(t *testing.T) {
+	tree := buildTestTree(t, 13)
+
+	t.Run("cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		proof, err := tree.GenerateConsistencyProofContext(ctx, 5)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("GenerateConsistencyProofContext() error = %v, want %v", err, context.Canceled)
+		}
+		if proof != nil {
+			t.Errorf("GenerateConsistencyProofContext() = %v, want nil proof", proof)
+		}
+	})
+
+	t.Run("live context", func(t *testing.T) {
+		proof, err := tree.GenerateConsistencyProofContext(context.Background(), 5)
+		if err != nil {
+			t.Fatalf("GenerateConsistencyProofContext() unexpected error: %v", err)
+		}
+		oldRoot, _ := tree.RootAt(5)
+		if !VerifyConsistencyProof(5, 13, oldRoot, tree.RootHash(), proof, nil) {
+			t.Error("proof generated with a live context does not verify")
+		}
+	})
+}
+
 // TestConsistencyProof_Standard valid mathematical generation and verification
 func TestConsistencyProof_Standard(t *testing.T) {
 	allData := [][]byte{
@@ -136,6 +168,25 @@ func TestConsistencyProof_Tampering(t *testing.T) {
 		}
 	})
 
+	t.Run("5-byte hash", func(t *testing.T) {
+		proof, _ := newTree.GenerateConsistencyProof(3)
+		proof.Hashes[len(proof.Hashes)-1] = []byte("short")
+
+		if VerifyConsistencyProof(3, 5, oldRoot, newRoot, proof, nil) {
+			t.Error("VerifyConsistencyProof passed with a hash of the wrong length")
+		}
+	})
+
+	t.Run("5-byte hash with a crafted root", func(t *testing.T) {
+		// for m=1, n=2 the new root is the hash of the old root and the single proof hash, which is easy to craft for a hash of any length
+		short := []byte("short")
+		craftedRoot := HashInternalNodes(oldRoot, short, hash.DefaultHashFunc)
+
+		if VerifyConsistencyProof(1, 2, oldRoot, craftedRoot, &ConsistencyProof{Hashes: [][]byte{short}}, nil) {
+			t.Error("VerifyConsistencyProof passed with a crafted 5-byte hash")
+		}
+	})
+
 	t.Run("truncated proof", func(t *testing.T) {
 		proof, _ := newTree.GenerateConsistencyProof(3)
 
@@ -184,7 +235,7 @@ func TestConsistencyProof_ContinuousAppend(t *testing.T) {
 		}
 
 		newRoot := tree.RootHash()
-		n := len(tree.Leaves) // current size
+		n := len(tree.leaves) // current size
 
 		// Verify consistency against EVERY previous state in history
 		for m, oldRoot := range history {
@@ -205,3 +256,92 @@ func TestConsistencyProof_ContinuousAppend(t *testing.T) {
 		history = append(history, newRoot)
 	}
 }
+
+func TestConsistencyProofBinary_RoundTrip(t *testing.T) {
+	data := [][]byte{[]byte("1"), []byte("2"), []byte("3"), []byte("4"), []byte("5"), []byte("6"), []byte("7")}
+	newTree, _ := NewTree(data, nil)
+
+	for m := 1; m <= len(data); m++ {
+		oldTree, _ := NewTree(data[:m], nil)
+		proof, _ := newTree.GenerateConsistencyProof(m)
+
+		encoded, err := proof.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary() unexpected error: %v", err)
+		}
+
+		var decoded ConsistencyProof
+		if err := decoded.UnmarshalBinary(encoded); err != nil {
+			t.Fatalf("UnmarshalBinary() unexpected error: %v", err)
+		}
+
+		if !VerifyConsistencyProof(m, len(data), oldTree.RootHash(), newTree.RootHash(), &decoded, nil) {
+			t.Errorf("decoded proof for m=%d does not verify", m)
+		}
+	}
+}
+
+func TestConsistencyProofBinary_Invalid(t *testing.T) {
+	proof := &ConsistencyProof{Hashes: [][]byte{{0x01, 0x02}, {0x03}}}
+	valid, _ := proof.MarshalBinary()
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty input", []byte{}},
+		{"truncated hash", valid[:len(valid)-1]},
+		{"truncated length", valid[:5]},
+		{"trailing bytes", append(append([]byte{}, valid...), 0x00)},
+		{"count exceeds input", []byte{0x01, 0x05, 0x01, 0xaa}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decoded ConsistencyProof
+			if err := decoded.UnmarshalBinary(tt.data); err == nil {
+				t.Error("UnmarshalBinary() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestConsistencyProofBinary_Version(t *testing.T) {
+	v1 := []byte{0x01, 0x02, 0x02, 0x01, 0x02, 0x01, 0x03}
+	var decoded ConsistencyProof
+	if err := decoded.UnmarshalBinary(v1); err != nil {
+		t.Fatalf("UnmarshalBinary() unexpected error for a v1 payload: %v", err)
+	}
+	if want := [][]byte{{0x01, 0x02}, {0x03}}; !reflect.DeepEqual(decoded.Hashes, want) {
+		t.Errorf("UnmarshalBinary() hashes = %x, want %x", decoded.Hashes, want)
+	}
+
+	v99 := append([]byte{99}, v1[1:]...)
+	if err := decoded.UnmarshalBinary(v99); !errors.Is(err, ErrUnsupportedProofVersion) {
+		t.Errorf("UnmarshalBinary() error = %v, want %v", err, ErrUnsupportedProofVersion)
+	}
+}
+
+func TestVerifyConsistencyProof_HashAlgorithmMismatch(t *testing.T) {
+	data := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")}
+	oldTree, _ := NewTree(data[:3], hash.SHA512HashFunc)
+	tree, _ := NewTree(data, hash.SHA512HashFunc)
+
+	proof, err := tree.GenerateConsistencyProof(3)
+	if err != nil {
+		t.Fatalf("GenerateConsistencyProof() unexpected error: %v", err)
+	}
+	if proof.HashAlgorithm != "sha512" {
+		t.Fatalf("proof.HashAlgorithm = %q, want %q", proof.HashAlgorithm, "sha512")
+	}
+
+	if !VerifyConsistencyProof(3, 5, oldTree.RootHash(), tree.RootHash(), proof, hash.SHA512HashFunc) {
+		t.Errorf("VerifyConsistencyProof() with matching function = false, want true")
+	}
+	if VerifyConsistencyProof(3, 5, oldTree.RootHash(), tree.RootHash(), proof, nil) {
+		t.Errorf("VerifyConsistencyProof() with default function = true, want false")
+	}
+	if VerifyConsistencyProof(3, 5, oldTree.RootHash(), tree.RootHash(), nil, hash.SHA512HashFunc) {
+		t.Errorf("VerifyConsistencyProof() with nil proof = true, want false")
+	}
+}
@@ -0,0 +1,156 @@
+package merkle
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+// CTLeafHash computes the Certificate Transparency leaf hash of a TLS-encoded MerkleTreeLeaf (RFC 6962 section 3.4), SHA-256(0x00 || entry). It is HashLeafData with SHA-256: the 0x00 leaf prefix of the tree is the one CT uses, so no other conversion is needed. For X.509 entries, the MerkleTreeLeaf can be encoded with CTX509Leaf.
+func CTLeafHash(entry []byte) []byte {
+	return HashLeafData(entry, hash.SHA256HashFunc)
+}
+
+// CTX509Leaf encodes a MerkleTreeLeaf for an X.509 certificate entry in the TLS presentation language, as hashed by CTLeafHash:
+//
+//	uint8  version = 0;                      // v1
+//	uint8  leaf_type = 0;                    // timestamped_entry
+//	uint64 timestamp;                        // milliseconds since the epoch
+//	uint16 entry_type = 0;                   // x509_entry
+//	opaque ASN.1Cert<1..2^24-1>;             // DER certificate, prefixed by a 3-byte length
+//	opaque CtExtensions<0..2^16-1>;          // prefixed by a 2-byte length
+//
+// All integers are big-endian. Precertificate entries are not supported.
+func CTX509Leaf(timestamp uint64, cert []byte, extensions []byte) ([]byte, error) {
+	if len(cert) == 0 || len(cert) > 1<<24-1 {
+		return nil, fmt.Errorf("invalid certificate length %d", len(cert))
+	}
+	if len(extensions) > 0xffff {
+		return nil, fmt.Errorf("invalid extensions length %d", len(extensions))
+	}
+
+	buf := make([]byte, 0, 2+8+2+3+len(cert)+2+len(extensions))
+	buf = append(buf, 0, 0) // version v1, leaf_type timestamped_entry
+	buf = binary.BigEndian.AppendUint64(buf, timestamp)
+	buf = binary.BigEndian.AppendUint16(buf, 0) // entry_type x509_entry
+	buf = append(buf, byte(len(cert)>>16), byte(len(cert)>>8), byte(len(cert)))
+	buf = append(buf, cert...)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(extensions)))
+	return append(buf, extensions...), nil
+}
+
+// NewCTTree creates a Merkle Tree over TLS-encoded MerkleTreeLeaf entries using the Certificate Transparency hashing conventions, SHA-256 with the RFC 6962 0x00 and 0x01 prefixes, so its root matches the root of a CT log (or a Trillian RFC 6962 log) with the same entries in the same order. Like the Merkle Tree Hash of RFC 6962, it accepts empty entries and no entries, whose root is SHA-256 of the empty string.
+func NewCTTree(entries [][]byte) (*Tree, error) {
+	return NewTree(entries, hash.SHA256HashFunc, AllowEmptyLeaves(), AllowEmptyTree())
+}
+
+// MarshalCT encodes the proof in the TLS presentation language encoding used by Certificate Transparency for inclusion proofs (the body of InclusionProofDataV2, RFC 9162 section 4.12):
+//
+//	uint64 tree_size;
+//	uint64 leaf_index;
+//	opaque NodeHash<32..2^8-1> inclusion_path<1..2^16-1>; // each hash prefixed by a 1-byte length, the vector by a 2-byte length
+//
+// Deviations: the log_id field is omitted, since a proof is not bound to a log here, and an empty inclusion path (single-leaf tree) is allowed. The sibling directions are not encoded, as CT derives them from the leaf index and tree size.
+func (p *InclusionProof) MarshalCT() ([]byte, error) {
+	if p.LeafIndex < 0 || p.LeafIndex >= p.TreeSize {
+		return nil, errors.New("invalid inclusion proof: leaf index outside tree size")
+	}
+	left, err := ctPathDirections(p.LeafIndex, p.TreeSize, len(p.Siblings))
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Left) != len(left) {
+		return nil, errors.New("invalid inclusion proof: siblings and directions have different lengths")
+	}
+	for i := range left {
+		if left[i] != p.Left[i] {
+			return nil, errors.New("invalid inclusion proof: sibling directions do not match leaf index and tree size")
+		}
+	}
+
+	buf := binary.BigEndian.AppendUint64(nil, uint64(p.TreeSize))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(p.LeafIndex))
+
+	var path []byte
+	for i, s := range p.Siblings {
+		if len(s) == 0 || len(s) > 0xff {
+			return nil, fmt.Errorf("invalid inclusion proof: sibling %d has unsupported length %d", i, len(s))
+		}
+		path = append(path, byte(len(s)))
+		path = append(path, s...)
+	}
+	if len(path) > 0xffff {
+		return nil, errors.New("invalid inclusion proof: inclusion path too long")
+	}
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(path)))
+	return append(buf, path...), nil
+}
+
+// UnmarshalCT decodes a proof produced by MarshalCT and reconstructs the sibling directions from the leaf index and tree size. It rejects truncated input, trailing bytes, and paths whose length doesn't match the tree shape.
+func (p *InclusionProof) UnmarshalCT(data []byte) error {
+	if len(data) < 18 {
+		return errors.New("invalid CT inclusion proof: truncated header")
+	}
+	treeSize := binary.BigEndian.Uint64(data[0:8])
+	leafIndex := binary.BigEndian.Uint64(data[8:16])
+	pathLen := int(binary.BigEndian.Uint16(data[16:18]))
+	data = data[18:]
+
+	if treeSize > uint64(maxInt) || leafIndex >= treeSize {
+		return errors.New("invalid CT inclusion proof: leaf index outside tree size")
+	}
+	if pathLen != len(data) {
+		return fmt.Errorf("invalid CT inclusion proof: path length %d does not match remaining %d bytes", pathLen, len(data))
+	}
+
+	var siblings [][]byte
+	for len(data) > 0 {
+		size := int(data[0])
+		if size == 0 || size > len(data)-1 {
+			return fmt.Errorf("invalid CT inclusion proof: truncated hash %d", len(siblings))
+		}
+		siblings = append(siblings, append([]byte(nil), data[1:1+size]...))
+		data = data[1+size:]
+	}
+
+	left, err := ctPathDirections(int(leafIndex), int(treeSize), len(siblings))
+	if err != nil {
+		return err
+	}
+
+	*p = InclusionProof{LeafIndex: int(leafIndex), TreeSize: int(treeSize), Siblings: siblings, Left: left}
+	return nil
+}
+
+// maxInt is the largest value of the int type.
+const maxInt = int(^uint(0) >> 1)
+
+// ctPathDirections derives the sibling directions of an inclusion path of the given length from the leaf index and tree size, following the verification algorithm of RFC 9162 section 2.1.3.2. It returns an error if the path length doesn't match the tree shape.
+func ctPathDirections(leafIndex int, treeSize int, pathLen int) ([]bool, error) {
+	fn, sn := leafIndex, treeSize-1
+	left := make([]bool, 0, pathLen)
+	for i := 0; i < pathLen; i++ {
+		if sn == 0 {
+			return nil, errors.New("invalid inclusion proof: path longer than the tree height")
+		}
+		if fn&1 == 1 || fn == sn { // sibling is on the left
+			left = append(left, true)
+			if fn&1 == 0 { // skip the levels where the node has no right sibling
+				for fn&1 == 0 && fn != 0 {
+					fn >>= 1
+					sn >>= 1
+				}
+			}
+		} else { // sibling is on the right
+			left = append(left, false)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return nil, errors.New("invalid inclusion proof: path shorter than the tree height")
+	}
+	return left, nil
+}
@@ -0,0 +1,153 @@
+package merkle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+func TestInclusionProofCT_RoundTrip(t *testing.T) {
+	for size := 1; size <= 20; size++ {
+		var data [][]byte
+		for i := 0; i < size; i++ {
+			data = append(data, []byte(fmt.Sprintf("leaf-%d", i)))
+		}
+		tree, _ := NewTree(data, nil)
+
+		for index := 0; index < size; index++ {
+			proof, _ := tree.GenerateInclusionProof(index)
+
+			encoded, err := proof.MarshalCT()
+			if err != nil {
+				t.Fatalf("size %d index %d: MarshalCT() unexpected error: %v", size, index, err)
+			}
+
+			var decoded InclusionProof
+			if err := decoded.UnmarshalCT(encoded); err != nil {
+				t.Fatalf("size %d index %d: UnmarshalCT() unexpected error: %v", size, index, err)
+			}
+			if decoded.LeafIndex != index || decoded.TreeSize != size {
+				t.Errorf("size %d index %d: decoded position = (%d, %d)", size, index, decoded.LeafIndex, decoded.TreeSize)
+			}
+			if !VerifyInclusionProof(data[index], &decoded, tree.RootHash(), nil) {
+				t.Errorf("size %d index %d: decoded proof does not verify", size, index)
+			}
+		}
+	}
+}
+
+func TestInclusionProofCT_Layout(t *testing.T) {
+	proof := &InclusionProof{LeafIndex: 1, TreeSize: 2, Siblings: [][]byte{{0xaa, 0xbb}}, Left: []bool{true}}
+
+	encoded, err := proof.MarshalCT()
+	if err != nil {
+		t.Fatalf("MarshalCT() unexpected error: %v", err)
+	}
+
+	expected := []byte{
+		0, 0, 0, 0, 0, 0, 0, 2, // tree_size
+		0, 0, 0, 0, 0, 0, 0, 1, // leaf_index
+		0, 3, // inclusion_path length
+		2, 0xaa, 0xbb, // NodeHash
+	}
+	if !bytes.Equal(encoded, expected) {
+		t.Errorf("MarshalCT() = %x, want %x", encoded, expected)
+	}
+}
+
+func TestInclusionProofCT_Invalid(t *testing.T) {
+	tree, _ := NewTree([][]byte{[]byte("a"), []byte("b"), []byte("c")}, nil)
+	proof, _ := tree.GenerateInclusionProof(2)
+	valid, _ := proof.MarshalCT()
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated header", valid[:10]},
+		{"truncated path", valid[:len(valid)-1]},
+		{"trailing bytes", append(append([]byte{}, valid...), 0x00)},
+		{"leaf index beyond size", append([]byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1}, 0, 0)},
+		{"path too short for tree", []byte{0, 0, 0, 0, 0, 0, 0, 4, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 1, 0xaa}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decoded InclusionProof
+			if err := decoded.UnmarshalCT(tt.data); err == nil {
+				t.Error("UnmarshalCT() expected error, got nil")
+			}
+		})
+	}
+
+	t.Run("marshal with inconsistent directions", func(t *testing.T) {
+		bad, _ := tree.GenerateInclusionProof(0)
+		bad.Left[0] = !bad.Left[0]
+		if _, err := bad.MarshalCT(); err == nil {
+			t.Error("MarshalCT() expected error, got nil")
+		}
+	})
+}
+
+// TestNewCTTree_RFC6962Vectors checks the roots of the reference Merkle Tree Hash test vectors of the certificate-transparency and Trillian RFC 6962 implementations.
+func TestNewCTTree_RFC6962Vectors(t *testing.T) {
+	inputs := []string{"", "00", "10", "2021", "3031", "40414243", "5051525354555657", "606162636465666768696a6b6c6d6e6f"}
+	roots := []string{
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", // empty tree
+		"6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d",
+		"fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125",
+		"aeb6bcfe274b70a14fb067a5e5578264db0fa9b51af5e0ba159158f329e06e77",
+		"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+		"4e3bbb1f7b478dcfe71fb631631519a3bca12c9aefca1612bfce4c13a86264d4",
+		"76e67dadbcdf1e10e1b74ddc608abd2f98dfb16fbce75277b5232a127f2087ef",
+		"ddb89be403809e325750d3d263cd78929c2942b7942a34b77e122c9594a74c8c",
+		"5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328",
+	}
+
+	var entries [][]byte
+	for _, in := range inputs {
+		entry, _ := hex.DecodeString(in)
+		entries = append(entries, entry)
+	}
+
+	for size, want := range roots {
+		tree, err := NewCTTree(entries[:size])
+		if err != nil {
+			t.Fatalf("NewCTTree() with %d entries unexpected error: %v", size, err)
+		}
+		if got := hex.EncodeToString(tree.RootHash()); got != want {
+			t.Errorf("root of %d entries = %s, want %s", size, got, want)
+		}
+	}
+
+	if got := hex.EncodeToString(CTLeafHash(nil)); got != roots[1] {
+		t.Errorf("CTLeafHash(empty) = %s, want %s", got, roots[1])
+	}
+}
+
+func TestCTX509Leaf_Layout(t *testing.T) {
+	leaf, err := CTX509Leaf(0x0102030405060708, []byte{0xaa, 0xbb}, []byte{0xcc})
+	if err != nil {
+		t.Fatalf("CTX509Leaf() unexpected error: %v", err)
+	}
+	want := []byte{
+		0x00, 0x00, // version, leaf_type
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, // timestamp
+		0x00, 0x00, // entry_type
+		0x00, 0x00, 0x02, 0xaa, 0xbb, // certificate
+		0x00, 0x01, 0xcc, // extensions
+	}
+	if !bytes.Equal(leaf, want) {
+		t.Errorf("CTX509Leaf() = %x, want %x", leaf, want)
+	}
+	if !bytes.Equal(CTLeafHash(leaf), HashLeafData(leaf, hash.SHA256HashFunc)) {
+		t.Error("CTLeafHash() differs from HashLeafData() with SHA-256")
+	}
+
+	if _, err := CTX509Leaf(0, nil, nil); err == nil {
+		t.Error("CTX509Leaf() with an empty certificate expected error, got nil")
+	}
+}
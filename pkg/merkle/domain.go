@@ -0,0 +1,83 @@
+package merkle
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+// DomainParams are the prefixes prepended to the hash input of leaves and internal nodes. Distinct prefixes separate the two domains, so an internal node can't be passed off as a leaf (a second-preimage attack). A nil prefix means the RFC 6962 default, 0x00 for leaves and 0x01 for internal nodes, so the zero value are the RFC 6962 params.
+// Trees built with custom params only verify with the params-aware verifiers, VerifyInclusionProofWithParams and VerifyConsistencyProofWithParams; the other verifiers and the standalone root computations always use the RFC 6962 prefixes. Save and MarshalBinary encode the params, so Load and UnmarshalBinary restore them.
+type DomainParams struct {
+	LeafPrefix []byte
+	NodePrefix []byte
+}
+
+// RFC6962DomainParams returns the domain separation prefixes of RFC 6962, 0x00 for leaves and 0x01 for internal nodes.
+func RFC6962DomainParams() DomainParams {
+	return DomainParams{LeafPrefix: []byte{0x00}, NodePrefix: []byte{0x01}}
+}
+
+// WithDomainParams makes the tree hash its leaves and internal nodes with the given prefixes instead of the RFC 6962 ones. NewTree rejects params where one prefix is a prefix of the other, as the domains wouldn't be separated.
+func WithDomainParams(p DomainParams) Option {
+	return func(t *Tree) {
+		t.domain = DomainParams{LeafPrefix: bytes.Clone(p.LeafPrefix), NodePrefix: bytes.Clone(p.NodePrefix)}
+	}
+}
+
+// HashLeaf computes the hash of the leaf data prefixed by the leaf prefix.
+func (p DomainParams) HashLeaf(data []byte, hashFunc hash.Func) []byte {
+	if p.LeafPrefix == nil {
+		return HashLeafData(data, hashFunc)
+	}
+	buf := make([]byte, 0, len(p.LeafPrefix)+len(data))
+	buf = append(buf, p.LeafPrefix...)
+	return hashFunc(append(buf, data...))
+}
+
+// HashNode computes the hash of an internal node from the concatenated left and right child hashes prefixed by the node prefix.
+func (p DomainParams) HashNode(left, right []byte, hashFunc hash.Func) []byte {
+	if p.NodePrefix == nil {
+		return HashInternalNodes(left, right, hashFunc)
+	}
+	buf := make([]byte, 0, len(p.NodePrefix)+len(left)+len(right))
+	buf = append(buf, p.NodePrefix...)
+	buf = append(buf, left...)
+	return hashFunc(append(buf, right...))
+}
+
+// nodeHasher returns a function hashing internal nodes with these prefixes and the hash function.
+func (p DomainParams) nodeHasher(hashFunc hash.Func) func(left, right []byte) []byte {
+	return func(left, right []byte) []byte {
+		return p.HashNode(left, right, hashFunc)
+	}
+}
+
+// equal reports whether both params hash with the same prefixes, treating nil as the RFC 6962 default.
+func (p DomainParams) equal(o DomainParams) bool {
+	leaf, node := p.effective()
+	otherLeaf, otherNode := o.effective()
+	return bytes.Equal(leaf, otherLeaf) && bytes.Equal(node, otherNode)
+}
+
+// effective returns the leaf and node prefixes with nil replaced by the RFC 6962 defaults.
+func (p DomainParams) effective() (leaf []byte, node []byte) {
+	leaf, node = p.LeafPrefix, p.NodePrefix
+	if leaf == nil {
+		leaf = []byte{0x00}
+	}
+	if node == nil {
+		node = []byte{0x01}
+	}
+	return leaf, node
+}
+
+// validate checks that the prefixes separate the leaf and node domains: if one prefix started with the other, the leaf data could be chosen to produce the same hash input as an internal node.
+func (p DomainParams) validate() error {
+	leaf, node := p.effective()
+	if bytes.HasPrefix(leaf, node) || bytes.HasPrefix(node, leaf) {
+		return errors.New("leaf and node prefixes must not be prefixes of each other")
+	}
+	return nil
+}
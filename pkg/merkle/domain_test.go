@@ -0,0 +1,105 @@
+package merkle
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestDomainParams_DifferentRoots(t *testing.T) {
+	data := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	custom := DomainParams{LeafPrefix: []byte("leaf:"), NodePrefix: []byte("node:")}
+
+	rfc, err := NewTree(data, nil)
+	if err != nil {
+		t.Fatalf("NewTree() unexpected error: %v", err)
+	}
+	explicit, err := NewTree(data, nil, WithDomainParams(RFC6962DomainParams()))
+	if err != nil {
+		t.Fatalf("NewTree() unexpected error: %v", err)
+	}
+	tagged, err := NewTree(data, nil, WithDomainParams(custom))
+	if err != nil {
+		t.Fatalf("NewTree() unexpected error: %v", err)
+	}
+
+	if !bytes.Equal(explicit.RootHash(), rfc.RootHash()) {
+		t.Errorf("root with explicit RFC 6962 params = %x, want default root %x", explicit.RootHash(), rfc.RootHash())
+	}
+	if bytes.Equal(tagged.RootHash(), rfc.RootHash()) {
+		t.Errorf("trees with different prefixes have the same root %x", tagged.RootHash())
+	}
+}
+
+func TestDomainParams_Proofs(t *testing.T) {
+	params := DomainParams{LeafPrefix: []byte{0xaa}, NodePrefix: []byte{0xbb}}
+
+	var data [][]byte
+	for i := 0; i < 5; i++ {
+		data = append(data, []byte(fmt.Sprintf("leaf%d", i)))
+	}
+	tree, err := NewTree(data[:3], nil, WithDomainParams(params))
+	if err != nil {
+		t.Fatalf("NewTree() unexpected error: %v", err)
+	}
+	oldRoot := tree.RootHash()
+	for _, d := range data[3:] {
+		if err := tree.Append(d); err != nil {
+			t.Fatalf("Append() unexpected error: %v", err)
+		}
+	}
+
+	rebuilt, err := NewTree(data, nil, WithDomainParams(params))
+	if err != nil {
+		t.Fatalf("NewTree() unexpected error: %v", err)
+	}
+	if !bytes.Equal(tree.RootHash(), rebuilt.RootHash()) {
+		t.Errorf("RootHash() after appends = %x, want %x", tree.RootHash(), rebuilt.RootHash())
+	}
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Validate() unexpected error: %v", err)
+	}
+
+	proof, err := tree.GenerateInclusionProofByData(data[4])
+	if err != nil {
+		t.Fatalf("GenerateInclusionProofByData() unexpected error: %v", err)
+	}
+	if !VerifyInclusionProofWithParams(data[4], proof, tree.RootHash(), nil, params) {
+		t.Errorf("VerifyInclusionProofWithParams() = false, want true")
+	}
+	if VerifyInclusionProof(data[4], proof, tree.RootHash(), nil) {
+		t.Errorf("VerifyInclusionProof() with RFC 6962 prefixes = true, want false")
+	}
+
+	consistency, err := tree.GenerateConsistencyProof(3)
+	if err != nil {
+		t.Fatalf("GenerateConsistencyProof() unexpected error: %v", err)
+	}
+	if !VerifyConsistencyProofWithParams(3, 5, oldRoot, tree.RootHash(), consistency, nil, params) {
+		t.Errorf("VerifyConsistencyProofWithParams() = false, want true")
+	}
+	if VerifyConsistencyProof(3, 5, oldRoot, tree.RootHash(), consistency, nil) {
+		t.Errorf("VerifyConsistencyProof() with RFC 6962 prefixes = true, want false")
+	}
+}
+
+func TestDomainParams_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		params DomainParams
+	}{
+		{"equal prefixes", DomainParams{LeafPrefix: []byte("x"), NodePrefix: []byte("x")}},
+		{"leaf prefix starts with node prefix", DomainParams{LeafPrefix: []byte("ab"), NodePrefix: []byte("a")}},
+		{"node prefix starts with leaf prefix", DomainParams{LeafPrefix: []byte("a"), NodePrefix: []byte("ab")}},
+		{"empty leaf prefix", DomainParams{LeafPrefix: []byte{}, NodePrefix: []byte("n")}},
+		{"custom node prefix equal to default leaf prefix", DomainParams{NodePrefix: []byte{0x00}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewTree([][]byte{[]byte("a")}, nil, WithDomainParams(tt.params)); err == nil {
+				t.Errorf("NewTree() expected error, got nil")
+			}
+		})
+	}
+}
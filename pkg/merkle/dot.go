@@ -0,0 +1,53 @@
+package merkle
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteDOT writes the tree structure to w as a Graphviz digraph, e.g. for rendering with `dot -Tsvg`. Each node is labeled with its truncated hash and has an edge to each of its children; leaves are drawn as boxes.
+func (t *Tree) WriteDOT(w io.Writer) error {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	var b strings.Builder
+	b.WriteString("digraph merkle {\n")
+	if t.root != nil {
+		id := 0
+		writeDOTNode(&b, t.root, &id)
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeDOTNode writes the node and its subtree to b in pre-order, numbering the nodes with the counter id, and returns the identifier of the node.
+func writeDOTNode(b *strings.Builder, n *Node, id *int) string {
+	name := fmt.Sprintf("n%d", *id)
+	*id++
+
+	shape := "ellipse"
+	if n.Left == nil && n.Right == nil {
+		shape = "box"
+	}
+	fmt.Fprintf(b, "\t%s [label=%q, shape=%s];\n", name, dotLabel(n.Hash), shape)
+
+	for _, child := range []*Node{n.Left, n.Right} {
+		if child != nil {
+			fmt.Fprintf(b, "\t%s -> %s;\n", name, writeDOTNode(b, child, id))
+		}
+	}
+	return name
+}
+
+// dotLabel returns the first 8 hex characters of the hash.
+func dotLabel(h []byte) string {
+	s := hex.EncodeToString(h)
+	if len(s) > 8 {
+		s = s[:8]
+	}
+	return s
+}
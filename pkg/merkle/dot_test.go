@@ -0,0 +1,56 @@
+package merkle
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteDOT(t *testing.T) {
+	tests := []struct {
+		name      string
+		leaves    int
+		nodes     int
+		leafNodes int
+		edges     int
+	}{
+		{"single leaf", 1, 1, 1, 0},
+		{"4 leaves", 4, 7, 4, 6},
+		{"5 leaves", 5, 9, 5, 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := buildTestTree(t, tt.leaves)
+
+			var b strings.Builder
+			if err := tree.WriteDOT(&b); err != nil {
+				t.Fatalf("WriteDOT() unexpected error: %v", err)
+			}
+			out := b.String()
+
+			if !strings.HasPrefix(out, "digraph merkle {\n") || !strings.HasSuffix(out, "}\n") {
+				t.Errorf("WriteDOT() = %q, want a digraph", out)
+			}
+			if got := strings.Count(out, "[label="); got != tt.nodes {
+				t.Errorf("node count = %d, want %d", got, tt.nodes)
+			}
+			if got := strings.Count(out, "shape=box"); got != tt.leafNodes {
+				t.Errorf("leaf count = %d, want %d", got, tt.leafNodes)
+			}
+			if got := strings.Count(out, " -> "); got != tt.edges {
+				t.Errorf("edge count = %d, want %d", got, tt.edges)
+			}
+		})
+	}
+}
+
+func TestWriteDOT_EmptyTree(t *testing.T) {
+	var tree Tree
+	var b strings.Builder
+	if err := tree.WriteDOT(&b); err != nil {
+		t.Fatalf("WriteDOT() unexpected error: %v", err)
+	}
+	if b.String() != "digraph merkle {\n}\n" {
+		t.Errorf("WriteDOT() = %q, want an empty digraph", b.String())
+	}
+}
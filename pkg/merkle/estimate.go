@@ -0,0 +1,43 @@
+package merkle
+
+// ExpectedInclusionProofSize returns the size in bytes of the sibling hashes of an inclusion proof for the leaf at leafIndex in a tree of treeSize leaves, with hashes of hashLen bytes, e.g. to budget proof requests over a metered link. The number of siblings follows from the RFC 6962 tree shape alone. Encoding overhead, like the direction flags or length prefixes, isn't included. It returns 0 for an invalid index or size.
+func ExpectedInclusionProofSize(treeSize, leafIndex, hashLen int) int {
+	if treeSize <= 0 || leafIndex < 0 || leafIndex >= treeSize {
+		return 0
+	}
+
+	siblings := 0
+	for n := treeSize; n > 1; siblings++ { // descend towards the leaf, one sibling per level
+		k := largestPowerOfTwoLessThan(n)
+		if leafIndex < k {
+			n = k
+		} else {
+			leafIndex, n = leafIndex-k, n-k
+		}
+	}
+	return siblings * hashLen
+}
+
+// ExpectedConsistencyProofSize returns the size in bytes of the hashes of a consistency proof from a tree of m leaves to a tree of n leaves, with hashes of hashLen bytes, following the RFC 6962 SUBPROOF structure. Encoding overhead isn't included. It returns 0 if m == n, as no hashes are needed, and for invalid sizes.
+func ExpectedConsistencyProofSize(m, n, hashLen int) int {
+	if m <= 0 || m >= n {
+		return 0
+	}
+	return consistencyProofHashCount(m, n, true) * hashLen
+}
+
+// consistencyProofHashCount counts the hashes subProofRecursively produces for the given arguments.
+func consistencyProofHashCount(m int, n int, b bool) int {
+	if m == n {
+		if b {
+			return 0
+		}
+		return 1
+	}
+
+	k := largestPowerOfTwoLessThan(n)
+	if m <= k {
+		return consistencyProofHashCount(m, k, b) + 1
+	}
+	return consistencyProofHashCount(m-k, n-k, false) + 1
+}
@@ -0,0 +1,60 @@
+package merkle
+
+import (
+	"testing"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+func TestExpectedInclusionProofSize(t *testing.T) {
+	for _, hashFunc := range []hash.Func{hash.SHA256HashFunc, hash.SHA512HashFunc} {
+		hashLen := len(hashFunc(nil))
+		for size := 1; size <= 33; size++ {
+			tree, _ := NewTree(leafDataRange(0, size), hashFunc)
+			for index := 0; index < size; index++ {
+				proof, err := tree.GenerateInclusionProof(index)
+				if err != nil {
+					t.Fatalf("GenerateInclusionProof(%d) unexpected error: %v", index, err)
+				}
+				if got, want := ExpectedInclusionProofSize(size, index, hashLen), len(proof.Siblings)*hashLen; got != want {
+					t.Errorf("ExpectedInclusionProofSize(%d, %d, %d) = %d, want %d", size, index, hashLen, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestExpectedConsistencyProofSize(t *testing.T) {
+	const hashLen = 32
+	for n := 1; n <= 33; n++ {
+		tree := buildTestTree(t, n)
+		for m := 1; m <= n; m++ {
+			proof, err := tree.GenerateConsistencyProof(m)
+			if err != nil {
+				t.Fatalf("GenerateConsistencyProof(%d) unexpected error: %v", m, err)
+			}
+			if got, want := ExpectedConsistencyProofSize(m, n, hashLen), len(proof.Hashes)*hashLen; got != want {
+				t.Errorf("ExpectedConsistencyProofSize(%d, %d, %d) = %d, want %d", m, n, hashLen, got, want)
+			}
+		}
+	}
+}
+
+func TestExpectedProofSize_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		got  int
+	}{
+		{"inclusion, empty tree", ExpectedInclusionProofSize(0, 0, 32)},
+		{"inclusion, negative index", ExpectedInclusionProofSize(4, -1, 32)},
+		{"inclusion, index past the tree", ExpectedInclusionProofSize(4, 4, 32)},
+		{"consistency, zero old size", ExpectedConsistencyProofSize(0, 4, 32)},
+		{"consistency, shrinking tree", ExpectedConsistencyProofSize(5, 4, 32)},
+	}
+
+	for _, tt := range tests {
+		if tt.got != 0 {
+			t.Errorf("%s: got %d, want 0", tt.name, tt.got)
+		}
+	}
+}
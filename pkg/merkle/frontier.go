@@ -0,0 +1,120 @@
+package merkle
+
+import (
+	"errors"
+	"math/bits"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+// Frontier returns the root hashes of the perfect subtrees that make up the tree, ordered from left (largest) to right (smallest). Their number equals the count of set bits in the tree size.
+func (t *Tree) Frontier() [][]byte {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.frontierLocked(len(t.leaves))
+}
+
+// frontierLocked computes the frontier of the first size leaves. It assumes the caller has already acquired the read lock.
+func (t *Tree) frontierLocked(size int) [][]byte {
+	var frontier [][]byte
+	offset := 0
+	for bit := bits.Len(uint(size)) - 1; bit >= 0; bit-- { // iterate through the bits of size from MSB to LSB
+		if size&(1<<bit) == 0 {
+			continue
+		}
+		frontier = append(frontier, t.subtreeHash(offset, 1<<bit))
+		offset += 1 << bit
+	}
+	return frontier
+}
+
+// RootFromFrontier bags the frontier hashes of a tree with the given size into its RFC 6962 root hash. The frontier hashes are combined from right to left, which reproduces the tree's root without holding any of its leaves.
+func RootFromFrontier(frontier [][]byte, size int, hashFunc hash.Func) ([]byte, error) {
+	if size <= 0 {
+		return nil, errors.New("invalid size: must be positive")
+	}
+	if len(frontier) != bits.OnesCount(uint(size)) {
+		return nil, errors.New("frontier length does not match tree size")
+	}
+
+	if hashFunc == nil {
+		hashFunc = hash.DefaultHashFunc
+	}
+
+	root := frontier[len(frontier)-1] // start with the rightmost (smallest) subtree
+	for i := len(frontier) - 2; i >= 0; i-- {
+		root = HashInternalNodes(frontier[i], root, hashFunc)
+	}
+	return root, nil
+}
+
+// AppendToFrontier appends the leaf data to a tree known only by its frontier and returns the frontier and root hash of the grown tree. The new leaf hash is pushed onto the frontier and merged with its left neighbours once for every trailing set bit of size, the same way equal-height peaks merge in an MMR. An empty frontier with size 0 starts a new tree.
+func AppendToFrontier(frontier [][]byte, size int, newLeaf []byte, hashFunc hash.Func) ([][]byte, []byte, error) {
+	if size < 0 {
+		return nil, nil, errors.New("invalid size: must not be negative")
+	}
+	if len(frontier) != bits.OnesCount(uint(size)) {
+		return nil, nil, errors.New("frontier length does not match tree size")
+	}
+
+	if hashFunc == nil {
+		hashFunc = hash.DefaultHashFunc
+	}
+
+	newFrontier := make([][]byte, len(frontier), len(frontier)+1)
+	copy(newFrontier, frontier) // never modify the caller's frontier
+	newFrontier = pushLeafHash(newFrontier, size, HashLeafData(newLeaf, hashFunc), hashFunc)
+
+	newRoot, err := RootFromFrontier(newFrontier, size+1, hashFunc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newFrontier, newRoot, nil
+}
+
+// pushLeafHash pushes the leaf hash onto the frontier of a tree with the given size and merges it with its left neighbours once for every trailing set bit of size. It modifies the frontier in place and returns it.
+func pushLeafHash(frontier [][]byte, size int, leafHash []byte, hashFunc hash.Func) [][]byte {
+	current := leafHash
+	for merges := bits.TrailingZeros(^uint(size)); merges > 0; merges-- { // each trailing set bit is a subtree of the same height as current
+		left := frontier[len(frontier)-1]
+		frontier = frontier[:len(frontier)-1]
+		current = HashInternalNodes(left, current, hashFunc)
+	}
+	return append(frontier, current)
+}
+
+// StreamingRootComputer computes the root hash of a log from its leaf hashes as they stream in, e.g. during a full log download. It only keeps the O(log n) frontier instead of all the leaves.
+type StreamingRootComputer struct {
+	frontier [][]byte
+	size     int
+	hashFunc hash.Func
+}
+
+// NewStreamingRootComputer creates an empty StreamingRootComputer using the given hash function, or the default one if nil.
+func NewStreamingRootComputer(hashFunc hash.Func) *StreamingRootComputer {
+	if hashFunc == nil {
+		hashFunc = hash.DefaultHashFunc
+	}
+	return &StreamingRootComputer{hashFunc: hashFunc}
+}
+
+// Add appends the next leaf hash, i.e. the hash of the leaf data as returned by HashLeafData.
+func (c *StreamingRootComputer) Add(leafHash []byte) {
+	c.frontier = pushLeafHash(c.frontier, c.size, leafHash, c.hashFunc)
+	c.size++
+}
+
+// Size returns the number of leaf hashes added so far.
+func (c *StreamingRootComputer) Size() int {
+	return c.size
+}
+
+// Root returns the root hash of the leaves added so far, or nil if none were added.
+func (c *StreamingRootComputer) Root() []byte {
+	if c.size == 0 {
+		return nil
+	}
+	root, _ := RootFromFrontier(c.frontier, c.size, c.hashFunc) // the frontier always matches the size
+	return root
+}
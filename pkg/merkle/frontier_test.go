@@ -0,0 +1,134 @@
+package merkle
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestRootFromFrontier(t *testing.T) {
+	for size := 1; size <= 16; size++ {
+		t.Run(fmt.Sprintf("size %d", size), func(t *testing.T) {
+			var data [][]byte
+			for i := 0; i < size; i++ {
+				data = append(data, []byte(fmt.Sprintf("leaf%d", i)))
+			}
+			tree, err := NewTree(data, nil)
+			if err != nil {
+				t.Fatalf("Failed to create tree: %v", err)
+			}
+
+			root, err := RootFromFrontier(tree.Frontier(), len(tree.leaves), nil)
+			if err != nil {
+				t.Fatalf("RootFromFrontier() unexpected error: %v", err)
+			}
+			if !bytes.Equal(root, tree.RootHash()) {
+				t.Errorf("RootFromFrontier() = %x, want %x", root, tree.RootHash())
+			}
+		})
+	}
+}
+
+func TestRootFromFrontier_Errors(t *testing.T) {
+	tree, _ := NewTree([][]byte{[]byte("a"), []byte("b"), []byte("c")}, nil)
+
+	tests := []struct {
+		name     string
+		frontier [][]byte
+		size     int
+	}{
+		{"zero size", tree.Frontier(), 0},
+		{"negative size", tree.Frontier(), -1},
+		{"frontier too short", tree.Frontier()[:1], 3},
+		{"frontier too long", tree.Frontier(), 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := RootFromFrontier(tt.frontier, tt.size, nil); err == nil {
+				t.Errorf("RootFromFrontier() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestAppendToFrontier_TracksTree(t *testing.T) {
+	tree, _ := NewTree([][]byte{[]byte("tx0")}, nil)
+	frontier := tree.Frontier()
+	size := 1
+
+	for i := 1; i <= 20; i++ {
+		data := []byte(fmt.Sprintf("tx%d", i))
+		if err := tree.Append(data); err != nil {
+			t.Fatalf("Append failed at step %d: %v", i, err)
+		}
+
+		var root []byte
+		var err error
+		frontier, root, err = AppendToFrontier(frontier, size, data, nil)
+		if err != nil {
+			t.Fatalf("AppendToFrontier failed at step %d: %v", i, err)
+		}
+		size++
+
+		if !bytes.Equal(root, tree.RootHash()) {
+			t.Errorf("step %d: frontier root = %x, tree root = %x", i, root, tree.RootHash())
+		}
+		if len(frontier) != len(tree.Frontier()) {
+			t.Errorf("step %d: frontier length = %d, want %d", i, len(frontier), len(tree.Frontier()))
+		}
+	}
+}
+
+func TestAppendToFrontier_FromEmpty(t *testing.T) {
+	frontier, root, err := AppendToFrontier(nil, 0, []byte("first"), nil)
+	if err != nil {
+		t.Fatalf("AppendToFrontier() unexpected error: %v", err)
+	}
+
+	tree, _ := NewTree([][]byte{[]byte("first")}, nil)
+	if !bytes.Equal(root, tree.RootHash()) {
+		t.Errorf("AppendToFrontier() root = %x, want %x", root, tree.RootHash())
+	}
+	if len(frontier) != 1 {
+		t.Errorf("AppendToFrontier() frontier length = %d, want 1", len(frontier))
+	}
+}
+
+func TestAppendToFrontier_Errors(t *testing.T) {
+	tree, _ := NewTree([][]byte{[]byte("a"), []byte("b"), []byte("c")}, nil)
+
+	if _, _, err := AppendToFrontier(tree.Frontier(), -1, []byte("d"), nil); err == nil {
+		t.Error("AppendToFrontier() expected error for negative size, got nil")
+	}
+	if _, _, err := AppendToFrontier(tree.Frontier(), 4, []byte("d"), nil); err == nil {
+		t.Error("AppendToFrontier() expected error for mismatched frontier, got nil")
+	}
+}
+
+func TestStreamingRootComputer(t *testing.T) {
+	tree := buildTestTree(t, 10000)
+	leafHashes := leafHashesOf(tree)
+
+	c := NewStreamingRootComputer(nil)
+	if c.Root() != nil {
+		t.Errorf("Root() of empty computer = %x, want nil", c.Root())
+	}
+
+	for i, h := range leafHashes {
+		c.Add(h)
+		if i < 64 { // spot check the intermediate roots of small sizes
+			want := rootFromLeafHashes(leafHashes[:i+1], tree.hashFunc, DomainParams{})
+			if !bytes.Equal(c.Root(), want) {
+				t.Fatalf("Root() after %d leaves = %x, want %x", i+1, c.Root(), want)
+			}
+		}
+	}
+
+	if c.Size() != len(leafHashes) {
+		t.Errorf("Size() = %d, want %d", c.Size(), len(leafHashes))
+	}
+	if !bytes.Equal(c.Root(), tree.RootHash()) {
+		t.Errorf("Root() = %x, want %x", c.Root(), tree.RootHash())
+	}
+}
@@ -1,15 +1,50 @@
 package merkle
 
-import "github.com/andrlikjirka/dp-teals/pkg/hash"
+import (
+	"sync"
 
-// HashLeafData computes the hash of the leaf data by prefixing it with 0x00 and applying the hash function.
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+// maxPooledBufSize bounds the capacity of buffers returned to bufPool, so hashing one huge leaf doesn't pin its buffer in memory.
+const maxPooledBufSize = 64 << 10
+
+// bufPool holds the buffers used to compose the prefixed hash inputs, which are only needed until the digest is computed.
+var bufPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 1+2*64) // room for an internal node of a 512-bit hash
+		return &b
+	},
+}
+
+// HashLeafData computes the hash of the leaf data by prefixing it with 0x00 and applying the hash function. The input slice is never modified.
+// The prefixed input is composed in a pooled buffer, so the hash function must not retain or alias its input, as required by hash.Func.
 func HashLeafData(data []byte, hashFunc hash.Func) []byte {
-	prefix := []byte{0x00}
-	return hashFunc(append(prefix, data...))
+	bp := bufPool.Get().(*[]byte)
+	buf := append((*bp)[:0], 0x00)
+	buf = append(buf, data...)
+	h := hashFunc(buf)
+	putBuf(bp, buf)
+	return h
 }
 
-// HashInternalNodes computes the hash of the internal nodes by prefixing the concatenated left and right child hashes with 0x01 and applying the hash function.
+// HashInternalNodes computes the hash of the internal nodes by prefixing the concatenated left and right child hashes with 0x01 and applying the hash function. The input slices are never modified, even when they have spare capacity.
+// Like HashLeafData, it composes the input in a pooled buffer.
 func HashInternalNodes(left, right []byte, hashFunc hash.Func) []byte {
-	prefix := []byte{0x01}
-	return hashFunc(append(prefix, append(left, right...)...))
+	bp := bufPool.Get().(*[]byte)
+	buf := append((*bp)[:0], 0x01)
+	buf = append(buf, left...)
+	buf = append(buf, right...)
+	h := hashFunc(buf)
+	putBuf(bp, buf)
+	return h
+}
+
+// putBuf returns the buffer, possibly grown from the one held by bp, to the pool unless it grew too large.
+func putBuf(bp *[]byte, buf []byte) {
+	if cap(buf) > maxPooledBufSize {
+		return
+	}
+	*bp = buf[:0]
+	bufPool.Put(bp)
 }
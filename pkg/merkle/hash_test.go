@@ -3,6 +3,8 @@ package merkle
 import (
 	"bytes"
 	"crypto/sha256"
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/andrlikjirka/dp-teals/pkg/hash"
@@ -57,7 +59,7 @@ func TestHashLeafData(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tree, err := NewTree([][]byte{tt.data}, nil)
+			tree, err := NewTree([][]byte{tt.data}, nil, AllowEmptyLeaves())
 			if err != nil {
 				t.Fatalf("Failed to create tree: %v", err)
 			}
@@ -141,12 +143,12 @@ func TestHashInternalNodes(t *testing.T) {
 			result := HashInternalNodes(tt.left, tt.right, tree.hashFunc)
 
 			if (err != nil) != tt.expectErr {
-				t.Errorf("hashInternalNodes() error = %v, wantErr %v", err, tt.expectErr)
+				t.Errorf("HashInternalNodes() error = %v, wantErr %v", err, tt.expectErr)
 				return
 			}
 
 			if !tt.validate(result) {
-				t.Errorf("hashInternalNodes() validation failed for left: %x, right: %x", tt.left, tt.right)
+				t.Errorf("HashInternalNodes() validation failed for left: %x, right: %x", tt.left, tt.right)
 			}
 		})
 	}
@@ -172,7 +174,7 @@ func TestHashPrefixDifference(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tree, _ := NewTree([][]byte{tt.data}, nil)
+			tree, _ := NewTree([][]byte{tt.data}, nil, AllowEmptyLeaves())
 
 			leafHash := HashLeafData(tt.data, tree.hashFunc)
 			internalHash := HashInternalNodes(tt.data, tt.data, tree.hashFunc)
@@ -184,3 +186,56 @@ func TestHashPrefixDifference(t *testing.T) {
 		})
 	}
 }
+
+func TestHashInternalNodes_DoesNotModifyInputs(t *testing.T) {
+	backing := make([]byte, 32, 64) // left has spare capacity that a naive append would write into
+	copy(backing, bytes.Repeat([]byte{0xaa}, 32))
+	left := backing[:32]
+	right := bytes.Repeat([]byte{0xbb}, 32)
+	spare := backing[32:64]
+
+	first := HashInternalNodes(left, right, hash.SHA256HashFunc)
+	if !bytes.Equal(spare, make([]byte, 32)) {
+		t.Fatalf("HashInternalNodes() wrote into the spare capacity of left: %x", spare)
+	}
+
+	// a second call over the same backing array must produce the same hash
+	other := bytes.Repeat([]byte{0xcc}, 32)
+	_ = HashInternalNodes(left, other, hash.SHA256HashFunc)
+	if second := HashInternalNodes(left, right, hash.SHA256HashFunc); !bytes.Equal(first, second) {
+		t.Errorf("HashInternalNodes() = %x, want %x", second, first)
+	}
+
+	expected := sha256Bytes(append([]byte{0x01}, append(bytes.Repeat([]byte{0xaa}, 32), right...)...))
+	if !bytes.Equal(first, expected) {
+		t.Errorf("HashInternalNodes() = %x, want %x", first, expected)
+	}
+}
+
+func TestHashLeafData_PooledBuffersConcurrent(t *testing.T) {
+	large := bytes.Repeat([]byte{0xab}, maxPooledBufSize+1) // not returned to the pool
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				data := []byte(fmt.Sprintf("leaf-%d-%d", g, i))
+				if got, want := HashLeafData(data, hash.SHA256HashFunc), sha256Bytes(append([]byte{0x00}, data...)); !bytes.Equal(got, want) {
+					t.Errorf("HashLeafData(%q) = %x, want %x", data, got, want)
+					return
+				}
+				if i%50 == 0 {
+					HashLeafData(large, hash.SHA256HashFunc)
+				}
+				left, right := sha256Bytes(data), sha256Bytes(data[1:])
+				want := sha256Bytes(append(append([]byte{0x01}, left...), right...))
+				if got := HashInternalNodes(left, right, hash.SHA256HashFunc); !bytes.Equal(got, want) {
+					t.Errorf("HashInternalNodes() = %x, want %x", got, want)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,57 @@
+package merkle
+
+import (
+	"context"
+	stdhash "hash"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+// NewTreeWithHasher creates a new Merkle Tree like NewTree, but takes a constructor of incremental hashes, e.g. sha256.New, instead of a hash.Func.
+// The tree is built with a single reused hash.Hash: the domain prefix and the leaf data or child hashes are written into it directly rather than joined into a new buffer for every hash, which saves an allocation and a copy of each leaf and matters for large leaves. Later operations such as appends and proofs use hash.FromHasher(newHash), so proofs verify with either form.
+func NewTreeWithHasher(data [][]byte, newHash func() stdhash.Hash, opts ...Option) (*Tree, error) {
+	if newHash == nil {
+		return NewTree(data, nil, opts...)
+	}
+
+	t, data, err := newTreeWithData(data, hash.FromHasher(newHash), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &streamHasher{h: newHash(), domain: t.domain}
+	_ = t.buildWith(context.Background(), data, s.leaf, s.node, nil) // can't fail without cancellation
+	return t, nil
+}
+
+// streamHasher hashes leaves and internal nodes by writing the domain prefix and the inputs into a reused hash.Hash. It is not safe for concurrent use.
+type streamHasher struct {
+	h      stdhash.Hash
+	domain DomainParams
+}
+
+// leaf returns the leaf hash of the data, see DomainParams.HashLeaf.
+func (s *streamHasher) leaf(data []byte) []byte {
+	s.h.Reset()
+	s.writePrefix(s.domain.LeafPrefix, 0x00)
+	s.h.Write(data)
+	return s.h.Sum(nil)
+}
+
+// node returns the internal node hash of the children, see DomainParams.HashNode.
+func (s *streamHasher) node(left, right []byte) []byte {
+	s.h.Reset()
+	s.writePrefix(s.domain.NodePrefix, 0x01)
+	s.h.Write(left)
+	s.h.Write(right)
+	return s.h.Sum(nil)
+}
+
+// writePrefix writes the prefix, or the RFC 6962 default if it is nil.
+func (s *streamHasher) writePrefix(prefix []byte, rfc6962 byte) {
+	if prefix == nil {
+		s.h.Write([]byte{rfc6962})
+		return
+	}
+	s.h.Write(prefix)
+}
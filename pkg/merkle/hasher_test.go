@@ -0,0 +1,84 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	stdhash "hash"
+	"testing"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+func TestNewTreeWithHasher(t *testing.T) {
+	var data [][]byte
+	for i := 0; i < 7; i++ {
+		data = append(data, []byte(fmt.Sprintf("leaf%d", i)))
+	}
+	params := DomainParams{LeafPrefix: []byte("L"), NodePrefix: []byte("N")}
+
+	tests := []struct {
+		name     string
+		newHash  func() stdhash.Hash
+		hashFunc hash.Func
+		opts     []Option
+	}{
+		{"sha256", sha256.New, hash.SHA256HashFunc, nil},
+		{"sha512", sha512.New, hash.SHA512HashFunc, nil},
+		{"custom domain params", sha256.New, hash.SHA256HashFunc, []Option{WithDomainParams(params)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := NewTreeWithHasher(data, tt.newHash, tt.opts...)
+			if err != nil {
+				t.Fatalf("NewTreeWithHasher() unexpected error: %v", err)
+			}
+			want, err := NewTree(data, tt.hashFunc, tt.opts...)
+			if err != nil {
+				t.Fatalf("NewTree() unexpected error: %v", err)
+			}
+			if !bytes.Equal(tree.RootHash(), want.RootHash()) {
+				t.Fatalf("RootHash() = %x, want %x", tree.RootHash(), want.RootHash())
+			}
+
+			if err := tree.Append([]byte("next")); err != nil {
+				t.Fatalf("Append() unexpected error: %v", err)
+			}
+			if err := want.Append([]byte("next")); err != nil {
+				t.Fatalf("Append() unexpected error: %v", err)
+			}
+			if !bytes.Equal(tree.RootHash(), want.RootHash()) {
+				t.Errorf("RootHash() after append = %x, want %x", tree.RootHash(), want.RootHash())
+			}
+			if err := tree.Validate(); err != nil {
+				t.Errorf("Validate() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func BenchmarkNewTree_64KBLeaves(b *testing.B) {
+	data := make([][]byte, 64)
+	for i := range data {
+		data[i] = bytes.Repeat([]byte{byte(i)}, 64*1024)
+	}
+
+	b.Run("HashFunc", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := NewTree(data, hash.SHA256HashFunc); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Hasher", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := NewTreeWithHasher(data, sha256.New); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
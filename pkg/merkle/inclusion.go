@@ -1,16 +1,92 @@
 package merkle
 
 import (
-	"bytes"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
 
 	"github.com/andrlikjirka/dp-teals/pkg/hash"
 )
 
 type InclusionProof struct {
-	Siblings [][]byte // Hashes of sibling nodes along the path to the root
-	Left     []bool   // Indicates whether the sibling is a left sibling (true) or right sibling (false)
+	LeafIndex     int      // Index of the proven leaf
+	TreeSize      int      // Number of leaves in the tree the proof was generated for
+	HashAlgorithm string   // Registered name of the tree's hash function, see hash.ByName; empty if unknown
+	Siblings      [][]byte // Hashes of sibling nodes along the path to the root
+	Left          []bool   // Indicates whether the sibling is a left sibling (true) or right sibling (false)
+}
+
+// ProofFormatVersion is the major version of the JSON and binary proof encodings, written by MarshalJSON of InclusionProof and MarshalBinary of ConsistencyProof. It is bumped on changes that older decoders can't read; the decoders reject payloads of an unknown version with ErrUnsupportedProofVersion.
+const ProofFormatVersion = 1
+
+// ErrUnsupportedProofVersion is returned when decoding a proof encoded with an unknown format version.
+var ErrUnsupportedProofVersion = errors.New("unsupported proof format version")
+
+// inclusionProofJSON is the wire representation of an InclusionProof with hex-encoded sibling hashes.
+type inclusionProofJSON struct {
+	Version       int      `json:"version"`
+	LeafIndex     int      `json:"leaf_index"`
+	TreeSize      int      `json:"tree_size"`
+	HashAlgorithm string   `json:"hash_algorithm,omitempty"`
+	Siblings      []string `json:"siblings"`
+	Left          []bool   `json:"left"`
+}
+
+// MarshalJSON encodes the proof as JSON with the sibling hashes as hex strings and the sibling directions as a boolean array.
+func (p *InclusionProof) MarshalJSON() ([]byte, error) {
+	siblings := make([]string, len(p.Siblings))
+	for i, s := range p.Siblings {
+		siblings[i] = hex.EncodeToString(s)
+	}
+	left := p.Left
+	if left == nil {
+		left = []bool{}
+	}
+	return json.Marshal(inclusionProofJSON{Version: ProofFormatVersion, LeafIndex: p.LeafIndex, TreeSize: p.TreeSize, HashAlgorithm: p.HashAlgorithm, Siblings: siblings, Left: left})
+}
+
+// UnmarshalJSON decodes a proof produced by MarshalJSON. It rejects payloads with invalid hex hashes or with a different number of sibling hashes and directions, and payloads of an unknown format version with ErrUnsupportedProofVersion. Payloads without a version, written before it was added, are decoded as version 1.
+func (p *InclusionProof) UnmarshalJSON(data []byte) error {
+	raw := inclusionProofJSON{Version: 1}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.Version != ProofFormatVersion {
+		return fmt.Errorf("invalid inclusion proof: %w %d", ErrUnsupportedProofVersion, raw.Version)
+	}
+	if len(raw.Siblings) != len(raw.Left) {
+		return fmt.Errorf("invalid inclusion proof: %d siblings but %d directions", len(raw.Siblings), len(raw.Left))
+	}
+	if raw.LeafIndex < 0 || raw.TreeSize < 0 {
+		return errors.New("invalid inclusion proof: negative leaf index or tree size")
+	}
+
+	siblings := make([][]byte, len(raw.Siblings))
+	for i, s := range raw.Siblings {
+		h, err := hex.DecodeString(s)
+		if err != nil {
+			return fmt.Errorf("invalid inclusion proof: sibling %d: %w", i, err)
+		}
+		siblings[i] = h
+	}
+
+	*p = InclusionProof{LeafIndex: raw.LeafIndex, TreeSize: raw.TreeSize, HashAlgorithm: raw.HashAlgorithm, Siblings: siblings, Left: raw.Left}
+	return nil
+}
+
+// ValidAtSize returns the tree size whose root the proof verifies against. An inclusion proof only verifies against the root of the exact tree size it was generated for, not against later roots. The leaf index and path shape alone are ambiguous, e.g. leaf 0 has the same path shape in trees of 3 and 4 leaves, so the size is taken from TreeSize and checked against the shape. It returns 0 if the proof doesn't record a size or its shape doesn't fit it.
+func (p *InclusionProof) ValidAtSize() int {
+	if p.TreeSize <= 0 || p.LeafIndex < 0 || p.LeafIndex >= p.TreeSize || len(p.Siblings) != len(p.Left) {
+		return 0
+	}
+
+	left, err := ctPathDirections(p.LeafIndex, p.TreeSize, len(p.Siblings))
+	if err != nil || !slices.Equal(left, p.Left) {
+		return 0
+	}
+	return p.TreeSize
 }
 
 // GenerateInclusionProof generates an inclusion proof for the leaf at the specified index in the Merkle Tree.
@@ -21,12 +97,12 @@ func (t *Tree) GenerateInclusionProof(index int) (*InclusionProof, error) {
 	return t.generateInclusionProofLocked(index)
 }
 
-// GenerateInclusionProofByData generates an inclusion proof for the first occurrence of the specified leaf data in the Merkle Tree.
+// GenerateInclusionProofByData generates an inclusion proof for the first occurrence of the specified leaf data in the Merkle Tree. The index the data resolved to is the proof's LeafIndex.
 func (t *Tree) GenerateInclusionProofByData(data []byte) (*InclusionProof, error) {
 	t.lock.RLock()
 	defer t.lock.RUnlock()
 
-	leafHash := HashLeafData(data, t.hashFunc)
+	leafHash := t.domain.HashLeaf(data, t.hashFuncOrDefault())
 	indices := t.indexMap[hex.EncodeToString(leafHash)]
 	if len(indices) == 0 {
 		return nil, errors.New("leaf not found in the tree")
@@ -35,13 +111,112 @@ func (t *Tree) GenerateInclusionProofByData(data []byte) (*InclusionProof, error
 	return t.generateInclusionProofLocked(indices[0]) // generate proof for the first occurrence of the leaf (if duplicates exist)
 }
 
+// GenerateInclusionProofsByData generates an inclusion proof for every occurrence of the specified leaf data in the Merkle Tree, in ascending index order, see IndicesOf. It returns an error only if the data is not in the tree.
+func (t *Tree) GenerateInclusionProofsByData(data []byte) ([]*InclusionProof, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	leafHash := t.domain.HashLeaf(data, t.hashFuncOrDefault())
+	indices := t.indexMap[hex.EncodeToString(leafHash)]
+	if len(indices) == 0 {
+		return nil, errors.New("leaf not found in the tree")
+	}
+
+	proofs := make([]*InclusionProof, 0, len(indices))
+	for _, index := range indices {
+		proof, err := t.generateInclusionProofLocked(index)
+		if err != nil {
+			return nil, err
+		}
+		proofs = append(proofs, proof)
+	}
+	return proofs, nil
+}
+
+// GenerateInclusionProofAtSize generates an inclusion proof for the leaf at the specified index against the historic root of the tree when it had the given number of leaves.
+func (t *Tree) GenerateInclusionProofAtSize(index int, size int) (*InclusionProof, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.generateInclusionProofAtSizeLocked(index, size)
+}
+
+// GenerateInclusionProofByDataAtSize generates an inclusion proof for the first occurrence of the specified leaf data against the historic root of the tree when it had the given number of leaves. It returns an error if the data was only appended after that size.
+func (t *Tree) GenerateInclusionProofByDataAtSize(data []byte, size int) (*InclusionProof, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	leafHash := t.domain.HashLeaf(data, t.hashFuncOrDefault())
+	indices := t.indexMap[hex.EncodeToString(leafHash)]
+	if len(indices) == 0 {
+		return nil, errors.New("leaf not found in the tree")
+	}
+	if indices[0] >= size { // indices are ascending, so the first one is the earliest occurrence
+		return nil, errors.New("leaf not present in the tree at the given size")
+	}
+
+	return t.generateInclusionProofAtSizeLocked(indices[0], size)
+}
+
+// generateInclusionProofAtSizeLocked generates an inclusion proof for the leaf at the specified index in the tree of the first size leaves, following the RFC 6962 PATH algorithm. It assumes the caller has already acquired the read lock.
+func (t *Tree) generateInclusionProofAtSizeLocked(index int, size int) (*InclusionProof, error) {
+	if size <= 0 || size > len(t.leaves) {
+		return nil, errors.New("invalid size: must be between 1 and the number of leaves")
+	}
+	if index < 0 || index >= size {
+		return nil, errors.New("invalid index")
+	}
+
+	var siblings [][]byte
+	var left []bool
+	t.historicPath(index, 0, size, &siblings, &left)
+
+	return &InclusionProof{LeafIndex: index, TreeSize: size, HashAlgorithm: t.hashName, Siblings: siblings, Left: left}, nil
+}
+
+// historicPath recursively collects the siblings on the path from the leaf at index to the root of the subtree over n leaves starting at start. Siblings are collected from the leaf upwards.
+func (t *Tree) historicPath(index int, start int, n int, siblings *[][]byte, left *[]bool) {
+	if n == 1 {
+		return
+	}
+
+	k := largestPowerOfTwoLessThan(n)
+	if index < start+k { // leaf is in the left subtree, sibling is the right subtree
+		t.historicPath(index, start, k, siblings, left)
+		*siblings = append(*siblings, t.historicSubtreeHash(start+k, n-k))
+		*left = append(*left, false)
+		return
+	}
+	// leaf is in the right subtree, sibling is the left subtree
+	t.historicPath(index, start+k, n-k, siblings, left)
+	*siblings = append(*siblings, t.subtreeHash(start, k))
+	*left = append(*left, true)
+}
+
+// LatestInclusionProof generates an inclusion proof for the most recently appended leaf. It returns the leaf index, the proof, and the root hash the proof verifies against, all read atomically under the read lock.
+func (t *Tree) LatestInclusionProof() (int, *InclusionProof, []byte, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if len(t.leaves) == 0 || t.root == nil {
+		return 0, nil, nil, errors.New("tree is empty")
+	}
+
+	index := len(t.leaves) - 1
+	proof, err := t.generateInclusionProofLocked(index)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	return index, proof, t.root.Hash, nil
+}
+
 // generateInclusionProofLocked is the internal method that generates an inclusion proof for the leaf at the specified index. It assumes the caller has already acquired the read lock.
 func (t *Tree) generateInclusionProofLocked(index int) (*InclusionProof, error) {
-	if index < 0 || index >= len(t.Leaves) {
+	if index < 0 || index >= len(t.leaves) {
 		return nil, errors.New("invalid index")
 	}
 
-	leaf := t.Leaves[index]
+	leaf := t.leaves[index]
 	current := leaf
 
 	var siblings [][]byte
@@ -59,37 +234,140 @@ func (t *Tree) generateInclusionProofLocked(index int) (*InclusionProof, error)
 		current = parent // move up to the parent for the next iteration
 	}
 
-	proof := &InclusionProof{Siblings: siblings, Left: left}
+	proof := &InclusionProof{LeafIndex: index, TreeSize: len(t.leaves), HashAlgorithm: t.hashName, Siblings: siblings, Left: left}
 	return proof, nil
 }
 
+// Errors returned by VerifyInclusionProofErr describing why a proof was rejected.
+var (
+	ErrProofMissingInput  = errors.New("missing proof, leaf data or root hash")
+	ErrProofDirections    = errors.New("proof siblings and directions differ in length")
+	ErrProofSiblingLength = errors.New("proof sibling length does not match the hash size")
+	ErrProofRootMismatch  = errors.New("computed root does not match the expected root")
+	ErrProofHashAlgorithm = errors.New("hash function does not match the proof's hash algorithm")
+)
+
 // VerifyInclusionProof verifies that the provided leaf data is included in the Merkle Tree with the given root hash using the provided inclusion proof.
 func VerifyInclusionProof(leafData []byte, proof *InclusionProof, rootHash []byte, hashFunc hash.Func) bool {
-	if proof == nil {
-		return false
+	return VerifyInclusionProofErr(leafData, proof, rootHash, hashFunc) == nil
+}
+
+// VerifyInclusionProofErr verifies an inclusion proof like VerifyInclusionProof, but returns an error telling why the proof was rejected.
+// It returns ErrProofMissingInput if the proof, leaf data or root hash is missing, ErrProofHashAlgorithm if the proof declares a hash algorithm other than hashFunc, ErrProofDirections if the siblings and directions differ in length, ErrProofSiblingLength if a sibling isn't as long as the leaf hash, and ErrProofRootMismatch if the computed root differs from the expected root.
+func VerifyInclusionProofErr(leafData []byte, proof *InclusionProof, rootHash []byte, hashFunc hash.Func) error {
+	return verifyInclusionProof(leafData, proof, rootHash, hashFunc, DomainParams{}, false)
+}
+
+// VerifyInclusionProofWithParams verifies an inclusion proof like VerifyInclusionProof for a tree built with the given domain separation prefixes, see WithDomainParams.
+func VerifyInclusionProofWithParams(leafData []byte, proof *InclusionProof, rootHash []byte, hashFunc hash.Func, params DomainParams) bool {
+	return verifyInclusionProof(leafData, proof, rootHash, hashFunc, params, false) == nil
+}
+
+// VerifyInclusionProofAllowEmpty verifies an inclusion proof like VerifyInclusionProof, but accepts nil or empty leaf data, for trees created with AllowEmptyLeaves. The other verifiers treat empty leaf data as missing input, so an empty leaf is only accepted when the caller opts in by using this function.
+func VerifyInclusionProofAllowEmpty(leafData []byte, proof *InclusionProof, rootHash []byte, hashFunc hash.Func) bool {
+	return verifyInclusionProof(leafData, proof, rootHash, hashFunc, DomainParams{}, true) == nil
+}
+
+// verifyInclusionProof implements VerifyInclusionProofErr for the given domain separation prefixes, treating empty leaf data as missing input unless allowEmpty is set.
+func verifyInclusionProof(leafData []byte, proof *InclusionProof, rootHash []byte, hashFunc hash.Func, params DomainParams, allowEmpty bool) error {
+	if proof == nil || (len(leafData) == 0 && !allowEmpty) || len(rootHash) == 0 {
+		return ErrProofMissingInput
 	}
 
-	if len(proof.Siblings) != len(proof.Left) {
-		return false
+	if hashFunc == nil {
+		hashFunc = hash.DefaultHashFunc
+	}
+	if err := checkHashAlgorithm(proof.HashAlgorithm, hashFunc); err != nil {
+		return err
 	}
 
-	if len(leafData) == 0 || len(rootHash) == 0 {
-		return false
+	computedRoot, err := proof.computeRoot(leafData, hashFunc, params)
+	if err != nil {
+		return err
+	}
+	if !RootsEqual(computedRoot, rootHash) {
+		return ErrProofRootMismatch
+	}
+	return nil
+}
+
+// checkHashAlgorithm returns ErrProofHashAlgorithm if a proof declares a hash algorithm that is unknown or differs from hashFunc. Proofs without a declared algorithm are accepted with any hash function.
+func checkHashAlgorithm(algorithm string, hashFunc hash.Func) error {
+	if algorithm == "" {
+		return nil
+	}
+	declared, err := hash.ByName(algorithm)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrProofHashAlgorithm, err)
+	}
+	if !hash.Same(declared, hashFunc) {
+		return fmt.Errorf("%w: proof uses %s", ErrProofHashAlgorithm, algorithm)
+	}
+	return nil
+}
+
+// ComputeRoot folds the leaf data with the proof's siblings and returns the resulting root hash, leaving the comparison to the caller, e.g. to match it against several candidate roots. It returns nil if the proof is malformed, see VerifyInclusionProofErr.
+func (p *InclusionProof) ComputeRoot(leafData []byte, hashFunc hash.Func) []byte {
+	root, err := p.computeRoot(leafData, hashFunc, DomainParams{})
+	if err != nil {
+		return nil
+	}
+	return root
+}
+
+// computeRoot folds the leaf data with the proof's siblings up to the root, returning ErrProofDirections or ErrProofSiblingLength for a malformed proof.
+func (p *InclusionProof) computeRoot(leafData []byte, hashFunc hash.Func, params DomainParams) ([]byte, error) {
+	if len(p.Siblings) != len(p.Left) {
+		return nil, fmt.Errorf("%w: %d siblings, %d directions", ErrProofDirections, len(p.Siblings), len(p.Left))
 	}
 
 	if hashFunc == nil {
 		hashFunc = hash.DefaultHashFunc
 	}
 
-	hashValue := hashFunc(append([]byte{0x00}, leafData...))
+	hashValue := params.HashLeaf(leafData, hashFunc)
+	hashSize := len(hashValue)
 
-	for i, siblingHash := range proof.Siblings { // iterate through the proof and compute the hashValue up to the root
-		if proof.Left[i] { // sibling is on the left
-			hashValue = hashFunc(append([]byte{0x01}, append(siblingHash, hashValue...)...))
+	for i, siblingHash := range p.Siblings { // iterate through the proof and compute the hashValue up to the root
+		if len(siblingHash) != hashSize {
+			return nil, fmt.Errorf("%w: sibling %d has %d bytes, want %d", ErrProofSiblingLength, i, len(siblingHash), hashSize)
+		}
+		if p.Left[i] { // sibling is on the left
+			hashValue = params.HashNode(siblingHash, hashValue, hashFunc)
 		} else { // sibling is on the right
-			hashValue = hashFunc(append([]byte{0x01}, append(hashValue, siblingHash...)...))
+			hashValue = params.HashNode(hashValue, siblingHash, hashFunc)
 		}
 	}
+	return hashValue, nil
+}
+
+// VerifyInclusionProofWithSize verifies an inclusion proof like VerifyInclusionProof against the root of a tree with the given number of leaves. It additionally rejects proofs whose recorded tree size differs from size or whose sibling path doesn't have the length and directions implied by the leaf index and size, so a malformed proof can't pass for a leaf at another position.
+func VerifyInclusionProofWithSize(leafData []byte, proof *InclusionProof, rootHash []byte, size int, hashFunc hash.Func) bool {
+	if proof == nil || proof.TreeSize != size || proof.ValidAtSize() != size {
+		return false
+	}
+	return VerifyInclusionProof(leafData, proof, rootHash, hashFunc)
+}
+
+// VerifyInclusionProofCompat verifies an inclusion proof like VerifyInclusionProof, but additionally tolerates the differing single-leaf root conventions found across RFC 6962 implementations.
+// For a proof without siblings (a single-leaf tree), the root is accepted either as the RFC 6962 leaf hash H(0x00||data) or as the plain hash H(data) used by some minimal logs. Proofs with siblings are verified strictly, and the proof's hash algorithm is checked for both conventions.
+func VerifyInclusionProofCompat(leafData []byte, proof *InclusionProof, rootHash []byte, hashFunc hash.Func) bool {
+	if VerifyInclusionProof(leafData, proof, rootHash, hashFunc) {
+		return true
+	}
 
-	return bytes.Equal(hashValue, rootHash)
+	if proof == nil || len(proof.Siblings) != 0 || len(proof.Left) != 0 {
+		return false
+	}
+	if len(leafData) == 0 || len(rootHash) == 0 {
+		return false
+	}
+
+	if hashFunc == nil {
+		hashFunc = hash.DefaultHashFunc
+	}
+	if checkHashAlgorithm(proof.HashAlgorithm, hashFunc) != nil {
+		return false
+	}
+	return RootsEqual(hashFunc(leafData), rootHash) // single leaf hashed without the domain separation prefix
 }
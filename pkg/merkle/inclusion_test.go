@@ -1,6 +1,11 @@
 package merkle
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
 	"testing"
 
 	"github.com/andrlikjirka/dp-teals/pkg/hash"
@@ -154,7 +159,7 @@ func TestGenerateInclusionProofByData(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tree, err := NewTree(tt.data, nil)
+			tree, err := NewTree(tt.data, nil, AllowEmptyLeaves())
 			if err != nil {
 				t.Fatalf("Failed to create tree: %v", err)
 			}
@@ -173,6 +178,72 @@ func TestGenerateInclusionProofByData(t *testing.T) {
 	}
 }
 
+func TestGenerateInclusionProofByData_LeafIndex(t *testing.T) {
+	data := [][]byte{[]byte("a"), []byte("b"), []byte("dup"), []byte("c"), []byte("dup"), []byte("d")}
+	tree, _ := NewTree(data, nil)
+
+	for _, tt := range []struct {
+		data      string
+		wantIndex int
+	}{{"a", 0}, {"c", 3}, {"d", 5}, {"dup", 2}} {
+		proof, err := tree.GenerateInclusionProofByData([]byte(tt.data))
+		if err != nil {
+			t.Fatalf("GenerateInclusionProofByData(%q) unexpected error: %v", tt.data, err)
+		}
+		if proof.LeafIndex != tt.wantIndex {
+			t.Errorf("GenerateInclusionProofByData(%q).LeafIndex = %d, want %d", tt.data, proof.LeafIndex, tt.wantIndex)
+		}
+		if got, _ := tree.GenerateInclusionProof(proof.LeafIndex); !slices.EqualFunc(got.Siblings, proof.Siblings, bytes.Equal) {
+			t.Errorf("proof by data %q differs from the proof for index %d", tt.data, proof.LeafIndex)
+		}
+	}
+}
+
+func TestGenerateInclusionProofsByData(t *testing.T) {
+	data := [][]byte{
+		[]byte("dup"), []byte("a"), []byte("dup"), []byte("b"), []byte("c"), []byte("dup"),
+	}
+	tree, err := NewTree(data, nil)
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	root := tree.RootHash()
+
+	t.Run("all duplicates", func(t *testing.T) {
+		proofs, err := tree.GenerateInclusionProofsByData([]byte("dup"))
+		if err != nil {
+			t.Fatalf("GenerateInclusionProofsByData() unexpected error: %v", err)
+		}
+		if len(proofs) != 3 {
+			t.Fatalf("GenerateInclusionProofsByData() returned %d proofs, want 3", len(proofs))
+		}
+		for i, want := range []int{0, 2, 5} {
+			if proofs[i].LeafIndex != want {
+				t.Errorf("proofs[%d].LeafIndex = %d, want %d", i, proofs[i].LeafIndex, want)
+			}
+			if !VerifyInclusionProof([]byte("dup"), proofs[i], root, nil) {
+				t.Errorf("proof for index %d does not verify", proofs[i].LeafIndex)
+			}
+		}
+	})
+
+	t.Run("single occurrence", func(t *testing.T) {
+		proofs, err := tree.GenerateInclusionProofsByData([]byte("b"))
+		if err != nil {
+			t.Fatalf("GenerateInclusionProofsByData() unexpected error: %v", err)
+		}
+		if len(proofs) != 1 || proofs[0].LeafIndex != 3 {
+			t.Errorf("GenerateInclusionProofsByData() = %d proofs, want one for index 3", len(proofs))
+		}
+	})
+
+	t.Run("data absent", func(t *testing.T) {
+		if _, err := tree.GenerateInclusionProofsByData([]byte("missing")); err == nil {
+			t.Error("GenerateInclusionProofsByData() expected error for missing data, got nil")
+		}
+	})
+}
+
 func TestVerifyInclusionProof(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -271,6 +342,48 @@ func TestVerifyInclusionProof(t *testing.T) {
 	}
 }
 
+func TestVerifyInclusionProofErr(t *testing.T) {
+	tree, _ := NewTree([][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")}, nil)
+	root := tree.RootHash()
+	proof, _ := tree.GenerateInclusionProof(2)
+
+	tamperedSibling := &InclusionProof{Siblings: [][]byte{bytes.Clone(proof.Siblings[0]), proof.Siblings[1], proof.Siblings[2]}, Left: proof.Left}
+	tamperedSibling.Siblings[0][0] ^= 0xff
+	flippedDirection := &InclusionProof{Siblings: proof.Siblings, Left: []bool{!proof.Left[0], proof.Left[1], proof.Left[2]}}
+
+	tests := []struct {
+		name     string
+		leafData []byte
+		proof    *InclusionProof
+		root     []byte
+		wantErr  error
+	}{
+		{"valid proof", []byte("c"), proof, root, nil},
+		{"nil proof", []byte("c"), nil, root, ErrProofMissingInput},
+		{"empty leaf data", nil, proof, root, ErrProofMissingInput},
+		{"empty root", []byte("c"), proof, nil, ErrProofMissingInput},
+		{"directions shorter than siblings", []byte("c"), &InclusionProof{Siblings: proof.Siblings, Left: proof.Left[:2]}, root, ErrProofDirections},
+		{"truncated sibling", []byte("c"), &InclusionProof{Siblings: [][]byte{proof.Siblings[0][:16], proof.Siblings[1], proof.Siblings[2]}, Left: proof.Left}, root, ErrProofSiblingLength},
+		{"5-byte sibling", []byte("c"), &InclusionProof{Siblings: [][]byte{proof.Siblings[0], []byte("short"), proof.Siblings[2]}, Left: proof.Left}, root, ErrProofSiblingLength},
+		{"wrong leaf data", []byte("x"), proof, root, ErrProofRootMismatch},
+		{"tampered sibling", []byte("c"), tamperedSibling, root, ErrProofRootMismatch},
+		{"flipped direction", []byte("c"), flippedDirection, root, ErrProofRootMismatch},
+		{"wrong root", []byte("c"), proof, make([]byte, 32), ErrProofRootMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyInclusionProofErr(tt.leafData, tt.proof, tt.root, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyInclusionProofErr() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := VerifyInclusionProof(tt.leafData, tt.proof, tt.root, nil); got != (tt.wantErr == nil) {
+				t.Errorf("VerifyInclusionProof() = %v, want %v", got, tt.wantErr == nil)
+			}
+		})
+	}
+}
+
 func TestVerifyInclusionProofConsistency(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -428,3 +541,460 @@ func TestVerifyInclusionProofWithModifiedProof(t *testing.T) {
 		})
 	}
 }
+
+func TestLatestInclusionProof(t *testing.T) {
+	data := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	tree, _ := NewTree(data, nil)
+
+	appended := []byte("d")
+	if err := tree.Append(appended); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	index, proof, root, err := tree.LatestInclusionProof()
+	if err != nil {
+		t.Fatalf("LatestInclusionProof() unexpected error: %v", err)
+	}
+	if index != len(tree.leaves)-1 {
+		t.Errorf("LatestInclusionProof() index = %d, want %d", index, len(tree.leaves)-1)
+	}
+	if !bytes.Equal(root, tree.RootHash()) {
+		t.Errorf("LatestInclusionProof() root = %x, want %x", root, tree.RootHash())
+	}
+	if !VerifyInclusionProof(appended, proof, root, nil) {
+		t.Error("LatestInclusionProof() returned a proof that does not verify")
+	}
+}
+
+func TestLatestInclusionProof_EmptyTree(t *testing.T) {
+	tree := &Tree{}
+	if _, _, _, err := tree.LatestInclusionProof(); err == nil {
+		t.Error("LatestInclusionProof() expected error for empty tree, got nil")
+	}
+}
+
+func TestVerifyInclusionProofCompat_SingleLeaf(t *testing.T) {
+	data := []byte("only")
+	tree, _ := NewTree([][]byte{data}, nil)
+	proof, _ := tree.GenerateInclusionProof(0)
+
+	tests := []struct {
+		name       string
+		root       []byte
+		wantStrict bool
+		wantCompat bool
+	}{
+		{"RFC 6962 prefixed leaf hash", tree.RootHash(), true, true},
+		{"plain hash of the data", hash.DefaultHashFunc(data), false, true},
+		{"unrelated root", hash.DefaultHashFunc([]byte("other")), false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyInclusionProof(data, proof, tt.root, nil); got != tt.wantStrict {
+				t.Errorf("VerifyInclusionProof() = %v, want %v", got, tt.wantStrict)
+			}
+			if got := VerifyInclusionProofCompat(data, proof, tt.root, nil); got != tt.wantCompat {
+				t.Errorf("VerifyInclusionProofCompat() = %v, want %v", got, tt.wantCompat)
+			}
+		})
+	}
+}
+
+func TestVerifyInclusionProofCompat_SingleLeafChecksHashAlgorithm(t *testing.T) {
+	data := []byte("only")
+	tree, _ := NewTree([][]byte{data}, nil)
+	proof, _ := tree.GenerateInclusionProof(0)
+	plainRoot := hash.DefaultHashFunc(data)
+
+	if !VerifyInclusionProofCompat(data, proof, plainRoot, nil) {
+		t.Fatal("VerifyInclusionProofCompat() rejected the plain hash root of a proof declaring the matching algorithm")
+	}
+	mismatched := *proof
+	mismatched.HashAlgorithm = "sha512"
+	if VerifyInclusionProofCompat(data, &mismatched, plainRoot, nil) {
+		t.Error("VerifyInclusionProofCompat() accepted a proof declaring a different hash algorithm through the plain hash fallback")
+	}
+}
+
+func TestVerifyInclusionProofCompat_MultiLeafIsStrict(t *testing.T) {
+	data := [][]byte{[]byte("a"), []byte("b")}
+	tree, _ := NewTree(data, nil)
+	proof, _ := tree.GenerateInclusionProof(0)
+
+	if !VerifyInclusionProofCompat(data[0], proof, tree.RootHash(), nil) {
+		t.Error("VerifyInclusionProofCompat() rejected a valid proof")
+	}
+	if VerifyInclusionProofCompat(data[0], proof, hash.DefaultHashFunc(data[0]), nil) {
+		t.Error("VerifyInclusionProofCompat() accepted the plain leaf hash as root of a multi-leaf tree")
+	}
+}
+
+func TestGenerateInclusionProofAtSize(t *testing.T) {
+	var data [][]byte
+	for i := 0; i < 13; i++ {
+		data = append(data, []byte{byte('a' + i)})
+	}
+	tree, _ := NewTree(data, nil)
+
+	for size := 1; size <= len(data); size++ {
+		historic, _ := NewTree(data[:size], nil)
+		for index := 0; index < size; index++ {
+			proof, err := tree.GenerateInclusionProofAtSize(index, size)
+			if err != nil {
+				t.Fatalf("GenerateInclusionProofAtSize(%d, %d) unexpected error: %v", index, size, err)
+			}
+			if !VerifyInclusionProof(data[index], proof, historic.RootHash(), nil) {
+				t.Errorf("proof for index %d at size %d does not verify against the historic root", index, size)
+			}
+		}
+	}
+}
+
+func TestGenerateInclusionProofByDataAtSize(t *testing.T) {
+	data := [][]byte{
+		[]byte("a"), []byte("b"), []byte("c"), []byte("d"),
+		[]byte("e"), []byte("target"), []byte("g"),
+	}
+	tree, _ := NewTree(data, nil)
+
+	t.Run("data appended after size", func(t *testing.T) {
+		if _, err := tree.GenerateInclusionProofByDataAtSize([]byte("target"), 4); err == nil {
+			t.Error("GenerateInclusionProofByDataAtSize() expected error at size 4, got nil")
+		}
+	})
+
+	t.Run("data present at size", func(t *testing.T) {
+		historic, _ := NewTree(data[:6], nil)
+		proof, err := tree.GenerateInclusionProofByDataAtSize([]byte("target"), 6)
+		if err != nil {
+			t.Fatalf("GenerateInclusionProofByDataAtSize() unexpected error: %v", err)
+		}
+		if !VerifyInclusionProof([]byte("target"), proof, historic.RootHash(), nil) {
+			t.Error("proof at size 6 does not verify against the historic root")
+		}
+	})
+
+	t.Run("data absent", func(t *testing.T) {
+		if _, err := tree.GenerateInclusionProofByDataAtSize([]byte("missing"), 6); err == nil {
+			t.Error("GenerateInclusionProofByDataAtSize() expected error for missing data, got nil")
+		}
+	})
+
+	t.Run("size out of range", func(t *testing.T) {
+		if _, err := tree.GenerateInclusionProofByDataAtSize([]byte("a"), 8); err == nil {
+			t.Error("GenerateInclusionProofByDataAtSize() expected error for size beyond tree, got nil")
+		}
+	})
+}
+
+func TestInclusionProofJSON_RoundTrip(t *testing.T) {
+	data := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")}
+	tree, _ := NewTree(data, nil)
+
+	for index := range data {
+		proof, _ := tree.GenerateInclusionProof(index)
+
+		encoded, err := json.Marshal(proof)
+		if err != nil {
+			t.Fatalf("json.Marshal() unexpected error: %v", err)
+		}
+
+		var decoded InclusionProof
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			t.Fatalf("json.Unmarshal() unexpected error: %v", err)
+		}
+
+		if decoded.LeafIndex != index {
+			t.Errorf("decoded LeafIndex = %d, want %d", decoded.LeafIndex, index)
+		}
+		if !VerifyInclusionProof(data[index], &decoded, tree.RootHash(), nil) {
+			t.Errorf("decoded proof for index %d does not verify", index)
+		}
+	}
+}
+
+func TestInclusionProofJSON_Format(t *testing.T) {
+	proof := &InclusionProof{LeafIndex: 1, TreeSize: 2, Siblings: [][]byte{{0xab, 0xcd}}, Left: []bool{true}}
+
+	encoded, err := json.Marshal(proof)
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error: %v", err)
+	}
+
+	expected := `{"version":1,"leaf_index":1,"tree_size":2,"siblings":["abcd"],"left":[true]}`
+	if string(encoded) != expected {
+		t.Errorf("got  %s\nwant %s", encoded, expected)
+	}
+}
+
+func TestInclusionProofJSON_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+	}{
+		{"more siblings than directions", `{"leaf_index":0,"siblings":["abcd","ef01"],"left":[true]}`},
+		{"more directions than siblings", `{"leaf_index":0,"siblings":["abcd"],"left":[true,false]}`},
+		{"invalid hex sibling", `{"leaf_index":0,"siblings":["zz"],"left":[true]}`},
+		{"negative leaf index", `{"leaf_index":-1,"siblings":[],"left":[]}`},
+		{"malformed json", `{"leaf_index":`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var proof InclusionProof
+			if err := json.Unmarshal([]byte(tt.payload), &proof); err == nil {
+				t.Error("json.Unmarshal() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestInclusionProofJSON_Version(t *testing.T) {
+	tests := []struct {
+		name        string
+		payload     string
+		wantVersion bool // whether decoding fails with ErrUnsupportedProofVersion
+	}{
+		{"v1", `{"version":1,"leaf_index":1,"tree_size":2,"siblings":["abcd"],"left":[true]}`, false},
+		{"without version", `{"leaf_index":1,"tree_size":2,"siblings":["abcd"],"left":[true]}`, false},
+		{"v99", `{"version":99,"leaf_index":1,"tree_size":2,"siblings":["abcd"],"left":[true]}`, true},
+		{"v0", `{"version":0,"leaf_index":1,"tree_size":2,"siblings":["abcd"],"left":[true]}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var proof InclusionProof
+			err := json.Unmarshal([]byte(tt.payload), &proof)
+			if tt.wantVersion {
+				if !errors.Is(err, ErrUnsupportedProofVersion) {
+					t.Errorf("json.Unmarshal() error = %v, want %v", err, ErrUnsupportedProofVersion)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("json.Unmarshal() unexpected error: %v", err)
+			}
+			if proof.LeafIndex != 1 || proof.TreeSize != 2 || len(proof.Siblings) != 1 {
+				t.Errorf("json.Unmarshal() = %+v, want leaf 1 of 2 with one sibling", proof)
+			}
+		})
+	}
+}
+
+func TestInclusionProof_ValidAtSize(t *testing.T) {
+	tree := buildTestTree(t, 5)
+	var roots [][]byte
+	roots = append(roots, nil) // roots[k] is the root at size k
+	for k := 1; k <= 5; k++ {
+		roots = append(roots, buildTestTree(t, k).RootHash())
+	}
+
+	proof, err := tree.GenerateInclusionProof(2)
+	if err != nil {
+		t.Fatalf("GenerateInclusionProof() unexpected error: %v", err)
+	}
+	if got := proof.ValidAtSize(); got != 5 {
+		t.Errorf("ValidAtSize() = %d, want 5", got)
+	}
+
+	for k := 3; k <= 5; k++ {
+		want := k == 5
+		if got := VerifyInclusionProof([]byte("leaf2"), proof, roots[k], nil); got != want {
+			t.Errorf("VerifyInclusionProof() against root_%d = %v, want %v", k, got, want)
+		}
+	}
+
+	for i := 0; i < 8; i++ {
+		if err := tree.Append([]byte(fmt.Sprintf("leaf%d", 5+i))); err != nil {
+			t.Fatalf("Append() unexpected error: %v", err)
+		}
+		if VerifyInclusionProof([]byte("leaf2"), proof, tree.RootHash(), nil) {
+			t.Errorf("VerifyInclusionProof() against root_%d = true, want false", 6+i)
+		}
+	}
+}
+
+func TestInclusionProof_ValidAtSize_Invalid(t *testing.T) {
+	tree := buildTestTree(t, 6)
+	proof, _ := tree.GenerateInclusionProof(4)
+
+	tests := []struct {
+		name  string
+		proof *InclusionProof
+	}{
+		{"no tree size", &InclusionProof{LeafIndex: 4, Siblings: proof.Siblings, Left: proof.Left}},
+		{"index beyond size", &InclusionProof{LeafIndex: 6, TreeSize: 6, Siblings: proof.Siblings, Left: proof.Left}},
+		{"shape does not fit size", &InclusionProof{LeafIndex: 4, TreeSize: 16, Siblings: proof.Siblings, Left: proof.Left}},
+		{"wrong directions", &InclusionProof{LeafIndex: 4, TreeSize: 6, Siblings: proof.Siblings, Left: []bool{true, true}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.proof.ValidAtSize(); got != 0 {
+				t.Errorf("ValidAtSize() = %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestVerifyInclusionProofWithSize(t *testing.T) {
+	tree := buildTestTree(t, 6)
+	root := tree.RootHash()
+	proof, _ := tree.GenerateInclusionProof(4)
+
+	tests := []struct {
+		name  string
+		proof *InclusionProof
+		size  int
+		want  bool
+	}{
+		{"valid proof", proof, 6, true},
+		{"nil proof", nil, 6, false},
+		{"size differs from proof", proof, 7, false},
+		{"wrong leaf index", &InclusionProof{LeafIndex: 5, TreeSize: 6, Siblings: proof.Siblings, Left: proof.Left}, 6, false},
+		{"path too long for size", &InclusionProof{LeafIndex: 4, TreeSize: 6, Siblings: append([][]byte{proof.Siblings[0]}, proof.Siblings...), Left: append([]bool{false}, proof.Left...)}, 6, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyInclusionProofWithSize([]byte("leaf4"), tt.proof, root, tt.size, nil); got != tt.want {
+				t.Errorf("VerifyInclusionProofWithSize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInclusionProof_ComputeRoot(t *testing.T) {
+	for size := 1; size <= 13; size++ {
+		tree := buildTestTree(t, size)
+		for i := 0; i < size; i++ {
+			proof, err := tree.GenerateInclusionProof(i)
+			if err != nil {
+				t.Fatalf("GenerateInclusionProof(%d) unexpected error: %v", i, err)
+			}
+			if got := proof.ComputeRoot([]byte(fmt.Sprintf("leaf%d", i)), nil); !bytes.Equal(got, tree.RootHash()) {
+				t.Errorf("size %d, leaf %d: ComputeRoot() = %x, want %x", size, i, got, tree.RootHash())
+			}
+		}
+	}
+}
+
+func TestInclusionProof_ComputeRoot_Malformed(t *testing.T) {
+	tree := buildTestTree(t, 4)
+	proof, _ := tree.GenerateInclusionProof(1)
+
+	malformed := &InclusionProof{Siblings: proof.Siblings, Left: proof.Left[:1]}
+	if got := malformed.ComputeRoot([]byte("leaf1"), nil); got != nil {
+		t.Errorf("ComputeRoot() = %x, want nil", got)
+	}
+}
+
+func TestVerifyInclusionProof_HashAlgorithmMismatch(t *testing.T) {
+	tree, _ := NewTree([][]byte{[]byte("a"), []byte("b"), []byte("c")}, hash.SHA512HashFunc)
+	proof, _ := tree.GenerateInclusionProof(1)
+	if proof.HashAlgorithm != "sha512" {
+		t.Fatalf("proof.HashAlgorithm = %q, want %q", proof.HashAlgorithm, "sha512")
+	}
+
+	tests := []struct {
+		name     string
+		proof    *InclusionProof
+		hashFunc hash.Func
+		wantErr  error
+	}{
+		{"matching function", proof, hash.SHA512HashFunc, nil},
+		{"different function", proof, hash.SHA256HashFunc, ErrProofHashAlgorithm},
+		{"nil defaults to SHA-256", proof, nil, ErrProofHashAlgorithm},
+		{"unknown algorithm", &InclusionProof{HashAlgorithm: "md5", Siblings: proof.Siblings, Left: proof.Left}, hash.SHA512HashFunc, ErrProofHashAlgorithm},
+		{"undeclared algorithm", &InclusionProof{Siblings: proof.Siblings, Left: proof.Left}, hash.SHA512HashFunc, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyInclusionProofErr([]byte("b"), tt.proof, tree.RootHash(), tt.hashFunc)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyInclusionProofErr() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateInclusionProof_HashAlgorithmResolvedOnce(t *testing.T) {
+	calls := 0
+	counting := func(data []byte) []byte {
+		calls++
+		return hash.SHA512HashFunc(data)
+	}
+	tree, _ := NewTree([][]byte{[]byte("a"), []byte("b"), []byte("c")}, counting)
+
+	calls = 0
+	proof, err := tree.GenerateInclusionProof(1)
+	if err != nil {
+		t.Fatalf("GenerateInclusionProof() unexpected error: %v", err)
+	}
+	if proof.HashAlgorithm != "sha512" {
+		t.Errorf("proof.HashAlgorithm = %q, want %q", proof.HashAlgorithm, "sha512")
+	}
+	if calls != 0 {
+		t.Errorf("GenerateInclusionProof() called the hash function %d times, want 0", calls)
+	}
+
+	var zero Tree
+	if err := zero.Append([]byte("a")); err != nil {
+		t.Fatalf("Append() unexpected error: %v", err)
+	}
+	if proof, _ := zero.GenerateInclusionProof(0); proof.HashAlgorithm != "sha256" {
+		t.Errorf("zero-value tree proof.HashAlgorithm = %q, want %q", proof.HashAlgorithm, "sha256")
+	}
+}
+
+func TestInclusionProof_JSONHashAlgorithm(t *testing.T) {
+	tree := buildTestTree(t, 3)
+	proof, _ := tree.GenerateInclusionProof(0)
+
+	data, err := json.Marshal(proof)
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error: %v", err)
+	}
+	var decoded InclusionProof
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() unexpected error: %v", err)
+	}
+	if decoded.HashAlgorithm != "sha256" {
+		t.Errorf("decoded HashAlgorithm = %q, want %q", decoded.HashAlgorithm, "sha256")
+	}
+}
+
+func TestVerifiers_RootComparison(t *testing.T) {
+	tree := buildTestTree(t, 7)
+	root := tree.RootHash()
+	oldRoot, _ := tree.RootAt(3)
+	inclusion, _ := tree.GenerateInclusionProof(4)
+	consistency, _ := tree.GenerateConsistencyProof(3)
+
+	lastByteFlipped := bytes.Clone(root)
+	lastByteFlipped[len(lastByteFlipped)-1] ^= 0x01
+
+	tests := []struct {
+		name string
+		root []byte
+		want bool
+	}{
+		{"matching root", root, true},
+		{"last byte differs", lastByteFlipped, false},
+		{"truncated root", root[:len(root)-1], false},
+		{"extended root", append(bytes.Clone(root), 0x00), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyInclusionProof([]byte("leaf4"), inclusion, tt.root, nil); got != tt.want {
+				t.Errorf("VerifyInclusionProof() = %v, want %v", got, tt.want)
+			}
+			if got := VerifyConsistencyProof(3, 7, oldRoot, tt.root, consistency, nil); got != tt.want {
+				t.Errorf("VerifyConsistencyProof() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
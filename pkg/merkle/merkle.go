@@ -1,9 +1,18 @@
 package merkle
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"math/bits"
+	"os"
+	"slices"
+	"strings"
 	"sync"
 
 	"github.com/andrlikjirka/dp-teals/pkg/hash"
@@ -11,57 +20,334 @@ import (
 
 type Node struct {
 	Hash   []byte
+	Data   []byte // Raw leaf data, only retained for leaves of trees created with KeepData
 	Left   *Node
 	Right  *Node
 	Parent *Node
 }
 
 type Tree struct {
-	root     *Node
-	Leaves   []*Node
-	indexMap map[string][]int // hash → indices
-	hashFunc hash.Func
-	lock     sync.RWMutex
+	root           *Node
+	rootHash       []byte // cached hash of root, replaced together with it by setRootLocked
+	leaves         []*Node
+	indexMap       map[string][]int // hash → indices, always in ascending order
+	hashFunc       hash.Func
+	hashName       string       // registered name of hashFunc recorded in proofs, resolved once when hashFunc is set
+	requirePerfect bool         // only allow leaf counts that are a power of two
+	keepData       bool         // retain the raw leaf data in the leaf nodes
+	sorted         bool         // keep the leaves in strictly ascending order of their data
+	allowEmpty     bool         // accept nil or empty leaf data
+	allowEmptyTree bool         // accept no data, starting with the RFC 6962 empty root
+	domain         DomainParams // leaf and node hash prefixes, the zero value are the RFC 6962 ones
+	lock           sync.RWMutex
+}
+
+// Option configures optional behavior of a Merkle Tree created by NewTree or one of the other constructors.
+type Option func(*Tree)
+
+// RequirePerfect makes the tree accept only leaf counts that are a power of two, i.e. perfect binary trees. NewTree errors unless the number of data items is a power of two, and Append errors unless the resulting leaf count is a power of two, leaving the tree unchanged. Single appends therefore only succeed when growing a 1-leaf tree to 2 leaves; larger perfect trees can't be grown one leaf at a time.
+func RequirePerfect() Option {
+	return func(t *Tree) {
+		t.requirePerfect = true
+	}
+}
+
+// KeepData makes the tree retain a copy of each leaf's raw data, so it can be read back with LeafData. By default only the leaf hashes are kept to save memory. Such trees can't import leaf hashes with ImportFrom, as the data of the imported leaves is unknown.
+func KeepData() Option {
+	return func(t *Tree) {
+		t.keepData = true
+	}
+}
+
+// AllowEmptyLeaves makes the tree accept nil or empty leaf data. By default empty leaves are rejected, matching the MMR. Proofs for empty leaves are verified with VerifyInclusionProofAllowEmpty.
+func AllowEmptyLeaves() Option {
+	return func(t *Tree) {
+		t.allowEmpty = true
+	}
+}
+
+// AllowEmptyTree makes NewTree accept no data and create an empty tree, e.g. for a log that starts empty and grows by Append. The root hash of the empty tree is the hash of the empty string, as defined by RFC 6962. By default NewTree requires at least one data item.
+func AllowEmptyTree() Option {
+	return func(t *Tree) {
+		t.allowEmptyTree = true
+	}
 }
 
 // NewTree creates a new Merkle Tree from the provided data.
-func NewTree(data [][]byte, hashFunc hash.Func) (*Tree, error) {
-	if len(data) == 0 {
-		return nil, errors.New("no data provided")
+func NewTree(data [][]byte, hashFunc hash.Func, opts ...Option) (*Tree, error) {
+	t, data, err := newTreeWithData(data, hashFunc, opts)
+	if err != nil {
+		return nil, err
 	}
+	t.build(data)
+	return t, nil
+}
 
+// newTree creates a tree with the hash function, or the default one if nil, and the options applied. It is shared by all constructors, which then validate and add their leaves. The tree is not built yet.
+func newTree(hashFunc hash.Func, opts []Option) (*Tree, error) {
 	if hashFunc == nil {
 		hashFunc = hash.DefaultHashFunc
 	}
 
-	t := build(data, hashFunc)
+	t := &Tree{hashFunc: hashFunc, hashName: hash.NameOf(hashFunc)}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if err := t.domain.validate(); err != nil {
+		return nil, err
+	}
 	return t, nil
 }
 
-// build constructs the Merkle Tree from the provided data.
-func build(data [][]byte, hashFunc hash.Func) *Tree {
+// newTreeWithData creates a tree like newTree and validates the data against its options, returning the data in leaf order.
+func newTreeWithData(data [][]byte, hashFunc hash.Func, opts []Option) (*Tree, [][]byte, error) {
+	t, err := newTree(hashFunc, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := t.checkLeafCount(len(data)); err != nil {
+		return nil, nil, err
+	}
+	if !t.allowEmpty && slices.ContainsFunc(data, func(d []byte) bool { return len(d) == 0 }) {
+		return nil, nil, errors.New("empty leaf not allowed")
+	}
+
+	if t.sorted {
+		data = slices.Clone(data) // don't reorder the caller's slice
+		slices.SortFunc(data, bytes.Compare)
+		for i := 1; i < len(data); i++ {
+			if bytes.Equal(data[i-1], data[i]) {
+				return nil, nil, errors.New("sorted tree requires distinct leaves")
+			}
+		}
+	}
+	return t, data, nil
+}
+
+// checkLeafCount validates the number of leaves a new tree is built with against its options.
+func (t *Tree) checkLeafCount(n int) error {
+	if n == 0 && !t.allowEmptyTree {
+		return errors.New("no data provided")
+	}
+	if t.requirePerfect && n > 0 && !isPowerOfTwo(n) {
+		return errors.New("leaf count must be a power of two")
+	}
+	return nil
+}
+
+// schemaPrefix is prepended to the schema ID hashed into the first leaf by NewTreeWithSchema. It differs from the RFC 6962 leaf and node prefixes, so the schema leaf can't be confused with a data leaf or an internal node.
+const schemaPrefix = 0x02
+
+// SchemaLeafHash returns the hash of the schema leaf NewTreeWithSchema commits to, the hash function applied to the 0x02 schema prefix followed by the schema ID. Verifiers can compare it to the first leaf hash of a tree to check its schema.
+func SchemaLeafHash(schemaID []byte, hashFunc hash.Func) []byte {
+	if hashFunc == nil {
+		hashFunc = hash.DefaultHashFunc
+	}
+	buf := make([]byte, 0, 1+len(schemaID))
+	buf = append(buf, schemaPrefix)
+	return hashFunc(append(buf, schemaID...))
+}
+
+// NewTreeWithSchema creates a new Merkle Tree like NewTree, but commits the schema ID into the root through a synthetic first leaf, see SchemaLeafHash. The schema leaf is hashed with its own domain prefix, so the same data under different schemas, or without a schema, produces different roots, proofs don't verify across schemas, and the schema ID can't be proven as a data leaf. The data items are shifted by one, so data[i] is the leaf at index i+1.
+// The options apply as in NewTree, except Sorted, as the schema leaf precedes the data. Custom domain params must not be prefixes of the schema prefix or vice versa.
+func NewTreeWithSchema(schemaID []byte, data [][]byte, hashFunc hash.Func, opts ...Option) (*Tree, error) {
+	if len(schemaID) == 0 {
+		return nil, errors.New("no schema ID provided")
+	}
+	if len(data) == 0 {
+		return nil, errors.New("no data provided")
+	}
+
+	t, err := newTree(hashFunc, opts)
+	if err != nil {
+		return nil, err
+	}
+	if t.sorted {
+		return nil, errors.New("cannot build a sorted tree with a schema")
+	}
+	leafPrefix, nodePrefix := t.domain.effective()
+	for _, prefix := range [][]byte{leafPrefix, nodePrefix} {
+		if bytes.HasPrefix(prefix, []byte{schemaPrefix}) || bytes.HasPrefix([]byte{schemaPrefix}, prefix) {
+			return nil, errors.New("domain prefixes must not overlap the schema prefix")
+		}
+	}
+	if err := t.checkLeafCount(len(data) + 1); err != nil {
+		return nil, err
+	}
+	if !t.allowEmpty && slices.ContainsFunc(data, func(d []byte) bool { return len(d) == 0 }) {
+		return nil, errors.New("empty leaf not allowed")
+	}
+
+	leaves := make([]*Node, 0, len(data)+1)
+	leaves = append(leaves, &Node{Hash: SchemaLeafHash(schemaID, t.hashFunc)})
+	for _, d := range data {
+		leaves = append(leaves, t.newLeafNode(t.domain.HashLeaf(d, t.hashFunc), d))
+	}
+	t.setLeaves(leaves, t.domain.nodeHasher(t.hashFunc))
+	return t, nil
+}
+
+// NewTreeFromLeafHashes creates a new Merkle Tree from precomputed leaf hashes, e.g. to mirror a remote log whose raw data isn't available. The hashes are used as the leaves as is, without hashing them with the leaf prefix, and only the internal nodes are computed. Every hash must have the output length of the hash function.
+// The options apply as in NewTree, except that Sorted and KeepData are rejected, as both need the leaf data.
+func NewTreeFromLeafHashes(hashes [][]byte, hashFunc hash.Func, opts ...Option) (*Tree, error) {
+	t, err := newTree(hashFunc, opts)
+	if err != nil {
+		return nil, err
+	}
+	if t.sorted || t.keepData {
+		return nil, errors.New("cannot build a tree that needs the leaf data from leaf hashes")
+	}
+	if len(hashes) == 0 && !t.allowEmptyTree {
+		return nil, errors.New("no leaf hashes provided")
+	}
+	if err := t.checkLeafCount(len(hashes)); err != nil {
+		return nil, err
+	}
+
+	size := len(t.hashFunc(nil))
+	leaves := make([]*Node, 0, len(hashes))
+	for i, h := range hashes {
+		if len(h) != size {
+			return nil, fmt.Errorf("leaf hash %d has length %d, want %d", i, len(h), size)
+		}
+		leaves = append(leaves, &Node{Hash: bytes.Clone(h)}) // don't share the caller's slices with the tree
+	}
+
+	t.setLeaves(leaves, t.domain.nodeHasher(t.hashFunc))
+	return t, nil
+}
+
+// maxRecordSize is the largest record NewTreeFromReader accepts.
+const maxRecordSize = 1 << 20
+
+// NewTreeFromReader creates a new Merkle Tree from newline-delimited records read from r, hashing each record as it is read so memory stays bounded by the leaf hashes rather than the input. Each line, without its "\n" or "\r\n" terminator, is the data of one leaf; the last line doesn't need a terminator. Records longer than 1 MiB are rejected.
+// The options apply as in NewTree: empty lines are rejected like empty leaves unless AllowEmptyLeaves is given, and the records are only retained with KeepData. A Sorted tree can't reorder a stream, so its records must already be in strictly ascending order.
+func NewTreeFromReader(r io.Reader, hashFunc hash.Func, opts ...Option) (*Tree, error) {
+	t, err := newTree(hashFunc, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+
 	var leaves []*Node
-	indexMap := make(map[string][]int)
-	// create leaf nodes
+	var prev []byte
+	for scanner.Scan() {
+		record := scanner.Bytes()
+		if err := t.checkLeafLocked(record, prev, len(leaves) > 0); err != nil { // t is not shared yet, no lock needed
+			return nil, fmt.Errorf("record %d: %w", len(leaves), err)
+		}
+
+		leaf := t.newLeafNode(t.domain.HashLeaf(record, t.hashFunc), record)
+		leaves = append(leaves, leaf)
+		prev = leaf.Data // retained for sorted trees, the scanner reuses its buffer
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("record %d: %w", len(leaves), err)
+	}
+	if err := t.checkLeafCount(len(leaves)); err != nil {
+		return nil, err
+	}
+
+	t.setLeaves(leaves, t.domain.nodeHasher(t.hashFunc))
+	return t, nil
+}
+
+// BuildWithProgress creates a new Merkle Tree from the provided data like NewTree, reporting the number of hashed leaves to the optional progress callback and aborting when the context is cancelled.
+// On cancellation it returns an error wrapping the context error, e.g. context.Canceled, which tells how many leaves were processed.
+func BuildWithProgress(ctx context.Context, data [][]byte, hashFunc hash.Func, progress func(done, total int), opts ...Option) (*Tree, error) {
+	t, data, err := newTreeWithData(data, hashFunc, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.buildWith(ctx, data, t.leafHasher(), t.domain.nodeHasher(t.hashFunc), progress); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// build constructs the Merkle Tree from the provided data.
+func (t *Tree) build(data [][]byte) {
+	_ = t.buildWith(context.Background(), data, t.leafHasher(), t.domain.nodeHasher(t.hashFunc), nil) // can't fail without cancellation
+}
+
+// leafHasher returns a function hashing leaf data with the domain params and hash function of the tree.
+func (t *Tree) leafHasher() func(data []byte) []byte {
+	return func(data []byte) []byte {
+		return t.domain.HashLeaf(data, t.hashFunc)
+	}
+}
+
+// buildWith constructs the Merkle Tree from the provided data, hashing the leaves and internal nodes with the given functions. It reports the number of hashed leaves to the optional progress callback, and stops with an error wrapping the context error once ctx is cancelled.
+func (t *Tree) buildWith(ctx context.Context, data [][]byte, hashLeaf func(data []byte) []byte, hashNode func(left, right []byte) []byte, progress func(done, total int)) error {
+	total := len(data)
+	leaves := make([]*Node, 0, total)
 	for i, d := range data {
-		leafHash := HashLeafData(d, hashFunc)
-		leaves = append(leaves, &Node{Hash: leafHash})
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("build aborted after %d of %d leaves: %w", i, total, err)
+		}
+		leaves = append(leaves, t.newLeafNode(hashLeaf(d), d))
+		if progress != nil {
+			progress(i+1, total)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("build aborted after %d of %d leaves: %w", total, total, err)
+	}
+
+	t.setLeaves(leaves, hashNode)
+	return nil
+}
 
-		hashHex := hex.EncodeToString(leafHash)
+// setLeaves makes the leaves the leaves of a tree under construction, indexing them and building the internal nodes over them with hashNode.
+func (t *Tree) setLeaves(leaves []*Node, hashNode func(left, right []byte) []byte) {
+	indexMap := make(map[string][]int)
+	for i, leaf := range leaves {
+		hashHex := hex.EncodeToString(leaf.Hash)
 		indexMap[hashHex] = append(indexMap[hashHex], i)
 	}
 
-	t := &Tree{
-		Leaves:   leaves,
-		indexMap: indexMap,
-		hashFunc: hashFunc,
-		root:     buildRecursive(leaves, hashFunc),
+	t.leaves = leaves
+	t.indexMap = indexMap
+	if len(leaves) == 0 {
+		t.setRootLocked(nil)
+		return
+	}
+	t.setRootLocked(buildRecursive(leaves, hashNode))
+}
+
+// newLeafNode creates a leaf node with the given hash, retaining a copy of the data if the tree keeps leaf data. Retained data is never nil, even for an empty leaf, as a nil Data means it wasn't retained.
+func (t *Tree) newLeafNode(leafHash []byte, data []byte) *Node {
+	leaf := &Node{Hash: leafHash}
+	if t.keepData {
+		leaf.Data = append([]byte{}, data...)
 	}
-	return t
+	return leaf
+}
+
+// LeafData returns a copy of the raw data of the leaf at the specified index. It returns an error if the index is invalid or the tree doesn't retain leaf data, see KeepData.
+func (t *Tree) LeafData(index int) ([]byte, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if index < 0 || index >= len(t.leaves) {
+		return nil, errors.New("invalid index")
+	}
+	data := t.leaves[index].Data
+	if data == nil {
+		return nil, errors.New("leaf data not retained")
+	}
+	return bytes.Clone(data), nil
+}
+
+// isPowerOfTwo reports whether n is a positive power of two.
+func isPowerOfTwo(n int) bool {
+	return n > 0 && n&(n-1) == 0
 }
 
 // buildRecursive builds the tree recursively from the given nodes and returns the root node. It implements the tree construction logic defined in RFC 6962 to construct deterministic append-only binary trees (avoid data padding).
-func buildRecursive(nodes []*Node, hashFunc hash.Func) *Node {
+func buildRecursive(nodes []*Node, hashNode func(left, right []byte) []byte) *Node {
 	n := len(nodes)
 	if n == 1 {
 		return nodes[0] // Base case: if only one node, return it
@@ -70,10 +356,10 @@ func buildRecursive(nodes []*Node, hashFunc hash.Func) *Node {
 	k := largestPowerOfTwoLessThan(n) // find the largest power of two less than n to determine how to split the nodes into left and right halves
 
 	// split the slice into left and right halves
-	left := buildRecursive(nodes[:k], hashFunc)
-	right := buildRecursive(nodes[k:], hashFunc)
+	left := buildRecursive(nodes[:k], hashNode)
+	right := buildRecursive(nodes[k:], hashNode)
 
-	parentHash := HashInternalNodes(left.Hash, right.Hash, hashFunc) // compute the parent hash by combining the left and right child hashes
+	parentHash := hashNode(left.Hash, right.Hash) // compute the parent hash by combining the left and right child hashes
 
 	parent := &Node{ // create a new parent node with the combined hash and set its children
 		Hash:  parentHash,
@@ -86,43 +372,424 @@ func buildRecursive(nodes []*Node, hashFunc hash.Func) *Node {
 	return parent
 }
 
-// RootHash returns the hash of the root node of the Merkle Tree.
+// RootHash returns the hash of the root node of the Merkle Tree, or the hash of the empty string for an empty tree created with AllowEmptyTree. The hash is cached whenever the tree is built or mutated, so this is O(1).
 func (t *Tree) RootHash() []byte {
 	t.lock.RLock()
 	defer t.lock.RUnlock()
+	return t.rootHash
+}
+
+// setRootLocked replaces the root node and the cached root hash, which must always change together. Without a root the hash is that of the empty tree, the hash of the empty string (RFC 6962), or nil for a zero-value tree without a hash function. It assumes the caller holds the write lock or has exclusive access to a tree under construction.
+func (t *Tree) setRootLocked(root *Node) {
+	t.root = root
+	t.rootHash = nil
+	switch {
+	case root != nil:
+		t.rootHash = root.Hash
+	case t.hashFunc != nil:
+		t.rootHash = t.hashFunc([]byte{})
+	}
+}
+
+// FilteredRoot returns the root hash of a tree built over only the leaves for which keep returns true, in their original order. It is a different commitment from the full root: it commits to a sub-log view of the tree, and proofs against the full root don't verify against it. It returns nil if no leaf is kept.
+func (t *Tree) FilteredRoot(keep func(index int) bool) []byte {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	var kept [][]byte
+	for i, leaf := range t.leaves {
+		if keep(i) {
+			kept = append(kept, leaf.Hash)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return rootFromLeafHashes(kept, t.hashFunc, t.domain)
+}
+
+// Size returns the number of leaves in the Merkle Tree.
+func (t *Tree) Size() int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return len(t.leaves)
+}
+
+// Range calls f for each leaf hash in index order until f returns false. The read lock is held for the whole iteration, so it sees a consistent snapshot and is safe to use during concurrent appends, but f must not modify the tree. The hashes are not copied and must not be modified.
+func (t *Tree) Range(f func(index int, leafHash []byte) bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	for i, leaf := range t.leaves {
+		if !f(i, leaf.Hash) {
+			return
+		}
+	}
+}
+
+// LeafHashes returns a snapshot of the leaf hashes in index order. The hashes are copied, so the result can be kept and modified while the tree is appended to.
+func (t *Tree) LeafHashes() [][]byte {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	hashes := make([][]byte, len(t.leaves))
+	for i, leaf := range t.leaves {
+		hashes[i] = bytes.Clone(leaf.Hash)
+	}
+	return hashes
+}
+
+// IndicesOf returns the indices of every leaf with the given data in ascending order, or nil if the data is not in the tree. The result is a copy, so it can be kept and modified while the tree changes.
+func (t *Tree) IndicesOf(data []byte) []int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	leafHash := t.domain.HashLeaf(data, t.hashFuncOrDefault())
+	return slices.Clone(t.indexMap[hex.EncodeToString(leafHash)])
+}
+
+// Height returns the number of edges on the longest root-to-leaf path of the Merkle Tree, 0 for a single leaf or an empty tree. In an RFC 6962 tree the left subtree of every node is perfect and at least as large as the right one, so the leftmost path is the longest and is walked without visiting the other nodes.
+func (t *Tree) Height() int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	height := 0
+	for node := t.root; node != nil && node.Left != nil; node = node.Left {
+		height++
+	}
+	return height
+}
+
+// RootAt returns the root hash the Merkle Tree had when it held only its first k leaves. The historic root is recombined from the subtree hashes already present in the tree instead of building a new tree.
+func (t *Tree) RootAt(k int) ([]byte, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if k < 1 || k > len(t.leaves) {
+		return nil, errors.New("invalid size: must be between 1 and the number of leaves")
+	}
+	return t.historicSubtreeHash(0, k), nil
+}
+
+// Clone returns a deep copy of the Merkle Tree, including its nodes, index map and options, so the clone can be appended to without affecting the original, e.g. as a snapshot before a risky batch of appends.
+func (t *Tree) Clone() *Tree {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	c := &Tree{
+		indexMap:       make(map[string][]int, len(t.indexMap)),
+		hashFunc:       t.hashFunc,
+		hashName:       t.hashName,
+		requirePerfect: t.requirePerfect,
+		keepData:       t.keepData,
+		sorted:         t.sorted,
+		allowEmpty:     t.allowEmpty,
+		allowEmptyTree: t.allowEmptyTree,
+		domain:         t.domain,
+	}
+	for k, indices := range t.indexMap {
+		c.indexMap[k] = slices.Clone(indices)
+	}
+	var root *Node
 	if t.root != nil {
-		return t.root.Hash
+		c.leaves = make([]*Node, 0, len(t.leaves))
+		root = cloneNode(t.root, nil, &c.leaves)
 	}
-	return nil
+	c.setRootLocked(root)
+	return c
 }
 
+// cloneNode deep-copies the subtree rooted at n, linking the copy to parent, and appends the copied leaves to leaves from left to right.
+func cloneNode(n *Node, parent *Node, leaves *[]*Node) *Node {
+	c := &Node{Hash: bytes.Clone(n.Hash), Data: bytes.Clone(n.Data), Parent: parent}
+	if n.Left == nil && n.Right == nil {
+		*leaves = append(*leaves, c)
+		return c
+	}
+	c.Left = cloneNode(n.Left, c, leaves)
+	c.Right = cloneNode(n.Right, c, leaves)
+	return c
+}
+
+// RootsEqual reports whether two root hashes are equal. The comparison runs in constant time and is the intended way to compare roots, since []byte values can't be compared with ==.
+func RootsEqual(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
+// Append adds a new leaf with the given data to the right edge of the Merkle Tree.
 func (t *Tree) Append(data []byte) error {
+	return t.AppendContext(context.Background(), data)
+}
+
+// AppendContext adds a new leaf like Append, but returns the context error without modifying the tree if the context is cancelled before the leaf is added, e.g. while waiting for the lock during a large batch ingestion.
+func (t *Tree) AppendContext(ctx context.Context, data []byte) error {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	t.defaultHashFuncLocked()
+	if t.requirePerfect && !isPowerOfTwo(len(t.leaves)+1) {
+		return errors.New("leaf count must be a power of two")
+	}
+	if err := t.checkLeafLocked(data, t.lastLeafData(), len(t.leaves) > 0); err != nil {
+		return err
+	}
+
+	leaf := t.newLeafNode(t.domain.HashLeaf(data, t.hashFunc), data)
+	t.spliceLeaves([]*Node{leaf})
+	t.addLeavesLocked([]*Node{leaf})
+	return nil
+}
+
+// AppendBatch appends the given items as new leaves under a single write lock and splices them into the tree at once, which is much faster than calling Append for each item. It returns the indices assigned to the items in order. The batch is all-or-nothing: every item is validated before the tree is modified, and if any item is invalid, or the resulting leaf count violates RequirePerfect, nothing is appended.
+func (t *Tree) AppendBatch(items [][]byte) ([]int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.defaultHashFuncLocked()
+	if t.requirePerfect && !isPowerOfTwo(len(t.leaves)+len(items)) {
+		return nil, errors.New("leaf count must be a power of two")
+	}
+	prev, hasPrev := t.lastLeafData(), len(t.leaves) > 0
+	for i, data := range items {
+		if err := t.checkLeafLocked(data, prev, hasPrev); err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		prev, hasPrev = data, true
+	}
+
+	leaves := make([]*Node, len(items))
+	for i, data := range items {
+		leaves[i] = t.newLeafNode(t.domain.HashLeaf(data, t.hashFunc), data)
+	}
+
+	first := len(t.leaves)
+	t.spliceLeaves(leaves)
+	t.addLeavesLocked(leaves)
+
+	indices := make([]int, len(leaves))
+	for i := range indices {
+		indices[i] = first + i
+	}
+	return indices, nil
+}
+
+// defaultHashFuncLocked sets the hash function of a zero-value Tree to the default one, as NewTree would, so it can be appended to. It assumes the caller holds the write lock.
+func (t *Tree) defaultHashFuncLocked() {
+	if t.hashFunc == nil {
+		t.hashFunc = hash.DefaultHashFunc
+		t.hashName = hash.NameOf(t.hashFunc)
+	}
+}
+
+// hashFuncOrDefault returns the hash function of the tree, or the default one for a zero-value Tree that was never appended to. Unlike defaultHashFuncLocked it doesn't modify the tree, so it is safe under the read lock.
+func (t *Tree) hashFuncOrDefault() hash.Func {
+	if t.hashFunc == nil {
+		return hash.DefaultHashFunc
+	}
+	return t.hashFunc
+}
+
+// checkLeafLocked validates the data of a leaf about to be appended after a leaf with the data prev, or as the first leaf if hasPrev is false. prev alone can't tell, as the previous leaf may be empty. It assumes the caller holds the write lock.
+func (t *Tree) checkLeafLocked(data []byte, prev []byte, hasPrev bool) error {
+	if !t.allowEmpty && len(data) == 0 {
+		return errors.New("empty leaf not allowed")
+	}
+	if t.sorted && hasPrev && bytes.Compare(data, prev) <= 0 {
+		return errors.New("sorted tree requires leaves in strictly ascending order")
+	}
+	return nil
+}
+
+// lastLeafData returns the retained data of the last leaf, or nil if the tree is empty or doesn't keep leaf data.
+func (t *Tree) lastLeafData() []byte {
+	if len(t.leaves) == 0 {
+		return nil
+	}
+	return t.leaves[len(t.leaves)-1].Data
+}
+
+// addLeavesLocked adds spliced leaves to t.leaves and the index map. It assumes the caller holds the write lock.
+func (t *Tree) addLeavesLocked(leaves []*Node) {
 	if t.indexMap == nil {
 		t.indexMap = make(map[string][]int)
 	}
+	for _, leaf := range leaves {
+		t.leaves = append(t.leaves, leaf)
+
+		hashHex := hex.EncodeToString(leaf.Hash)
+		t.indexMap[hashHex] = append(t.indexMap[hashHex], len(t.leaves)-1)
+	}
+}
+
+// spliceLeaves incrementally inserts new rightmost leaves into the tree, following the RFC 6962 right-spine merge. The perfect subtrees along the right spine (the frontier) are reused, each new leaf is merged with the equally sized ones at the end, and the spine joining the frontier is recreated once for all the leaves. It must be called before the leaves are added to t.leaves, with the write lock held.
+func (t *Tree) spliceLeaves(leaves []*Node) {
+	if len(leaves) == 0 {
+		return
+	}
+	n := len(t.leaves)
+	if t.root == nil {
+		n = 0
+	}
+
+	// collect the frontier nodes by walking down the right spine
+	frontier := make([]*Node, 0, bits.OnesCount(uint(n))+len(leaves))
+	if n > 0 {
+		node, remaining := t.root, n
+		for !isPowerOfTwo(remaining) {
+			frontier = append(frontier, node.Left) // the left child of a spine node is always a perfect subtree
+			remaining -= largestPowerOfTwoLessThan(remaining)
+			node = node.Right
+		}
+		frontier = append(frontier, node)
+	}
+
+	// merge each new leaf with the perfect subtrees of the same size, one per trailing set bit of the size
+	for _, leaf := range leaves {
+		carry := leaf
+		for merges := bits.TrailingZeros(^uint(n)); merges > 0; merges-- {
+			left := frontier[len(frontier)-1]
+			frontier = frontier[:len(frontier)-1]
+			carry = newParentNode(left, carry, t.hashFunc, t.domain)
+		}
+		frontier = append(frontier, carry)
+		n++
+	}
+
+	// rebuild the right spine joining the frontier from right to left
+	root := frontier[len(frontier)-1]
+	for i := len(frontier) - 2; i >= 0; i-- {
+		root = newParentNode(frontier[i], root, t.hashFunc, t.domain)
+	}
+	root.Parent = nil
+	t.setRootLocked(root)
+}
+
+// newParentNode creates an internal node over the given children and links them to it.
+func newParentNode(left, right *Node, hashFunc hash.Func, domain DomainParams) *Node {
+	parent := &Node{
+		Hash:  domain.HashNode(left.Hash, right.Hash, hashFunc),
+		Left:  left,
+		Right: right,
+	}
+	left.Parent = parent
+	right.Parent = parent
+	return parent
+}
+
+// ImportFrom appends the leaf hashes of another log to the tree. The source leaves are first verified to hash to the claimed source root, and the tree is left unchanged if they don't, so a corrupt source is never imported. The source must use the same hash function as the tree. Trees that keep leaf data are rejected, as only the hashes of the imported leaves are known.
+func (t *Tree) ImportFrom(leafHashes [][]byte, claimedSourceRoot []byte, hashFunc hash.Func) error {
+	if len(leafHashes) == 0 {
+		return errors.New("no leaf hashes provided")
+	}
+	if hashFunc == nil {
+		hashFunc = hash.DefaultHashFunc
+	}
 
-	leafHash := HashLeafData(data, t.hashFunc)
-	t.Leaves = append(t.Leaves, &Node{Hash: leafHash})
+	t.lock.Lock()
+	defer t.lock.Unlock()
 
-	hashHex := hex.EncodeToString(leafHash)
-	t.indexMap[hashHex] = append(t.indexMap[hashHex], len(t.Leaves)-1)
+	t.defaultHashFuncLocked()
+	if !hash.Same(hashFunc, t.hashFunc) {
+		return errors.New("source uses a different hash function")
+	}
+	if t.requirePerfect && !isPowerOfTwo(len(t.leaves)+len(leafHashes)) {
+		return errors.New("leaf count must be a power of two")
+	}
+	if t.sorted || t.keepData {
+		return errors.New("cannot import leaf hashes into a tree that keeps leaf data")
+	}
+	hashSize := len(t.hashFunc(nil))
+	for i, h := range leafHashes {
+		if len(h) != hashSize {
+			return fmt.Errorf("leaf hash %d has length %d, want %d", i, len(h), hashSize)
+		}
+	}
+	if !RootsEqual(rootFromLeafHashes(leafHashes, t.hashFunc, t.domain), claimedSourceRoot) {
+		return errors.New("source leaves do not match the claimed source root")
+	}
 
-	t.root = buildRecursive(t.Leaves, t.hashFunc)
+	leaves := make([]*Node, len(leafHashes))
+	for i, h := range leafHashes {
+		leaves[i] = &Node{Hash: bytes.Clone(h)} // don't share the caller's slices with the tree
+	}
+	t.spliceLeaves(leaves)
+	t.addLeavesLocked(leaves)
 	return nil
 }
 
+// AppendTree appends the leaves of other to the tree, e.g. to combine trees built by separate ingestion workers. The leaf hashes of other are reused without rehashing the leaf data and spliced into the right spine, so the resulting root equals the root of a single tree built from the leaves of both trees in order. Both trees must use the same hash function and domain params, a tree that keeps leaf data only accepts a tree that keeps it too, and empty leaves of other are rejected unless the tree allows them. other is left unchanged and may be the tree itself.
+func (t *Tree) AppendTree(other *Tree) error {
+	if other == nil {
+		return errors.New("no tree provided")
+	}
+
+	// snapshot other first, so appending a tree to itself doesn't deadlock
+	other.lock.RLock()
+	otherHashFunc, otherDomain, otherKeepData := other.hashFuncOrDefault(), other.domain, other.keepData
+	leaves := make([]*Node, len(other.leaves))
+	for i, leaf := range other.leaves {
+		leaves[i] = &Node{Hash: leaf.Hash, Data: leaf.Data} // hashes are never modified in place, only the nodes are linked into t
+	}
+	other.lock.RUnlock()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.defaultHashFuncLocked()
+	if !hash.Same(t.hashFunc, otherHashFunc) {
+		return errors.New("trees use different hash functions")
+	}
+	if !t.domain.equal(otherDomain) {
+		return errors.New("trees use different domain params")
+	}
+	if t.requirePerfect && !isPowerOfTwo(len(t.leaves)+len(leaves)) {
+		return errors.New("leaf count must be a power of two")
+	}
+	if t.sorted {
+		return errors.New("cannot append a tree to a sorted tree")
+	}
+	if t.keepData && !otherKeepData {
+		return errors.New("cannot append a tree without leaf data to a tree that keeps leaf data")
+	}
+	if !t.allowEmpty {
+		emptyLeafHash := t.domain.HashLeaf(nil, t.hashFunc) // other may not keep the data, but the hash of an empty leaf is known
+		for i, leaf := range leaves {
+			if bytes.Equal(leaf.Hash, emptyLeafHash) {
+				return fmt.Errorf("leaf %d: empty leaf not allowed", i)
+			}
+		}
+	}
+	if !t.keepData {
+		for _, leaf := range leaves {
+			leaf.Data = nil
+		}
+	}
+
+	t.spliceLeaves(leaves)
+	t.addLeavesLocked(leaves)
+	return nil
+}
+
+// Print writes the tree structure to standard output, see Fprint.
 func (t *Tree) Print() {
+	t.Fprint(os.Stdout)
+}
+
+// Fprint writes the tree structure to w, one node per line with its truncated hash, the right subtree of each node above its left subtree.
+func (t *Tree) Fprint(w io.Writer) error {
 	t.lock.RLock()
-	root := t.root // Capture the root while under lock
+	var b strings.Builder
+	printNode(&b, t.root, "", true) // walk under the lock, UpdateLeaf replaces the hashes of existing nodes
 	t.lock.RUnlock()
 
-	printNode(root, "", true)
+	_, err := io.WriteString(w, b.String())
+	return err
 }
 
-func printNode(n *Node, prefix string, isTail bool) {
+func printNode(b *strings.Builder, n *Node, prefix string, isTail bool) {
 	if n == nil {
 		return
 	}
@@ -136,16 +803,16 @@ func printNode(n *Node, prefix string, isTail bool) {
 		} else {
 			newPrefix += "    "
 		}
-		printNode(n.Right, newPrefix, false)
+		printNode(b, n.Right, newPrefix, false)
 	}
 
-	fmt.Printf("%s", prefix)
+	b.WriteString(prefix)
 	if isTail {
-		fmt.Printf("└── ")
+		b.WriteString("└── ")
 	} else {
-		fmt.Printf("┌── ")
+		b.WriteString("┌── ")
 	}
-	fmt.Printf("%s\n", hashStr[:8]) // print first 8 chars
+	b.WriteString(hashStr[:8] + "\n") // print first 8 chars
 
 	if n.Left != nil {
 		newPrefix := prefix
@@ -154,6 +821,6 @@ func printNode(n *Node, prefix string, isTail bool) {
 		} else {
 			newPrefix += "│   "
 		}
-		printNode(n.Left, newPrefix, true)
+		printNode(b, n.Left, newPrefix, true)
 	}
 }
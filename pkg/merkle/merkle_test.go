@@ -2,7 +2,17 @@ package merkle
 
 import (
 	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
 )
 
 func TestNewTree(t *testing.T) {
@@ -51,8 +61,8 @@ func TestNewTree(t *testing.T) {
 				t.Errorf("NewTree() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !tt.wantErr && len(tree.Leaves) != tt.wantLeaves {
-				t.Errorf("NewTree() leaves = %d, want %d", len(tree.Leaves), tt.wantLeaves)
+			if !tt.wantErr && len(tree.leaves) != tt.wantLeaves {
+				t.Errorf("NewTree() leaves = %d, want %d", len(tree.leaves), tt.wantLeaves)
 			}
 		})
 	}
@@ -167,8 +177,8 @@ func TestAppend(t *testing.T) {
 				tree.Append(val)
 			}
 
-			if len(tree.Leaves) != tt.expectedFinalLen {
-				t.Errorf("Append() leaves = %d, want %d", len(tree.Leaves), tt.expectedFinalLen)
+			if len(tree.leaves) != tt.expectedFinalLen {
+				t.Errorf("Append() leaves = %d, want %d", len(tree.leaves), tt.expectedFinalLen)
 			}
 			if bytes.Equal(initialHash, tree.RootHash()) && len(tt.appendValues) > 0 {
 				t.Error("Root hash should change after append")
@@ -176,3 +186,1326 @@ func TestAppend(t *testing.T) {
 		})
 	}
 }
+
+func TestRootsEqual(t *testing.T) {
+	tree, _ := NewTree([][]byte{[]byte("a"), []byte("b")}, nil)
+	other, _ := NewTree([][]byte{[]byte("a"), []byte("c")}, nil)
+
+	root := tree.RootHash()
+	rootCopy := append([]byte(nil), root...)
+
+	tests := []struct {
+		name string
+		a    []byte
+		b    []byte
+		want bool
+	}{
+		{"equal roots with distinct backing arrays", root, rootCopy, true},
+		{"unequal roots of same length", root, other.RootHash(), false},
+		{"different lengths", root, root[:len(root)-1], false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RootsEqual(tt.a, tt.b); got != tt.want {
+				t.Errorf("RootsEqual() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilteredRoot(t *testing.T) {
+	for size := 1; size <= 12; size++ {
+		t.Run(fmt.Sprintf("size %d", size), func(t *testing.T) {
+			var data, evenData [][]byte
+			for i := 0; i < size; i++ {
+				d := []byte(fmt.Sprintf("leaf%d", i))
+				data = append(data, d)
+				if i%2 == 0 {
+					evenData = append(evenData, d)
+				}
+			}
+			tree, _ := NewTree(data, nil)
+			evenTree, _ := NewTree(evenData, nil)
+
+			got := tree.FilteredRoot(func(i int) bool { return i%2 == 0 })
+			if !bytes.Equal(got, evenTree.RootHash()) {
+				t.Errorf("FilteredRoot() = %x, want %x", got, evenTree.RootHash())
+			}
+		})
+	}
+}
+
+func TestFilteredRoot_KeepAllAndNone(t *testing.T) {
+	tree, _ := NewTree([][]byte{[]byte("a"), []byte("b"), []byte("c")}, nil)
+
+	if got := tree.FilteredRoot(func(int) bool { return true }); !bytes.Equal(got, tree.RootHash()) {
+		t.Errorf("FilteredRoot(all) = %x, want %x", got, tree.RootHash())
+	}
+	if got := tree.FilteredRoot(func(int) bool { return false }); got != nil {
+		t.Errorf("FilteredRoot(none) = %x, want nil", got)
+	}
+}
+
+func TestProofs_AlternativeHashFuncs(t *testing.T) {
+	tests := []struct {
+		name     string
+		hashFunc hash.Func
+		size     int
+	}{
+		{"SHA-512", hash.SHA512HashFunc, 64},
+		{"SHA-384", hash.SHA384HashFunc, 48},
+		{"BLAKE2b-256", hash.Blake2b256HashFunc, 32},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data [][]byte
+			for i := 0; i < 11; i++ {
+				data = append(data, []byte(fmt.Sprintf("leaf%d", i)))
+			}
+			tree, err := NewTree(data, tt.hashFunc)
+			if err != nil {
+				t.Fatalf("Failed to create tree: %v", err)
+			}
+			if len(tree.RootHash()) != tt.size {
+				t.Errorf("RootHash() length = %d, want %d", len(tree.RootHash()), tt.size)
+			}
+
+			for i, d := range data {
+				proof, err := tree.GenerateInclusionProof(i)
+				if err != nil {
+					t.Fatalf("GenerateInclusionProof(%d) unexpected error: %v", i, err)
+				}
+				if !VerifyInclusionProof(d, proof, tree.RootHash(), tt.hashFunc) {
+					t.Errorf("VerifyInclusionProof() failed for leaf %d", i)
+				}
+			}
+
+			oldTree, _ := NewTree(data[:5], tt.hashFunc)
+			proof, err := tree.GenerateConsistencyProof(5)
+			if err != nil {
+				t.Fatalf("GenerateConsistencyProof() unexpected error: %v", err)
+			}
+			if !VerifyConsistencyProof(5, len(data), oldTree.RootHash(), tree.RootHash(), proof, tt.hashFunc) {
+				t.Errorf("VerifyConsistencyProof() failed")
+			}
+		})
+	}
+}
+
+func TestNewTreeWithSchema(t *testing.T) {
+	data := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+
+	v1, err := NewTreeWithSchema([]byte("schema/v1"), data, nil)
+	if err != nil {
+		t.Fatalf("NewTreeWithSchema() unexpected error: %v", err)
+	}
+	v2, err := NewTreeWithSchema([]byte("schema/v2"), data, nil)
+	if err != nil {
+		t.Fatalf("NewTreeWithSchema() unexpected error: %v", err)
+	}
+	plain, _ := NewTree(data, nil)
+
+	if bytes.Equal(v1.RootHash(), v2.RootHash()) {
+		t.Errorf("trees with different schemas have the same root %x", v1.RootHash())
+	}
+	if bytes.Equal(v1.RootHash(), plain.RootHash()) {
+		t.Errorf("tree with schema has the same root as the plain tree %x", v1.RootHash())
+	}
+
+	if got := v1.LeafHashes()[0]; !bytes.Equal(got, SchemaLeafHash([]byte("schema/v1"), nil)) {
+		t.Errorf("first leaf hash = %x, want SchemaLeafHash() %x", got, SchemaLeafHash([]byte("schema/v1"), nil))
+	}
+
+	// the schema leaf is not hashed like data, so a tree over the schema ID and the data is a different commitment
+	withSchemaAsData, _ := NewTree(append([][]byte{[]byte("schema/v1")}, data...), nil)
+	if bytes.Equal(v1.RootHash(), withSchemaAsData.RootHash()) {
+		t.Errorf("tree with schema has the same root as a plain tree with the schema ID as the first leaf %x", v1.RootHash())
+	}
+
+	// the schema ID can't be proven as a data record
+	if _, err := v1.GenerateInclusionProofByData([]byte("schema/v1")); err == nil {
+		t.Error("GenerateInclusionProofByData() found the schema ID as a data leaf")
+	}
+	schemaProof, err := v1.GenerateInclusionProof(0)
+	if err != nil {
+		t.Fatalf("GenerateInclusionProof() unexpected error: %v", err)
+	}
+	if VerifyInclusionProof([]byte("schema/v1"), schemaProof, v1.RootHash(), nil) {
+		t.Error("VerifyInclusionProof() accepted the schema ID as the data of leaf 0")
+	}
+
+	for i, d := range data {
+		proof, err := v1.GenerateInclusionProofByData(d)
+		if err != nil {
+			t.Fatalf("GenerateInclusionProofByData() unexpected error: %v", err)
+		}
+		if proof.LeafIndex != i+1 {
+			t.Errorf("proof.LeafIndex = %d, want %d", proof.LeafIndex, i+1)
+		}
+		if !VerifyInclusionProof(d, proof, v1.RootHash(), nil) {
+			t.Errorf("VerifyInclusionProof() failed against own schema for %q", d)
+		}
+		if VerifyInclusionProof(d, proof, v2.RootHash(), nil) {
+			t.Errorf("VerifyInclusionProof() succeeded across schemas for %q", d)
+		}
+	}
+}
+
+func TestNewTreeWithSchema_Errors(t *testing.T) {
+	if _, err := NewTreeWithSchema(nil, [][]byte{[]byte("a")}, nil); err == nil {
+		t.Error("NewTreeWithSchema() expected error for empty schema ID, got nil")
+	}
+	if _, err := NewTreeWithSchema([]byte("schema/v1"), nil, nil); err == nil {
+		t.Error("NewTreeWithSchema() expected error for empty data, got nil")
+	}
+	if _, err := NewTreeWithSchema([]byte("schema/v1"), [][]byte{[]byte("a")}, nil, Sorted()); err == nil {
+		t.Error("NewTreeWithSchema() expected error for a sorted tree, got nil")
+	}
+	if _, err := NewTreeWithSchema([]byte("schema/v1"), [][]byte{[]byte("a")}, nil, WithDomainParams(DomainParams{LeafPrefix: []byte{0x02, 0x00}})); err == nil {
+		t.Error("NewTreeWithSchema() expected error for a leaf prefix starting with the schema prefix, got nil")
+	}
+	if _, err := NewTreeWithSchema([]byte("schema/v1"), [][]byte{[]byte("a"), []byte("b")}, nil, RequirePerfect()); err == nil {
+		t.Error("NewTreeWithSchema() with RequirePerfect expected error for 3 leaves including the schema leaf, got nil")
+	}
+}
+
+func TestLeafData(t *testing.T) {
+	data := [][]byte{[]byte("a"), []byte("b"), {}, nil}
+	tree, err := NewTree(data, nil, KeepData(), AllowEmptyLeaves())
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if err := tree.Append([]byte("d")); err != nil {
+		t.Fatalf("Append() unexpected error: %v", err)
+	}
+	data = append(data, []byte("d"))
+
+	for i, want := range data {
+		got, err := tree.LeafData(i)
+		if err != nil {
+			t.Fatalf("LeafData(%d) unexpected error: %v", i, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("LeafData(%d) = %q, want %q", i, got, want)
+		}
+	}
+
+	got, _ := tree.LeafData(0)
+	got[0] = 'x' // the returned slice must not alias the tree
+	if again, _ := tree.LeafData(0); !bytes.Equal(again, []byte("a")) {
+		t.Errorf("LeafData(0) after modifying the result = %q, want %q", again, "a")
+	}
+
+	for _, index := range []int{-1, len(data)} {
+		if _, err := tree.LeafData(index); err == nil {
+			t.Errorf("LeafData(%d) expected error, got nil", index)
+		}
+	}
+}
+
+func TestLeafData_NotRetainedByDefault(t *testing.T) {
+	tree, _ := NewTree([][]byte{[]byte("a"), []byte("b")}, nil)
+	if _, err := tree.LeafData(0); err == nil {
+		t.Errorf("LeafData() expected error without KeepData, got nil")
+	}
+	if tree.leaves[0].Data != nil {
+		t.Errorf("leaf Data = %q, want nil", tree.leaves[0].Data)
+	}
+}
+
+func TestSizeAndHeight(t *testing.T) {
+	tests := []struct {
+		leaves     int
+		wantHeight int
+	}{
+		{1, 0},
+		{3, 2},
+		{5, 3},
+		{8, 3},
+		{9, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d leaves", tt.leaves), func(t *testing.T) {
+			tree := buildTestTree(t, tt.leaves)
+			if got := tree.Size(); got != tt.leaves {
+				t.Errorf("Size() = %d, want %d", got, tt.leaves)
+			}
+			if got := tree.Height(); got != tt.wantHeight {
+				t.Errorf("Height() = %d, want %d", got, tt.wantHeight)
+			}
+			if got := maxDepth(tree.root); got != tree.Height() {
+				t.Errorf("Height() = %d, but the deepest leaf is at depth %d", tree.Height(), got)
+			}
+		})
+	}
+}
+
+func TestSizeAndHeight_EmptyTree(t *testing.T) {
+	var tree Tree
+	if tree.Size() != 0 || tree.Height() != 0 {
+		t.Errorf("Size(), Height() = %d, %d, want 0, 0", tree.Size(), tree.Height())
+	}
+}
+
+func TestAppend_ZeroValueTree(t *testing.T) {
+	tree := &Tree{}
+	if _, err := tree.GenerateInclusionProofByData([]byte("leaf0")); err == nil {
+		t.Error("GenerateInclusionProofByData() expected error for empty zero-value tree, got nil")
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := tree.Append([]byte(fmt.Sprintf("leaf%d", i))); err != nil {
+			t.Fatalf("Append() unexpected error: %v", err)
+		}
+	}
+
+	want := buildTestTree(t, 3).RootHash()
+	if !bytes.Equal(tree.RootHash(), want) {
+		t.Errorf("RootHash() = %x, want %x", tree.RootHash(), want)
+	}
+	proof, err := tree.GenerateInclusionProofByData([]byte("leaf1"))
+	if err != nil {
+		t.Fatalf("GenerateInclusionProofByData() unexpected error: %v", err)
+	}
+	if !VerifyInclusionProof([]byte("leaf1"), proof, want, nil) {
+		t.Error("GenerateInclusionProofByData() returned a proof that does not verify")
+	}
+}
+
+func TestAllowEmptyTree(t *testing.T) {
+	if _, err := NewTree(nil, nil); err == nil {
+		t.Error("NewTree() without AllowEmptyTree expected error for no data, got nil")
+	}
+
+	for _, hashFunc := range []hash.Func{hash.SHA256HashFunc, hash.SHA3HashFunc} {
+		tree, err := NewTree(nil, hashFunc, AllowEmptyTree())
+		if err != nil {
+			t.Fatalf("NewTree() with AllowEmptyTree unexpected error: %v", err)
+		}
+		if want := hashFunc([]byte{}); !bytes.Equal(tree.RootHash(), want) {
+			t.Errorf("empty RootHash() = %x, want %x", tree.RootHash(), want)
+		}
+		if tree.Size() != 0 {
+			t.Errorf("empty Size() = %d, want 0", tree.Size())
+		}
+		if err := tree.Validate(); err != nil {
+			t.Errorf("empty Validate() unexpected error: %v", err)
+		}
+		if c := tree.Clone(); !bytes.Equal(c.RootHash(), tree.RootHash()) {
+			t.Errorf("empty Clone().RootHash() = %x, want %x", c.RootHash(), tree.RootHash())
+		}
+	}
+
+	// the well-known RFC 6962 empty root for SHA-256
+	tree, _ := NewTree(nil, nil, AllowEmptyTree())
+	if got := hex.EncodeToString(tree.RootHash()); got != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("empty RootHash() = %s, want the SHA-256 of the empty string", got)
+	}
+}
+
+func TestAllowEmptyTree_FirstAppend(t *testing.T) {
+	tree, _ := NewTree(nil, nil, AllowEmptyTree(), RequirePerfect())
+	emptyRoot := tree.RootHash()
+
+	if err := tree.Append([]byte("leaf0")); err != nil {
+		t.Fatalf("Append() to empty tree unexpected error: %v", err)
+	}
+	if bytes.Equal(tree.RootHash(), emptyRoot) {
+		t.Error("RootHash() unchanged after the first append")
+	}
+	if want := buildTestTree(t, 1).RootHash(); !bytes.Equal(tree.RootHash(), want) {
+		t.Errorf("RootHash() after first append = %x, want %x", tree.RootHash(), want)
+	}
+
+	if err := tree.Append([]byte("leaf1")); err != nil {
+		t.Fatalf("Append() unexpected error: %v", err)
+	}
+	proof, err := tree.GenerateInclusionProof(1)
+	if err != nil {
+		t.Fatalf("GenerateInclusionProof() unexpected error: %v", err)
+	}
+	if !VerifyInclusionProof([]byte("leaf1"), proof, tree.RootHash(), nil) {
+		t.Error("inclusion proof in a tree grown from empty does not verify")
+	}
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Validate() unexpected error: %v", err)
+	}
+}
+
+// maxDepth returns the longest root-to-leaf path below the node by visiting every node.
+func maxDepth(n *Node) int {
+	if n == nil || (n.Left == nil && n.Right == nil) {
+		return 0
+	}
+	return 1 + max(maxDepth(n.Left), maxDepth(n.Right))
+}
+
+func TestRootAt(t *testing.T) {
+	var data [][]byte
+	for i := 0; i < 21; i++ {
+		data = append(data, []byte(fmt.Sprintf("leaf%d", i)))
+	}
+	tree, _ := NewTree(data, nil)
+
+	for k := 1; k <= len(data); k++ {
+		want, _ := NewTree(data[:k], nil)
+		got, err := tree.RootAt(k)
+		if err != nil {
+			t.Fatalf("RootAt(%d) unexpected error: %v", k, err)
+		}
+		if !bytes.Equal(got, want.RootHash()) {
+			t.Errorf("RootAt(%d) = %x, want %x", k, got, want.RootHash())
+		}
+	}
+
+	for _, k := range []int{0, -1, len(data) + 1} {
+		if _, err := tree.RootAt(k); err == nil {
+			t.Errorf("RootAt(%d) expected error, got nil", k)
+		}
+	}
+}
+
+func TestEmptyLeaves(t *testing.T) {
+	emptyLeaves := []struct {
+		name string
+		data []byte
+	}{
+		{"nil", nil},
+		{"empty", []byte{}},
+	}
+
+	for _, tt := range emptyLeaves {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewTree([][]byte{[]byte("a"), tt.data}, nil); err == nil {
+				t.Error("NewTree() expected error for empty leaf, got nil")
+			}
+			if _, err := BuildWithProgress(context.Background(), [][]byte{[]byte("a"), tt.data}, nil, nil); err == nil {
+				t.Error("BuildWithProgress() expected error for empty leaf, got nil")
+			}
+
+			tree := buildTestTree(t, 2)
+			if err := tree.Append(tt.data); err == nil {
+				t.Error("Append() expected error for empty leaf, got nil")
+			}
+			if indices, err := tree.AppendBatch([][]byte{[]byte("leaf2"), tt.data}); err == nil || indices != nil {
+				t.Errorf("AppendBatch() = %v, %v, want no indices and an error", indices, err)
+			}
+			if tree.Size() != 2 {
+				t.Errorf("Size() = %d, want 2", tree.Size())
+			}
+
+			allowing, err := NewTree([][]byte{[]byte("a"), tt.data}, nil, AllowEmptyLeaves())
+			if err != nil {
+				t.Fatalf("NewTree() with AllowEmptyLeaves unexpected error: %v", err)
+			}
+			if err := allowing.Append(tt.data); err != nil {
+				t.Errorf("Append() with AllowEmptyLeaves unexpected error: %v", err)
+			}
+
+			proof, err := allowing.GenerateInclusionProof(1)
+			if err != nil {
+				t.Fatalf("GenerateInclusionProof() unexpected error: %v", err)
+			}
+			if !VerifyInclusionProofAllowEmpty(tt.data, proof, allowing.RootHash(), nil) {
+				t.Error("VerifyInclusionProofAllowEmpty() = false, want true for the empty leaf")
+			}
+			if VerifyInclusionProof(tt.data, proof, allowing.RootHash(), nil) {
+				t.Error("VerifyInclusionProof() = true, want false for empty leaf data")
+			}
+			if VerifyInclusionProofAllowEmpty([]byte("a"), proof, allowing.RootHash(), nil) {
+				t.Error("VerifyInclusionProofAllowEmpty() = true, want false for the wrong leaf data")
+			}
+
+			sorted, err := NewTree(nil, nil, Sorted(), AllowEmptyLeaves(), AllowEmptyTree())
+			if err != nil {
+				t.Fatalf("NewTree() with Sorted unexpected error: %v", err)
+			}
+			if err := sorted.Append(tt.data); err != nil {
+				t.Fatalf("Append() of the first empty leaf to a sorted tree unexpected error: %v", err)
+			}
+			if err := sorted.Append(tt.data); err == nil {
+				t.Error("Append() of a second empty leaf to a sorted tree expected error, got nil")
+			}
+			if _, err := sorted.AppendBatch([][]byte{nil}); err == nil {
+				t.Error("AppendBatch() of a second empty leaf to a sorted tree expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestRootHash_CacheFollowsMutations(t *testing.T) {
+	tree := buildTestTree(t, 3)
+	assertRoot := func(step string) {
+		t.Helper()
+		if !bytes.Equal(tree.RootHash(), tree.root.Hash) {
+			t.Errorf("%s: RootHash() = %x, root node hash = %x", step, tree.RootHash(), tree.root.Hash)
+		}
+		if want := buildTestTree(t, tree.Size()).RootHash(); !bytes.Equal(tree.RootHash(), want) {
+			t.Errorf("%s: RootHash() = %x, want %x", step, tree.RootHash(), want)
+		}
+	}
+	assertRoot("NewTree")
+
+	if err := tree.Append([]byte("leaf3")); err != nil {
+		t.Fatalf("Append() unexpected error: %v", err)
+	}
+	assertRoot("Append")
+
+	if _, err := tree.AppendBatch([][]byte{[]byte("leaf4"), []byte("leaf5")}); err != nil {
+		t.Fatalf("AppendBatch() unexpected error: %v", err)
+	}
+	assertRoot("AppendBatch")
+
+	source := buildTestTree(t, 8)
+	if err := tree.ImportFrom(leafHashesOf(source)[6:], rootFromLeafHashes(leafHashesOf(source)[6:], source.hashFunc, DomainParams{}), nil); err != nil {
+		t.Fatalf("ImportFrom() unexpected error: %v", err)
+	}
+	assertRoot("ImportFrom")
+}
+
+func TestRootHash_ConcurrentWithAppend(t *testing.T) {
+	tree := buildTestTree(t, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i < 200; i++ {
+			if err := tree.Append([]byte(fmt.Sprintf("leaf%d", i))); err != nil {
+				t.Errorf("Append() unexpected error: %v", err)
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			if want := buildTestTree(t, 200).RootHash(); !bytes.Equal(tree.RootHash(), want) {
+				t.Errorf("RootHash() = %x, want %x", tree.RootHash(), want)
+			}
+			return
+		default:
+			if len(tree.RootHash()) != 32 {
+				t.Fatalf("RootHash() length = %d, want 32", len(tree.RootHash()))
+			}
+		}
+	}
+}
+
+// TestConcurrentAppendAndReads hammers Append while proofs and snapshots are read from other goroutines, run it with -race.
+func TestConcurrentAppendAndReads(t *testing.T) {
+	const appends = 300
+	tree := buildTestTree(t, 1)
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for i := 1; i <= appends; i++ {
+			if err := tree.Append([]byte(fmt.Sprintf("leaf%d", i))); err != nil {
+				t.Errorf("Append() unexpected error: %v", err)
+				return
+			}
+		}
+	}()
+
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				index := i % tree.Size()
+				proof, err := tree.GenerateInclusionProof(index)
+				if err != nil {
+					t.Errorf("GenerateInclusionProof(%d) unexpected error: %v", index, err)
+					return
+				}
+				root, err := tree.RootAt(proof.TreeSize) // the tree may have grown since the proof was generated
+				if err != nil {
+					t.Errorf("RootAt(%d) unexpected error: %v", proof.TreeSize, err)
+					return
+				}
+				if !VerifyInclusionProof([]byte(fmt.Sprintf("leaf%d", index)), proof, root, nil) {
+					t.Errorf("proof for index %d at size %d does not verify", index, proof.TreeSize)
+					return
+				}
+
+				switch r {
+				case 0:
+					_ = tree.Fprint(io.Discard)
+				case 1:
+					hashes := tree.LeafHashes()
+					hashes[0][0] ^= 0xff // the snapshot is a copy
+				case 2:
+					tree.Range(func(int, []byte) bool { return true })
+				}
+			}
+		}(r)
+	}
+	wg.Wait()
+
+	if tree.Size() != appends+1 {
+		t.Errorf("Size() = %d, want %d", tree.Size(), appends+1)
+	}
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Validate() unexpected error: %v", err)
+	}
+}
+
+func TestRequirePerfect(t *testing.T) {
+	t.Run("four leaves are accepted", func(t *testing.T) {
+		data := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d")}
+		if _, err := NewTree(data, nil, RequirePerfect()); err != nil {
+			t.Errorf("NewTree() unexpected error: %v", err)
+		}
+	})
+
+	t.Run("three leaves are rejected", func(t *testing.T) {
+		data := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+		if _, err := NewTree(data, nil, RequirePerfect()); err == nil {
+			t.Error("NewTree() expected error for 3 leaves, got nil")
+		}
+	})
+
+	t.Run("append growing 1 leaf to 2 is accepted", func(t *testing.T) {
+		tree, _ := NewTree([][]byte{[]byte("a")}, nil, RequirePerfect())
+		if err := tree.Append([]byte("b")); err != nil {
+			t.Errorf("Append() unexpected error: %v", err)
+		}
+	})
+
+	t.Run("append of 3rd leaf to 2-leaf tree is rejected", func(t *testing.T) {
+		tree, _ := NewTree([][]byte{[]byte("a"), []byte("b")}, nil, RequirePerfect())
+		rootBefore := tree.RootHash()
+
+		if err := tree.Append([]byte("c")); err == nil {
+			t.Fatal("Append() expected error for 3rd leaf, got nil")
+		}
+		if len(tree.leaves) != 2 {
+			t.Errorf("tree leaves = %d after rejected append, want 2", len(tree.leaves))
+		}
+		if !bytes.Equal(rootBefore, tree.RootHash()) {
+			t.Error("root hash changed after rejected append")
+		}
+	})
+}
+
+func TestImportFrom(t *testing.T) {
+	sourceData := [][]byte{[]byte("s1"), []byte("s2"), []byte("s3")}
+	source, _ := NewTree(sourceData, nil)
+
+	var sourceHashes [][]byte
+	for _, leaf := range source.leaves {
+		sourceHashes = append(sourceHashes, append([]byte(nil), leaf.Hash...))
+	}
+
+	t.Run("verified source is imported", func(t *testing.T) {
+		tree, _ := NewTree([][]byte{[]byte("a"), []byte("b")}, nil)
+		if err := tree.ImportFrom(sourceHashes, source.RootHash(), nil); err != nil {
+			t.Fatalf("ImportFrom() unexpected error: %v", err)
+		}
+
+		expected, _ := NewTree(append([][]byte{[]byte("a"), []byte("b")}, sourceData...), nil)
+		if !bytes.Equal(tree.RootHash(), expected.RootHash()) {
+			t.Errorf("root after import = %x, want %x", tree.RootHash(), expected.RootHash())
+		}
+		proof, err := tree.GenerateInclusionProofByData([]byte("s2"))
+		if err != nil {
+			t.Fatalf("imported leaf not found: %v", err)
+		}
+		if !VerifyInclusionProof([]byte("s2"), proof, tree.RootHash(), nil) {
+			t.Error("inclusion proof for imported leaf does not verify")
+		}
+	})
+
+	t.Run("tampered source is rejected", func(t *testing.T) {
+		tree, _ := NewTree([][]byte{[]byte("a"), []byte("b")}, nil)
+		rootBefore := tree.RootHash()
+
+		tampered := make([][]byte, len(sourceHashes))
+		for i, h := range sourceHashes {
+			tampered[i] = append([]byte(nil), h...)
+		}
+		tampered[1][0] ^= 0xFF
+
+		if err := tree.ImportFrom(tampered, source.RootHash(), nil); err == nil {
+			t.Fatal("ImportFrom() expected error for tampered source, got nil")
+		}
+		if len(tree.leaves) != 2 || !bytes.Equal(rootBefore, tree.RootHash()) {
+			t.Error("tree was mutated by a rejected import")
+		}
+	})
+
+	t.Run("invalid sources are rejected", func(t *testing.T) {
+		sha3Source, _ := NewTree(sourceData, hash.SHA3HashFunc)
+		short := [][]byte{sourceHashes[0], sourceHashes[1][:16], sourceHashes[2]}
+
+		tests := []struct {
+			name       string
+			opts       []Option
+			hashes     [][]byte
+			sourceRoot []byte
+			hashFunc   hash.Func
+		}{
+			{"different hash function", nil, leafHashesOf(sha3Source), sha3Source.RootHash(), hash.SHA3HashFunc},
+			{"short leaf hash", nil, short, rootFromLeafHashes(short, hash.DefaultHashFunc, DomainParams{}), nil},
+			{"tree keeps leaf data", []Option{KeepData()}, sourceHashes, source.RootHash(), nil},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tree, _ := NewTree([][]byte{[]byte("a"), []byte("b")}, nil, tt.opts...)
+				rootBefore := tree.RootHash()
+				if err := tree.ImportFrom(tt.hashes, tt.sourceRoot, tt.hashFunc); err == nil {
+					t.Fatal("ImportFrom() expected error, got nil")
+				}
+				if tree.Size() != 2 || !bytes.Equal(rootBefore, tree.RootHash()) {
+					t.Error("tree was mutated by a rejected import")
+				}
+			})
+		}
+	})
+}
+
+func TestAppendTree(t *testing.T) {
+	sizes := [][2]int{{1, 1}, {1, 6}, {5, 3}, {8, 8}, {7, 13}, {13, 2}}
+	for _, size := range sizes {
+		t.Run(fmt.Sprintf("%d+%d", size[0], size[1]), func(t *testing.T) {
+			all := leafDataRange(0, size[0]+size[1])
+			tree, _ := NewTree(all[:size[0]], nil)
+			other, _ := NewTree(all[size[0]:], nil)
+			otherRoot := other.RootHash()
+
+			if err := tree.AppendTree(other); err != nil {
+				t.Fatalf("AppendTree() unexpected error: %v", err)
+			}
+
+			expected, _ := NewTree(all, nil)
+			if !bytes.Equal(tree.RootHash(), expected.RootHash()) {
+				t.Errorf("root after AppendTree() = %x, want %x", tree.RootHash(), expected.RootHash())
+			}
+			if err := tree.Validate(); err != nil {
+				t.Errorf("Validate() after AppendTree() unexpected error: %v", err)
+			}
+			if !bytes.Equal(other.RootHash(), otherRoot) || other.Size() != size[1] {
+				t.Error("AppendTree() modified the appended tree")
+			}
+
+			last := len(all) - 1
+			proof, err := tree.GenerateInclusionProofByData(all[last])
+			if err != nil || proof.LeafIndex != last {
+				t.Fatalf("GenerateInclusionProofByData() for appended leaf = %v, %v, want index %d", proof, err, last)
+			}
+			if !VerifyInclusionProof(all[last], proof, tree.RootHash(), nil) {
+				t.Error("inclusion proof for appended leaf does not verify")
+			}
+		})
+	}
+}
+
+func TestAppendTree_Self(t *testing.T) {
+	tree := buildTestTree(t, 3)
+	if err := tree.AppendTree(tree); err != nil {
+		t.Fatalf("AppendTree() unexpected error: %v", err)
+	}
+
+	data := append(leafDataRange(0, 3), leafDataRange(0, 3)...)
+	expected, _ := NewTree(data, nil)
+	if !bytes.Equal(tree.RootHash(), expected.RootHash()) {
+		t.Errorf("root after appending the tree to itself = %x, want %x", tree.RootHash(), expected.RootHash())
+	}
+}
+
+func TestAppendTree_Errors(t *testing.T) {
+	other := buildTestTree(t, 2)
+
+	withEmptyLeaf, _ := NewTree([][]byte{[]byte("a"), nil}, nil, AllowEmptyLeaves())
+
+	tests := []struct {
+		name  string
+		tree  func() *Tree
+		other *Tree // the shared other tree if nil
+	}{
+		{"different hash function", func() *Tree { tree, _ := NewTree(leafDataRange(0, 2), hash.SHA512HashFunc); return tree }, nil},
+		{"different domain params", func() *Tree {
+			tree, _ := NewTree(leafDataRange(0, 2), nil, WithDomainParams(DomainParams{LeafPrefix: []byte{0x10}, NodePrefix: []byte{0x11}}))
+			return tree
+		}, nil},
+		{"not a power of two", func() *Tree { tree, _ := NewTree(leafDataRange(0, 1), nil, RequirePerfect()); return tree }, nil},
+		{"other doesn't keep leaf data", func() *Tree { tree, _ := NewTree(leafDataRange(0, 2), nil, KeepData()); return tree }, nil},
+		{"empty leaf not allowed", func() *Tree { return buildTestTree(t, 2) }, withEmptyLeaf},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, appended := tt.tree(), tt.other
+			if appended == nil {
+				appended = other
+			}
+			rootBefore := tree.RootHash()
+			if err := tree.AppendTree(appended); err == nil {
+				t.Fatal("AppendTree() expected error, got nil")
+			}
+			if !bytes.Equal(tree.RootHash(), rootBefore) {
+				t.Error("tree was mutated by a rejected AppendTree()")
+			}
+		})
+	}
+
+	if err := other.AppendTree(nil); err == nil {
+		t.Error("AppendTree(nil) expected error, got nil")
+	}
+}
+
+func TestBuildWithProgress(t *testing.T) {
+	var data [][]byte
+	for i := 0; i < 100; i++ {
+		data = append(data, []byte{byte(i)})
+	}
+
+	t.Run("full run", func(t *testing.T) {
+		calls, lastDone := 0, 0
+		tree, err := BuildWithProgress(context.Background(), data, nil, func(done, total int) {
+			calls++
+			lastDone = done
+			if total != len(data) {
+				t.Errorf("progress total = %d, want %d", total, len(data))
+			}
+		})
+		if err != nil {
+			t.Fatalf("BuildWithProgress() unexpected error: %v", err)
+		}
+
+		expected, _ := NewTree(data, nil)
+		if !bytes.Equal(tree.RootHash(), expected.RootHash()) {
+			t.Errorf("BuildWithProgress() root = %x, want %x", tree.RootHash(), expected.RootHash())
+		}
+		if calls != len(data) || lastDone != len(data) {
+			t.Errorf("progress called %d times ending at %d, want %d", calls, lastDone, len(data))
+		}
+	})
+
+	t.Run("cancelled midway", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		lastDone := 0
+		_, err := BuildWithProgress(ctx, data, nil, func(done, total int) {
+			lastDone = done
+			if done == total/2 {
+				cancel()
+			}
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("BuildWithProgress() error = %v, want context.Canceled", err)
+		}
+		if lastDone != len(data)/2 {
+			t.Errorf("build continued to %d leaves after cancellation at %d", lastDone, len(data)/2)
+		}
+	})
+
+	t.Run("no data", func(t *testing.T) {
+		if _, err := BuildWithProgress(context.Background(), nil, nil, nil); err == nil {
+			t.Error("BuildWithProgress() expected error for empty data, got nil")
+		}
+	})
+}
+
+func TestAppendContext(t *testing.T) {
+	tree := buildTestTree(t, 3)
+	root := tree.RootHash()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := tree.AppendContext(ctx, []byte("leaf3")); !errors.Is(err, context.Canceled) {
+		t.Errorf("AppendContext() error = %v, want %v", err, context.Canceled)
+	}
+	if tree.Size() != 3 || !bytes.Equal(tree.RootHash(), root) {
+		t.Errorf("tree changed after cancelled AppendContext")
+	}
+
+	if err := tree.AppendContext(context.Background(), []byte("leaf3")); err != nil {
+		t.Fatalf("AppendContext() unexpected error: %v", err)
+	}
+	if want := buildTestTree(t, 4).RootHash(); !bytes.Equal(tree.RootHash(), want) {
+		t.Errorf("RootHash() = %x, want %x", tree.RootHash(), want)
+	}
+}
+
+func TestAppendContext_CancelledWhileWaitingForLock(t *testing.T) {
+	tree := buildTestTree(t, 3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tree.lock.Lock() // simulate a long-running writer
+	errc := make(chan error, 1)
+	go func() { errc <- tree.AppendContext(ctx, []byte("leaf3")) }()
+
+	cancel()
+	tree.lock.Unlock()
+
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("AppendContext() error = %v, want %v", err, context.Canceled)
+	}
+	if tree.Size() != 3 {
+		t.Errorf("Size() = %d, want 3", tree.Size())
+	}
+}
+
+func TestAppendBatch(t *testing.T) {
+	for _, start := range []int{1, 3, 8} {
+		for _, batch := range []int{0, 1, 5, 13} {
+			t.Run(fmt.Sprintf("%d+%d leaves", start, batch), func(t *testing.T) {
+				tree := buildTestTree(t, start)
+				var items [][]byte
+				for i := start; i < start+batch; i++ {
+					items = append(items, []byte(fmt.Sprintf("leaf%d", i)))
+				}
+
+				indices, err := tree.AppendBatch(items)
+				if err != nil {
+					t.Fatalf("AppendBatch() unexpected error: %v", err)
+				}
+				if len(indices) != batch {
+					t.Fatalf("AppendBatch() returned %d indices, want %d", len(indices), batch)
+				}
+				for i, index := range indices {
+					if index != start+i {
+						t.Errorf("AppendBatch() index %d = %d, want %d", i, index, start+i)
+					}
+				}
+
+				want := buildTestTree(t, start+batch)
+				if !bytes.Equal(tree.RootHash(), want.RootHash()) {
+					t.Errorf("RootHash() = %x, want %x", tree.RootHash(), want.RootHash())
+				}
+				for i := 0; i < start+batch; i++ {
+					proof, err := tree.GenerateInclusionProofByData([]byte(fmt.Sprintf("leaf%d", i)))
+					if err != nil {
+						t.Fatalf("GenerateInclusionProofByData() unexpected error: %v", err)
+					}
+					if !VerifyInclusionProof([]byte(fmt.Sprintf("leaf%d", i)), proof, tree.RootHash(), nil) {
+						t.Errorf("VerifyInclusionProof() failed for leaf %d", i)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestAppendBatch_RejectsWholeBatchOnInvalidItem(t *testing.T) {
+	tree, _ := NewTree([][]byte{[]byte("a"), []byte("b")}, nil, Sorted())
+	root := tree.RootHash()
+	items := [][]byte{[]byte("c"), []byte("d"), []byte("b"), []byte("e")}
+
+	indices, err := tree.AppendBatch(items)
+	if err == nil {
+		t.Fatal("AppendBatch() expected error for out-of-order item, got nil")
+	}
+	if indices != nil {
+		t.Errorf("AppendBatch() indices = %v, want nil", indices)
+	}
+	if tree.Size() != 2 || !bytes.Equal(tree.RootHash(), root) {
+		t.Errorf("AppendBatch() modified the tree: size %d, root %x", tree.Size(), tree.RootHash())
+	}
+}
+
+func TestAppendBatch_RequirePerfect(t *testing.T) {
+	tree, _ := NewTree([][]byte{[]byte("a"), []byte("b")}, nil, RequirePerfect())
+
+	if _, err := tree.AppendBatch([][]byte{[]byte("c")}); err == nil {
+		t.Error("AppendBatch() expected error for 3 leaves, got nil")
+	}
+	if _, err := tree.AppendBatch([][]byte{[]byte("c"), nil}); err == nil {
+		t.Error("AppendBatch() expected error for an empty item, got nil")
+	}
+	if tree.Size() != 2 {
+		t.Fatalf("Size() = %d after rejected batches, want 2", tree.Size())
+	}
+	if _, err := tree.AppendBatch([][]byte{[]byte("c"), []byte("d")}); err != nil {
+		t.Errorf("AppendBatch() unexpected error for 4 leaves: %v", err)
+	}
+}
+
+func BenchmarkAppendBatch(b *testing.B) {
+	items := make([][]byte, 1000)
+	for i := range items {
+		items[i] = []byte(fmt.Sprintf("leaf%d", i))
+	}
+
+	b.Run("AppendBatch", func(b *testing.B) {
+		for b.Loop() {
+			tree := buildTestTree(b, 1)
+			if _, err := tree.AppendBatch(items); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Append loop", func(b *testing.B) {
+		for b.Loop() {
+			tree := buildTestTree(b, 1)
+			for _, item := range items {
+				if err := tree.Append(item); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+func TestAppend_IncrementalMatchesRebuild(t *testing.T) {
+	var data [][]byte
+	tree, _ := NewTree([][]byte{[]byte("leaf-0")}, nil)
+	data = append(data, []byte("leaf-0"))
+
+	for i := 1; i < 70; i++ {
+		d := []byte(fmt.Sprintf("leaf-%d", i))
+		data = append(data, d)
+		if err := tree.Append(d); err != nil {
+			t.Fatalf("Append failed at step %d: %v", i, err)
+		}
+
+		expected, _ := NewTree(data, nil)
+		if !bytes.Equal(tree.RootHash(), expected.RootHash()) {
+			t.Fatalf("size %d: incremental root = %x, rebuilt root = %x", len(data), tree.RootHash(), expected.RootHash())
+		}
+
+		// every leaf must still reach the root through its parent links
+		for index := range data {
+			proof, err := tree.GenerateInclusionProof(index)
+			if err != nil {
+				t.Fatalf("size %d: GenerateInclusionProof(%d) unexpected error: %v", len(data), index, err)
+			}
+			if !VerifyInclusionProof(data[index], proof, tree.RootHash(), nil) {
+				t.Fatalf("size %d: proof for index %d does not verify", len(data), index)
+			}
+		}
+	}
+}
+
+func BenchmarkAppend(b *testing.B) {
+	for _, size := range []int{1_000, 100_000} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			data := make([][]byte, size)
+			for i := range data {
+				data[i] = []byte(fmt.Sprintf("leaf-%d", i))
+			}
+			tree, _ := NewTree(data, nil)
+
+			i := 0
+			for b.Loop() {
+				if err := tree.Append([]byte(fmt.Sprintf("appended-%d", i))); err != nil {
+					b.Fatal(err)
+				}
+				i++
+			}
+		})
+	}
+}
+
+func TestTreeFprint(t *testing.T) {
+	tree := buildTestTree(t, 2)
+	short := func(h []byte) string { return hex.EncodeToString(h)[:8] }
+
+	want := "│   ┌── " + short(tree.leaves[1].Hash) + "\n" +
+		"└── " + short(tree.RootHash()) + "\n" +
+		"    └── " + short(tree.leaves[0].Hash) + "\n"
+
+	var b strings.Builder
+	if err := tree.Fprint(&b); err != nil {
+		t.Fatalf("Fprint() unexpected error: %v", err)
+	}
+	if b.String() != want {
+		t.Errorf("Fprint() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestClone(t *testing.T) {
+	original := buildTestTree(t, 5)
+	originalRoot := bytes.Clone(original.RootHash())
+
+	clone := original.Clone()
+	if !bytes.Equal(clone.RootHash(), originalRoot) {
+		t.Fatalf("clone RootHash() = %x, want %x", clone.RootHash(), originalRoot)
+	}
+	if clone.Size() != original.Size() {
+		t.Fatalf("clone Size() = %d, want %d", clone.Size(), original.Size())
+	}
+
+	for i := 5; i < 9; i++ {
+		if err := clone.Append([]byte(fmt.Sprintf("leaf%d", i))); err != nil {
+			t.Fatalf("clone Append() unexpected error: %v", err)
+		}
+	}
+
+	if original.Size() != 5 {
+		t.Errorf("original Size() = %d after appending to the clone, want 5", original.Size())
+	}
+	if !bytes.Equal(original.RootHash(), originalRoot) {
+		t.Errorf("original RootHash() changed after appending to the clone")
+	}
+	if !bytes.Equal(clone.RootHash(), buildTestTree(t, 9).RootHash()) {
+		t.Errorf("clone RootHash() after appends doesn't match a tree built from the same leaves")
+	}
+	if _, err := original.GenerateInclusionProofByData([]byte("leaf7")); err == nil {
+		t.Errorf("original finds a leaf appended to the clone")
+	}
+
+	// proofs from the original still verify, so its nodes weren't relinked by the clone's appends
+	proof, err := original.GenerateInclusionProof(4)
+	if err != nil {
+		t.Fatalf("GenerateInclusionProof() unexpected error: %v", err)
+	}
+	if !VerifyInclusionProof([]byte("leaf4"), proof, originalRoot, nil) {
+		t.Errorf("original proof failed to verify after appending to the clone")
+	}
+}
+
+func TestClone_EmptyTree(t *testing.T) {
+	var tree Tree
+	if clone := tree.Clone(); clone.RootHash() != nil || clone.Size() != 0 {
+		t.Errorf("Clone() of an empty tree = size %d, root %x, want empty", clone.Size(), clone.RootHash())
+	}
+}
+
+func TestNewTreeFromLeafHashes(t *testing.T) {
+	for _, n := range []int{1, 2, 7} {
+		t.Run(fmt.Sprintf("%d leaves", n), func(t *testing.T) {
+			source := buildTestTree(t, n)
+
+			tree, err := NewTreeFromLeafHashes(leafHashesOf(source), nil)
+			if err != nil {
+				t.Fatalf("NewTreeFromLeafHashes() unexpected error: %v", err)
+			}
+			if !bytes.Equal(tree.RootHash(), source.RootHash()) {
+				t.Errorf("RootHash() = %x, want %x", tree.RootHash(), source.RootHash())
+			}
+
+			proof, err := tree.GenerateInclusionProof(n - 1)
+			if err != nil {
+				t.Fatalf("GenerateInclusionProof() unexpected error: %v", err)
+			}
+			if !VerifyInclusionProof([]byte(fmt.Sprintf("leaf%d", n-1)), proof, tree.RootHash(), nil) {
+				t.Errorf("proof from the mirrored tree failed to verify")
+			}
+		})
+	}
+}
+
+func TestNewTreeFromLeafHashes_Errors(t *testing.T) {
+	valid := leafHashesOf(buildTestTree(t, 2))
+
+	tests := []struct {
+		name     string
+		hashes   [][]byte
+		hashFunc hash.Func
+	}{
+		{"no hashes", nil, nil},
+		{"short hash", [][]byte{valid[0], valid[1][:16]}, nil},
+		{"empty hash", [][]byte{valid[0], {}}, nil},
+		{"wrong hash function", valid, hash.SHA512HashFunc},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewTreeFromLeafHashes(tt.hashes, tt.hashFunc); err == nil {
+				t.Errorf("NewTreeFromLeafHashes() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestConstructorsApplyOptions(t *testing.T) {
+	data := [][]byte{[]byte("leaf0"), []byte("leaf1"), []byte("leaf2"), []byte("leaf3")}
+	params := DomainParams{LeafPrefix: []byte("L"), NodePrefix: []byte("N")}
+	want, _ := NewTree(data, nil, WithDomainParams(params), KeepData())
+
+	t.Run("BuildWithProgress", func(t *testing.T) {
+		tree, err := BuildWithProgress(context.Background(), data, nil, nil, WithDomainParams(params), KeepData())
+		if err != nil {
+			t.Fatalf("BuildWithProgress() unexpected error: %v", err)
+		}
+		if !bytes.Equal(tree.RootHash(), want.RootHash()) {
+			t.Errorf("RootHash() = %x, want %x", tree.RootHash(), want.RootHash())
+		}
+		if got, err := tree.LeafData(2); err != nil || !bytes.Equal(got, data[2]) {
+			t.Errorf("LeafData(2) = %q, %v, want %q", got, err, data[2])
+		}
+		if _, err := BuildWithProgress(context.Background(), data[:3], nil, nil, RequirePerfect()); err == nil {
+			t.Error("BuildWithProgress() with RequirePerfect expected error for 3 leaves, got nil")
+		}
+	})
+
+	t.Run("NewTreeFromLeafHashes", func(t *testing.T) {
+		tree, err := NewTreeFromLeafHashes(want.LeafHashes(), nil, WithDomainParams(params))
+		if err != nil {
+			t.Fatalf("NewTreeFromLeafHashes() unexpected error: %v", err)
+		}
+		if !bytes.Equal(tree.RootHash(), want.RootHash()) {
+			t.Errorf("RootHash() = %x, want %x", tree.RootHash(), want.RootHash())
+		}
+		if _, err := NewTreeFromLeafHashes(want.LeafHashes()[:3], nil, RequirePerfect()); err == nil {
+			t.Error("NewTreeFromLeafHashes() with RequirePerfect expected error for 3 leaves, got nil")
+		}
+		for name, opt := range map[string]Option{"KeepData": KeepData(), "Sorted": Sorted()} {
+			if _, err := NewTreeFromLeafHashes(want.LeafHashes(), nil, opt); err == nil {
+				t.Errorf("NewTreeFromLeafHashes() with %s expected error, got nil", name)
+			}
+		}
+	})
+
+	t.Run("NewTreeFromReader", func(t *testing.T) {
+		tree, err := NewTreeFromReader(strings.NewReader("leaf0\nleaf1\nleaf2\nleaf3\n"), nil, WithDomainParams(params), KeepData())
+		if err != nil {
+			t.Fatalf("NewTreeFromReader() unexpected error: %v", err)
+		}
+		if !bytes.Equal(tree.RootHash(), want.RootHash()) {
+			t.Errorf("RootHash() = %x, want %x", tree.RootHash(), want.RootHash())
+		}
+		if got, err := tree.LeafData(3); err != nil || !bytes.Equal(got, data[3]) {
+			t.Errorf("LeafData(3) = %q, %v, want %q", got, err, data[3])
+		}
+
+		if _, err := NewTreeFromReader(strings.NewReader("a\nc\nb\n"), nil, Sorted()); err == nil {
+			t.Error("NewTreeFromReader() with Sorted expected error for unsorted records, got nil")
+		}
+		if _, err := NewTreeFromReader(strings.NewReader("a\n\nb\n"), nil, AllowEmptyLeaves()); err != nil {
+			t.Errorf("NewTreeFromReader() with AllowEmptyLeaves unexpected error: %v", err)
+		}
+	})
+}
+
+func TestRange(t *testing.T) {
+	tree := buildTestTree(t, 5)
+
+	var visited []int
+	tree.Range(func(index int, leafHash []byte) bool {
+		if !bytes.Equal(leafHash, HashLeafData([]byte(fmt.Sprintf("leaf%d", index)), hash.DefaultHashFunc)) {
+			t.Errorf("Range() hash at index %d doesn't match the leaf", index)
+		}
+		visited = append(visited, index)
+		return true
+	})
+	if len(visited) != 5 || visited[0] != 0 || visited[4] != 4 {
+		t.Errorf("Range() visited %v, want indices 0 to 4", visited)
+	}
+
+	visited = nil
+	tree.Range(func(index int, leafHash []byte) bool {
+		visited = append(visited, index)
+		return index < 2
+	})
+	if len(visited) != 3 {
+		t.Errorf("Range() visited %v after stopping, want [0 1 2]", visited)
+	}
+
+	var empty Tree
+	empty.Range(func(int, []byte) bool {
+		t.Errorf("Range() on empty tree called f")
+		return true
+	})
+}
+
+func TestIndicesOf(t *testing.T) {
+	tree, _ := NewTree([][]byte{[]byte("a"), []byte("b")}, nil)
+	_ = tree.Append([]byte("a"))
+	_, _ = tree.AppendBatch([][]byte{[]byte("c"), []byte("b"), []byte("a")})
+	_ = tree.Append([]byte("b"))
+
+	tests := []struct {
+		data []byte
+		want []int
+	}{
+		{[]byte("a"), []int{0, 2, 5}},
+		{[]byte("b"), []int{1, 4, 6}},
+		{[]byte("c"), []int{3}},
+		{[]byte("missing"), nil},
+	}
+	for _, tt := range tests {
+		if got := tree.IndicesOf(tt.data); !slices.Equal(got, tt.want) {
+			t.Errorf("IndicesOf(%q) = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+
+	// moving a duplicate to a lower index keeps the indices ascending
+	if err := tree.UpdateLeaf(3, []byte("a")); err != nil {
+		t.Fatalf("UpdateLeaf() unexpected error: %v", err)
+	}
+	got := tree.IndicesOf([]byte("a"))
+	if want := []int{0, 2, 3, 5}; !slices.Equal(got, want) {
+		t.Errorf("IndicesOf() after UpdateLeaf = %v, want %v", got, want)
+	}
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Validate() unexpected error: %v", err)
+	}
+
+	got[0] = 42
+	if again := tree.IndicesOf([]byte("a")); again[0] != 0 {
+		t.Errorf("IndicesOf() returned the internal slice, got %v after modifying an earlier result", again)
+	}
+}
+
+func TestNewTreeFromReader(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"terminated", "leaf0\nleaf1\nleaf2\n"},
+		{"unterminated last line", "leaf0\nleaf1\nleaf2"},
+		{"crlf", "leaf0\r\nleaf1\r\nleaf2\r\n"},
+	}
+
+	want := buildTestTree(t, 3).RootHash()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := NewTreeFromReader(strings.NewReader(tt.input), nil)
+			if err != nil {
+				t.Fatalf("NewTreeFromReader() unexpected error: %v", err)
+			}
+			if !bytes.Equal(tree.RootHash(), want) {
+				t.Errorf("RootHash() = %x, want %x", tree.RootHash(), want)
+			}
+		})
+	}
+}
+
+func TestNewTreeFromReader_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"empty stream", ""},
+		{"empty line", "leaf0\n\nleaf2\n"},
+		{"record too long", strings.Repeat("x", maxRecordSize+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewTreeFromReader(strings.NewReader(tt.input), nil); err == nil {
+				t.Errorf("NewTreeFromReader() expected error, got nil")
+			}
+		})
+	}
+}
+
+func BenchmarkNewTree_100kLeaves(b *testing.B) {
+	data := make([][]byte, 100_000)
+	for i := range data {
+		data[i] = []byte(fmt.Sprintf("leaf%d", i))
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := NewTree(data, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
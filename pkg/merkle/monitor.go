@@ -0,0 +1,77 @@
+package merkle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+// Errors returned by Monitor.Update describing why an update was rejected.
+var (
+	ErrMonitorSizeDecreased = errors.New("tree size is smaller than the last verified size")
+	ErrMonitorInconsistent  = errors.New("root is not consistent with the last verified root")
+)
+
+// Monitor follows a remote append-only log and checks that it never rewrites its history. It stores the last verified tree size and root, and accepts a newer (size, root) only together with a consistency proof from the stored one. The first update is trusted as is, unless the monitor was created with NewMonitorAt. It is safe for concurrent use.
+type Monitor struct {
+	hashFunc hash.Func
+	size     int    // last verified tree size, 0 before the first update
+	root     []byte // last verified root hash
+	lock     sync.Mutex
+}
+
+// NewMonitor creates a Monitor using the given hash function, or the default one if nil, that trusts the first update it receives.
+func NewMonitor(hashFunc hash.Func) *Monitor {
+	if hashFunc == nil {
+		hashFunc = hash.DefaultHashFunc
+	}
+	return &Monitor{hashFunc: hashFunc}
+}
+
+// NewMonitorAt creates a Monitor starting from a trusted tree size and root, e.g. a checkpoint obtained out of band or saved by a previous run, so even the first update must be consistent with it.
+func NewMonitorAt(size int, root []byte, hashFunc hash.Func) (*Monitor, error) {
+	if size <= 0 || len(root) == 0 {
+		return nil, errors.New("trusted size and root must not be empty")
+	}
+	mon := NewMonitor(hashFunc)
+	mon.size, mon.root = size, bytes.Clone(root)
+	return mon, nil
+}
+
+// Update verifies a new tree size and root of the monitored log against the last verified ones and stores them if they are consistent. It returns ErrMonitorSizeDecreased if the size shrank and ErrMonitorInconsistent if the root differs at the same size or the consistency proof doesn't verify. A rejected update leaves the stored state unchanged. The proof is ignored for the first update and for an unchanged size.
+func (mon *Monitor) Update(newSize int, newRoot []byte, proof *ConsistencyProof) error {
+	if newSize <= 0 || len(newRoot) == 0 {
+		return errors.New("tree size and root must not be empty")
+	}
+
+	mon.lock.Lock()
+	defer mon.lock.Unlock()
+
+	switch {
+	case mon.size == 0: // trust on first use
+	case newSize < mon.size:
+		return fmt.Errorf("%w: %d < %d", ErrMonitorSizeDecreased, newSize, mon.size)
+	case newSize == mon.size:
+		if !RootsEqual(newRoot, mon.root) {
+			return fmt.Errorf("%w: different root at size %d", ErrMonitorInconsistent, newSize)
+		}
+		return nil
+	default:
+		if !VerifyConsistencyProof(mon.size, newSize, mon.root, newRoot, proof, mon.hashFunc) {
+			return fmt.Errorf("%w: consistency proof from size %d to %d does not verify", ErrMonitorInconsistent, mon.size, newSize)
+		}
+	}
+
+	mon.size, mon.root = newSize, bytes.Clone(newRoot)
+	return nil
+}
+
+// Latest returns the last verified tree size and a copy of its root, or 0 and nil before the first update.
+func (mon *Monitor) Latest() (int, []byte) {
+	mon.lock.Lock()
+	defer mon.lock.Unlock()
+	return mon.size, bytes.Clone(mon.root)
+}
@@ -0,0 +1,93 @@
+package merkle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestMonitor_FollowsGrowingLog(t *testing.T) {
+	tree := buildTestTree(t, 1)
+	mon := NewMonitor(nil)
+	if err := mon.Update(1, tree.RootHash(), nil); err != nil {
+		t.Fatalf("first Update() unexpected error: %v", err)
+	}
+
+	for size := 2; size <= 20; size++ {
+		oldSize, _ := mon.Latest()
+		if err := tree.Append([]byte(fmt.Sprintf("leaf%d", size-1))); err != nil {
+			t.Fatalf("Append() unexpected error: %v", err)
+		}
+		if size%3 == 0 { // the monitor doesn't see every size
+			continue
+		}
+
+		proof, err := tree.GenerateConsistencyProof(oldSize)
+		if err != nil {
+			t.Fatalf("GenerateConsistencyProof(%d) unexpected error: %v", oldSize, err)
+		}
+		if err := mon.Update(size, tree.RootHash(), proof); err != nil {
+			t.Fatalf("Update(%d) unexpected error: %v", size, err)
+		}
+	}
+
+	size, root := mon.Latest()
+	if size != 20 || !bytes.Equal(root, tree.RootHash()) {
+		t.Errorf("Latest() = %d, %x, want 20, %x", size, root, tree.RootHash())
+	}
+	if err := mon.Update(20, tree.RootHash(), nil); err != nil {
+		t.Errorf("Update() with an unchanged size and root unexpected error: %v", err)
+	}
+}
+
+func TestMonitor_RejectsRewrittenHistory(t *testing.T) {
+	honest := buildTestTree(t, 8)
+	oldRoot, _ := honest.RootAt(5)
+
+	forkData := leafDataRange(0, 8)
+	forkData[2] = []byte("rewritten")
+	fork, _ := NewTree(forkData, nil)
+	forkProof, _ := fork.GenerateConsistencyProof(5)
+
+	honestProof, _ := honest.GenerateConsistencyProof(5)
+	forkedAtSameSize, _ := fork.RootAt(5)
+
+	tests := []struct {
+		name    string
+		size    int
+		root    []byte
+		proof   *ConsistencyProof
+		wantErr error
+	}{
+		{"shrinking size", 4, oldRoot, nil, ErrMonitorSizeDecreased},
+		{"different root at the same size", 5, forkedAtSameSize, nil, ErrMonitorInconsistent},
+		{"forked log", 8, fork.RootHash(), forkProof, ErrMonitorInconsistent},
+		{"proof for another root", 8, fork.RootHash(), honestProof, ErrMonitorInconsistent},
+		{"missing proof", 8, honest.RootHash(), nil, ErrMonitorInconsistent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mon, err := NewMonitorAt(5, oldRoot, nil)
+			if err != nil {
+				t.Fatalf("NewMonitorAt() unexpected error: %v", err)
+			}
+			if err := mon.Update(tt.size, tt.root, tt.proof); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Update() error = %v, want %v", err, tt.wantErr)
+			}
+			if size, root := mon.Latest(); size != 5 || !bytes.Equal(root, oldRoot) {
+				t.Errorf("rejected Update() changed the state to %d, %x", size, root)
+			}
+		})
+	}
+}
+
+func TestMonitor_InvalidInput(t *testing.T) {
+	if _, err := NewMonitorAt(0, []byte("root"), nil); err == nil {
+		t.Error("NewMonitorAt() with size 0 expected error, got nil")
+	}
+	if err := NewMonitor(nil).Update(3, nil, nil); err == nil {
+		t.Error("Update() with an empty root expected error, got nil")
+	}
+}
@@ -0,0 +1,300 @@
+package merkle
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+// saveMagic identifies a saved Merkle Tree, followed by the format version.
+var saveMagic = []byte("MRKL")
+
+const (
+	saveVersion = 2
+
+	// maxSavedHashSize bounds the length of a single hash or domain prefix accepted by Load, so a corrupt length can't trigger a huge allocation.
+	maxSavedHashSize = 1 << 10
+)
+
+// Flags of the options encoded by Save.
+const (
+	saveRequirePerfect = 1 << iota
+	saveKeepData
+	saveSorted
+	saveAllowEmptyLeaves
+	saveAllowEmptyTree
+	saveKnownFlags = 1<<iota - 1
+)
+
+// Save writes the tree to w so it can be reconstructed by Load. The format is the magic "MRKL" and a version byte, followed by a byte of option flags, the leaf and node domain prefixes each prefixed by its uvarint length (0 for the RFC 6962 default), a uvarint leaf count, each leaf hash prefixed by its uvarint length, and finally the root hash as a checksum. If the tree keeps leaf data, each leaf hash is followed by the uvarint length of the data plus one and the data, or a single 0 if the leaf has no data. The hash function isn't encoded, see MarshalBinary. An empty tree can only be saved if it was created with AllowEmptyTree, its checksum is the RFC 6962 empty root.
+func (t *Tree) Save(w io.Writer) error {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if len(t.leaves) == 0 && !t.allowEmptyTree {
+		return errors.New("tree is empty")
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.Write(saveMagic)
+	bw.WriteByte(saveVersion)
+	bw.WriteByte(t.saveFlags())
+	writeLengthPrefixed(bw, t.domain.LeafPrefix)
+	writeLengthPrefixed(bw, t.domain.NodePrefix)
+	bw.Write(binary.AppendUvarint(nil, uint64(len(t.leaves))))
+	for _, leaf := range t.leaves {
+		writeLengthPrefixed(bw, leaf.Hash)
+		if !t.keepData {
+			continue
+		}
+		if leaf.Data == nil {
+			bw.WriteByte(0) // e.g. the schema leaf, see NewTreeWithSchema
+			continue
+		}
+		bw.Write(binary.AppendUvarint(nil, uint64(len(leaf.Data))+1))
+		bw.Write(leaf.Data)
+	}
+	writeLengthPrefixed(bw, t.rootHash)
+
+	return bw.Flush() // bufio.Writer keeps the first write error and returns it here
+}
+
+// saveFlags returns the options of the tree encoded as flags for Save.
+func (t *Tree) saveFlags() byte {
+	var flags byte
+	for _, f := range []struct {
+		set  bool
+		flag byte
+	}{
+		{t.requirePerfect, saveRequirePerfect},
+		{t.keepData, saveKeepData},
+		{t.sorted, saveSorted},
+		{t.allowEmpty, saveAllowEmptyLeaves},
+		{t.allowEmptyTree, saveAllowEmptyTree},
+	} {
+		if f.set {
+			flags |= f.flag
+		}
+	}
+	return flags
+}
+
+// writeLengthPrefixed writes b prefixed by its uvarint length.
+func writeLengthPrefixed(w *bufio.Writer, b []byte) {
+	w.Write(binary.AppendUvarint(nil, uint64(len(b))))
+	w.Write(b)
+}
+
+// Load reads a tree written by Save and rebuilds its internal nodes with the given hash function. The options and domain params are restored from the saved tree. It fails if the rebuilt root doesn't match the saved root, e.g. because the file is corrupt or was saved with a different hash function, if retained leaf data doesn't match its leaf hash, or if r has data after the saved tree. Trees saved by version 1 of the format are loaded with the default options. A tree without leaves is only loaded if it was saved with AllowEmptyTree.
+func Load(r io.Reader, hashFunc hash.Func) (*Tree, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(saveMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	if string(header[:len(saveMagic)]) != string(saveMagic) {
+		return nil, errors.New("invalid saved tree: bad magic")
+	}
+
+	var opts []Option
+	switch version := header[len(saveMagic)]; version {
+	case 1: // no options or domain params, the leaf hashes follow
+	case saveVersion:
+		var err error
+		if opts, err = readSavedOptions(br); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported saved tree version %d", version)
+	}
+	t, err := newTree(hashFunc, opts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid saved tree: %w", err)
+	}
+
+	count, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("read leaf count: %w", err)
+	}
+
+	leaves := make([]*Node, 0, min(count, 1<<16)) // don't trust the count for the initial allocation
+	var prev []byte
+	for i := uint64(0); i < count; i++ {
+		leafHash, err := readLengthPrefixed(br)
+		if err != nil {
+			return nil, fmt.Errorf("read leaf %d: %w", i, err)
+		}
+		leaf := &Node{Hash: leafHash}
+		if t.keepData {
+			if leaf.Data, err = readLeafData(br); err != nil {
+				return nil, fmt.Errorf("read leaf %d data: %w", i, err)
+			}
+		}
+		if leaf.Data != nil {
+			if !bytes.Equal(t.domain.HashLeaf(leaf.Data, t.hashFunc), leafHash) {
+				return nil, fmt.Errorf("invalid saved tree: leaf %d data does not match its hash", i)
+			}
+			if err := t.checkLeafLocked(leaf.Data, prev, len(leaves) > 0); err != nil { // t is not shared yet, no lock needed
+				return nil, fmt.Errorf("invalid saved tree: leaf %d: %w", i, err)
+			}
+			prev = leaf.Data
+		}
+		leaves = append(leaves, leaf)
+	}
+	if err := t.checkLeafCount(len(leaves)); err != nil {
+		return nil, fmt.Errorf("invalid saved tree: %w", err)
+	}
+
+	checksum, err := readLengthPrefixed(br)
+	if err != nil {
+		return nil, fmt.Errorf("read root checksum: %w", err)
+	}
+	if _, err := br.ReadByte(); err != io.EOF {
+		return nil, errors.New("invalid saved tree: trailing data")
+	}
+
+	t.setLeaves(leaves, t.domain.nodeHasher(t.hashFunc))
+	if !RootsEqual(t.rootHash, checksum) {
+		return nil, errors.New("invalid saved tree: root does not match checksum")
+	}
+	return t, nil
+}
+
+// readSavedOptions reads the option flags and domain params written by Save and returns them as options.
+func readSavedOptions(r *bufio.Reader) ([]Option, error) {
+	flags, err := r.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("read options: %w", err)
+	}
+	if flags&^saveKnownFlags != 0 {
+		return nil, fmt.Errorf("invalid saved tree: unknown option flags %#x", flags)
+	}
+
+	var opts []Option
+	for _, f := range []struct {
+		flag byte
+		opt  Option
+	}{
+		{saveRequirePerfect, RequirePerfect()},
+		{saveKeepData, KeepData()},
+		{saveSorted, Sorted()},
+		{saveAllowEmptyLeaves, AllowEmptyLeaves()},
+		{saveAllowEmptyTree, AllowEmptyTree()},
+	} {
+		if flags&f.flag != 0 {
+			opts = append(opts, f.opt)
+		}
+	}
+	if flags&saveSorted != 0 && flags&saveKeepData == 0 {
+		return nil, errors.New("invalid saved tree: sorted tree without leaf data")
+	}
+
+	var domain DomainParams
+	if domain.LeafPrefix, err = readDomainPrefix(r); err != nil {
+		return nil, fmt.Errorf("read leaf prefix: %w", err)
+	}
+	if domain.NodePrefix, err = readDomainPrefix(r); err != nil {
+		return nil, fmt.Errorf("read node prefix: %w", err)
+	}
+	return append(opts, WithDomainParams(domain)), nil
+}
+
+// readDomainPrefix reads a domain prefix written by Save, returning nil for the RFC 6962 default.
+func readDomainPrefix(r *bufio.Reader) ([]byte, error) {
+	prefix, err := readLengthPrefixed(r)
+	if err != nil || len(prefix) == 0 {
+		return nil, err
+	}
+	return prefix, nil
+}
+
+// readLeafData reads the retained data of a leaf written by Save, returning nil if the leaf has no data. The data length isn't bounded like readLengthPrefixed, but the data is read in chunks, so a corrupt length fails at the end of r instead of allocating the full length up front.
+func readLeafData(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	size--
+
+	buf := bytes.NewBuffer(make([]byte, 0, min(size, 1<<16)))
+	if _, err := io.CopyN(buf, r, int64(min(size, math.MaxInt64))); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, so the tree can be stored with gob and friends. The encoding is the registered name of the hash function prefixed by its uvarint length, followed by the Save format. Only the leaf hashes, retained leaf data, options and domain params are encoded, the internal nodes are rebuilt by UnmarshalBinary. It fails if the hash function isn't registered by name, see hash.Register.
+func (t *Tree) MarshalBinary() ([]byte, error) {
+	t.lock.RLock()
+	hashFunc := t.hashFunc
+	t.lock.RUnlock()
+
+	name := hash.NameOf(hashFunc)
+	if name == "" {
+		return nil, errors.New("cannot marshal a tree whose hash function is not registered")
+	}
+
+	var buf bytes.Buffer
+	buf.Write(binary.AppendUvarint(nil, uint64(len(name))))
+	buf.WriteString(name)
+	if err := t.Save(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, replacing the contents of t with a tree encoded by MarshalBinary. The hash function is looked up by its registered name, so it must be registered in the decoding process as well.
+func (t *Tree) UnmarshalBinary(data []byte) error {
+	br := bufio.NewReader(bytes.NewReader(data))
+	name, err := readLengthPrefixed(br)
+	if err != nil {
+		return fmt.Errorf("read hash algorithm: %w", err)
+	}
+	hashFunc, err := hash.ByName(string(name))
+	if err != nil {
+		return err
+	}
+	loaded, err := Load(br, hashFunc)
+	if err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.leaves = loaded.leaves
+	t.indexMap = loaded.indexMap
+	t.hashFunc, t.hashName = loaded.hashFunc, loaded.hashName
+	t.requirePerfect, t.keepData, t.sorted = loaded.requirePerfect, loaded.keepData, loaded.sorted
+	t.allowEmpty, t.allowEmptyTree = loaded.allowEmpty, loaded.allowEmptyTree
+	t.domain = loaded.domain
+	t.setRootLocked(loaded.root)
+	return nil
+}
+
+// readLengthPrefixed reads a byte slice prefixed by its uvarint length.
+func readLengthPrefixed(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxSavedHashSize {
+		return nil, fmt.Errorf("hash length %d exceeds limit", size)
+	}
+
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
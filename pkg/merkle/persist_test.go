@@ -0,0 +1,281 @@
+package merkle
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+func TestSaveLoad_RoundTrip(t *testing.T) {
+	for _, size := range []int{1, 2, 7, 10000} {
+		tree := buildTestTree(t, size)
+
+		var buf bytes.Buffer
+		if err := tree.Save(&buf); err != nil {
+			t.Fatalf("Save() unexpected error: %v", err)
+		}
+
+		loaded, err := Load(&buf, nil)
+		if err != nil {
+			t.Fatalf("Load() unexpected error: %v", err)
+		}
+		if !bytes.Equal(loaded.RootHash(), tree.RootHash()) {
+			t.Errorf("size %d: loaded RootHash() = %x, want %x", size, loaded.RootHash(), tree.RootHash())
+		}
+		if len(loaded.leaves) != size {
+			t.Errorf("size %d: loaded leaf count = %d, want %d", size, len(loaded.leaves), size)
+		}
+	}
+}
+
+func TestLoad_TreeIsUsable(t *testing.T) {
+	tree := buildTestTree(t, 5)
+	var buf bytes.Buffer
+	if err := tree.Save(&buf); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	loaded, err := Load(&buf, nil)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	proof, err := loaded.GenerateInclusionProofByData([]byte("leaf3"))
+	if err != nil {
+		t.Fatalf("GenerateInclusionProofByData() unexpected error: %v", err)
+	}
+	if !VerifyInclusionProof([]byte("leaf3"), proof, loaded.RootHash(), nil) {
+		t.Errorf("VerifyInclusionProof() failed on loaded tree")
+	}
+
+	if err := loaded.Append([]byte("leaf5")); err != nil {
+		t.Fatalf("Append() unexpected error: %v", err)
+	}
+	if err := tree.Append([]byte("leaf5")); err != nil {
+		t.Fatalf("Append() unexpected error: %v", err)
+	}
+	if !bytes.Equal(loaded.RootHash(), tree.RootHash()) {
+		t.Errorf("RootHash() after Append = %x, want %x", loaded.RootHash(), tree.RootHash())
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tree := buildTestTree(t, 3)
+	var buf bytes.Buffer
+	if err := tree.Save(&buf); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	saved := buf.Bytes()
+
+	corrupted := bytes.Clone(saved)
+	corrupted[12] ^= 0xff // flip a byte of the first leaf hash
+
+	badVersion := bytes.Clone(saved)
+	badVersion[4] = 99
+
+	tests := []struct {
+		name     string
+		data     []byte
+		hashFunc hash.Func
+	}{
+		{"empty input", nil, nil},
+		{"bad magic", append([]byte("XXXX"), saved[4:]...), nil},
+		{"unsupported version", badVersion, nil},
+		{"truncated", saved[:len(saved)-5], nil},
+		{"corrupted leaf", corrupted, nil},
+		{"trailing data", append(bytes.Clone(saved), 0), nil},
+		{"unknown option flags", append(append(bytes.Clone(saved[:5]), 0x80), saved[6:]...), nil},
+		{"different hash function", saved, hash.SHA3HashFunc},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(bytes.NewReader(tt.data), tt.hashFunc); err == nil {
+				t.Errorf("Load() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestSaveLoad_OptionsAndData(t *testing.T) {
+	domain := DomainParams{LeafPrefix: []byte{0x10}, NodePrefix: []byte{0x11}}
+	tests := []struct {
+		name string
+		tree func() (*Tree, error)
+	}{
+		{"keep data with empty leaves", func() (*Tree, error) {
+			return NewTree([][]byte{[]byte("a"), nil, []byte("c")}, nil, KeepData(), AllowEmptyLeaves(), AllowEmptyTree())
+		}},
+		{"sorted", func() (*Tree, error) { return NewTree(leafDataRange(0, 5), nil, Sorted()) }},
+		{"require perfect", func() (*Tree, error) { return NewTree(leafDataRange(0, 4), nil, RequirePerfect()) }},
+		{"custom domain params", func() (*Tree, error) {
+			return NewTree(leafDataRange(0, 5), hash.SHA3HashFunc, KeepData(), WithDomainParams(domain))
+		}},
+		{"schema leaf without data", func() (*Tree, error) {
+			return NewTreeWithSchema([]byte("schema"), leafDataRange(0, 3), nil, KeepData())
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := tt.tree()
+			if err != nil {
+				t.Fatalf("building tree unexpected error: %v", err)
+			}
+			encoded, err := tree.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary() unexpected error: %v", err)
+			}
+			var loaded Tree
+			if err := loaded.UnmarshalBinary(encoded); err != nil {
+				t.Fatalf("UnmarshalBinary() unexpected error: %v", err)
+			}
+
+			if !bytes.Equal(loaded.RootHash(), tree.RootHash()) {
+				t.Errorf("loaded RootHash() = %x, want %x", loaded.RootHash(), tree.RootHash())
+			}
+			if loaded.requirePerfect != tree.requirePerfect || loaded.keepData != tree.keepData || loaded.sorted != tree.sorted ||
+				loaded.allowEmpty != tree.allowEmpty || loaded.allowEmptyTree != tree.allowEmptyTree {
+				t.Errorf("loaded options differ from the saved tree")
+			}
+			if !loaded.domain.equal(tree.domain) {
+				t.Errorf("loaded domain params = %+v, want %+v", loaded.domain, tree.domain)
+			}
+			for i, leaf := range tree.leaves {
+				want, wantErr := tree.LeafData(i)
+				got, err := loaded.LeafData(i)
+				if (err != nil) != (wantErr != nil) || !bytes.Equal(got, want) {
+					t.Errorf("loaded LeafData(%d) = %q, %v, want %q, %v", i, got, err, want, wantErr)
+				}
+				if (leaf.Data == nil) != (loaded.leaves[i].Data == nil) {
+					t.Errorf("leaf %d: loaded data retained = %v, want %v", i, loaded.leaves[i].Data != nil, leaf.Data != nil)
+				}
+			}
+		})
+	}
+}
+
+func TestLoad_RejectsDataMismatch(t *testing.T) {
+	tree, _ := NewTree([][]byte{[]byte("a"), []byte("b")}, nil, KeepData())
+	var buf bytes.Buffer
+	if err := tree.Save(&buf); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	saved := buf.Bytes()
+
+	i := len(saved) - len(tree.RootHash()) - 2 // the data of the last leaf, before the length-prefixed root
+	if saved[i] != 'b' {
+		t.Fatalf("saved[%d] = %q, want the data of the last leaf", i, saved[i])
+	}
+	saved[i] = 'x' // the leaf hashes and root still match, only the retained data differs
+	if _, err := Load(bytes.NewReader(saved), nil); err == nil {
+		t.Error("Load() expected error for leaf data not matching its hash, got nil")
+	}
+}
+
+func TestLoad_Version1(t *testing.T) {
+	tree := buildTestTree(t, 3)
+	v1 := append([]byte("MRKL"), 1, 3)
+	for _, leaf := range tree.leaves {
+		v1 = append(append(v1, byte(len(leaf.Hash))), leaf.Hash...)
+	}
+	v1 = append(append(v1, byte(len(tree.RootHash()))), tree.RootHash()...)
+
+	loaded, err := Load(bytes.NewReader(v1), nil)
+	if err != nil {
+		t.Fatalf("Load() unexpected error for a version 1 tree: %v", err)
+	}
+	if !bytes.Equal(loaded.RootHash(), tree.RootHash()) {
+		t.Errorf("loaded RootHash() = %x, want %x", loaded.RootHash(), tree.RootHash())
+	}
+}
+
+func TestSave_EmptyTree(t *testing.T) {
+	var tree Tree
+	if err := tree.Save(&bytes.Buffer{}); err == nil {
+		t.Errorf("Save() expected error for empty tree, got nil")
+	}
+}
+
+func TestSaveLoad_EmptyTree(t *testing.T) {
+	tree, err := NewTree(nil, hash.SHA3HashFunc, AllowEmptyTree())
+	if err != nil {
+		t.Fatalf("NewTree() unexpected error: %v", err)
+	}
+	encoded, err := tree.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() unexpected error: %v", err)
+	}
+	var loaded Tree
+	if err := loaded.UnmarshalBinary(encoded); err != nil {
+		t.Fatalf("UnmarshalBinary() unexpected error: %v", err)
+	}
+
+	if want := hash.SHA3HashFunc([]byte{}); !bytes.Equal(loaded.RootHash(), want) {
+		t.Errorf("loaded RootHash() = %x, want the empty root %x", loaded.RootHash(), want)
+	}
+	if loaded.Size() != 0 || !loaded.allowEmptyTree {
+		t.Errorf("loaded Size() = %d, allowEmptyTree = %v, want 0 and true", loaded.Size(), loaded.allowEmptyTree)
+	}
+	if err := loaded.Append([]byte("a")); err != nil {
+		t.Fatalf("Append() to the loaded empty tree unexpected error: %v", err)
+	}
+	if want, _ := NewTree([][]byte{[]byte("a")}, hash.SHA3HashFunc); !bytes.Equal(loaded.RootHash(), want.RootHash()) {
+		t.Errorf("RootHash() after Append() = %x, want %x", loaded.RootHash(), want.RootHash())
+	}
+
+	var buf bytes.Buffer
+	if err := tree.Save(&buf); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	saved := buf.Bytes()
+	saved[5] &^= saveAllowEmptyTree
+	if _, err := Load(bytes.NewReader(saved), hash.SHA3HashFunc); err == nil {
+		t.Error("Load() of an empty tree without AllowEmptyTree expected error, got nil")
+	}
+}
+
+func TestTreeGob_RoundTrip(t *testing.T) {
+	for _, hashFunc := range []hash.Func{nil, hash.SHA3HashFunc} {
+		tree, err := NewTree(leafDataRange(0, 7), hashFunc)
+		if err != nil {
+			t.Fatalf("NewTree() unexpected error: %v", err)
+		}
+
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(tree); err != nil {
+			t.Fatalf("gob Encode() unexpected error: %v", err)
+		}
+		var decoded Tree
+		if err := gob.NewDecoder(&buf).Decode(&decoded); err != nil {
+			t.Fatalf("gob Decode() unexpected error: %v", err)
+		}
+
+		if !bytes.Equal(decoded.RootHash(), tree.RootHash()) {
+			t.Errorf("decoded RootHash() = %x, want %x", decoded.RootHash(), tree.RootHash())
+		}
+		if err := decoded.Append([]byte("leaf7")); err != nil {
+			t.Fatalf("Append() to decoded tree unexpected error: %v", err)
+		}
+		if err := tree.Append([]byte("leaf7")); err != nil {
+			t.Fatalf("Append() unexpected error: %v", err)
+		}
+		if !bytes.Equal(decoded.RootHash(), tree.RootHash()) {
+			t.Errorf("decoded RootHash() after Append() = %x, want %x", decoded.RootHash(), tree.RootHash())
+		}
+	}
+}
+
+func TestTreeMarshalBinary_Errors(t *testing.T) {
+	unregistered := func(data []byte) []byte { return hash.SHA256HashFunc(append([]byte("x"), data...)) }
+	tree, _ := NewTree(leafDataRange(0, 3), unregistered)
+	if _, err := tree.MarshalBinary(); err == nil {
+		t.Error("MarshalBinary() expected error for an unregistered hash function, got nil")
+	}
+
+	var decoded Tree
+	if err := decoded.UnmarshalBinary(append([]byte{4}, "nope"...)); err == nil {
+		t.Error("UnmarshalBinary() expected error for an unknown hash algorithm, got nil")
+	}
+}
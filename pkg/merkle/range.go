@@ -0,0 +1,96 @@
+package merkle
+
+import (
+	"errors"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+// RangeProof proves that a contiguous range of leaves is included in the Merkle Tree, e.g. to serve a page of entries with a single proof. Subtrees entirely inside the range are recomputed by the verifier from the leaf data, so the proof only carries the hashes of the maximal subtrees outside the range along its two boundaries, at most about 2*log2(TreeSize) hashes.
+type RangeProof struct {
+	Start    int      // Index of the first leaf in the range
+	End      int      // Index after the last leaf in the range
+	TreeSize int      // Number of leaves in the tree the proof was generated for
+	Hashes   [][]byte // Hashes of the subtrees outside the range, in left-to-right order
+}
+
+// GenerateRangeProof generates a proof of the leaves in [start, end).
+func (t *Tree) GenerateRangeProof(start, end int) (*RangeProof, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if start < 0 || start >= end || end > len(t.leaves) {
+		return nil, errors.New("invalid range: must satisfy 0 <= start < end <= number of leaves")
+	}
+
+	var hashes [][]byte
+	t.rangePath(start, end, 0, len(t.leaves), &hashes)
+	return &RangeProof{Start: start, End: end, TreeSize: len(t.leaves), Hashes: hashes}, nil
+}
+
+// rangePath recursively collects the hashes of the subtrees outside [start, end) within the subtree over n leaves starting at nodeStart, in left-to-right order.
+func (t *Tree) rangePath(start, end int, nodeStart int, n int, hashes *[][]byte) {
+	if nodeStart+n <= start || nodeStart >= end { // disjoint, the verifier needs the whole subtree hash
+		*hashes = append(*hashes, t.historicSubtreeHash(nodeStart, n))
+		return
+	}
+	if start <= nodeStart && nodeStart+n <= end { // inside the range, the verifier hashes it from the leaf data
+		return
+	}
+
+	k := largestPowerOfTwoLessThan(n)
+	t.rangePath(start, end, nodeStart, k, hashes)
+	t.rangePath(start, end, nodeStart+k, n-k, hashes)
+}
+
+// VerifyRangeProof verifies that the provided leaf data is the range of leaves [proof.Start, proof.End) of the Merkle Tree with the given root hash.
+func VerifyRangeProof(leafData [][]byte, proof *RangeProof, rootHash []byte, hashFunc hash.Func) bool {
+	if proof == nil || len(rootHash) == 0 {
+		return false
+	}
+	if proof.Start < 0 || proof.Start >= proof.End || proof.End > proof.TreeSize || len(leafData) != proof.End-proof.Start {
+		return false
+	}
+
+	if hashFunc == nil {
+		hashFunc = hash.DefaultHashFunc
+	}
+
+	leafHashes := make([][]byte, len(leafData))
+	for i, d := range leafData {
+		leafHashes[i] = HashLeafData(d, hashFunc)
+	}
+
+	remaining := proof.Hashes
+	root, ok := rangeRoot(proof.Start, proof.End, leafHashes, 0, proof.TreeSize, &remaining, hashFunc)
+	if !ok || len(remaining) != 0 { // every proof hash must be consumed exactly once
+		return false
+	}
+	return RootsEqual(root, rootHash)
+}
+
+// rangeRoot recomputes the hash of the subtree over n leaves starting at nodeStart from the hashes of the leaves in [start, end), consuming the proof hashes for the subtrees outside the range. It reports false if the proof runs out of hashes.
+func rangeRoot(start, end int, leafHashes [][]byte, nodeStart int, n int, hashes *[][]byte, hashFunc hash.Func) ([]byte, bool) {
+	if nodeStart+n <= start || nodeStart >= end {
+		if len(*hashes) == 0 {
+			return nil, false
+		}
+		h := (*hashes)[0]
+		*hashes = (*hashes)[1:]
+		return h, true
+	}
+	if start <= nodeStart && nodeStart+n <= end {
+		return rootFromLeafHashes(leafHashes[nodeStart-start:nodeStart-start+n], hashFunc, DomainParams{}), true
+	}
+
+	k := largestPowerOfTwoLessThan(n)
+	left, ok := rangeRoot(start, end, leafHashes, nodeStart, k, hashes, hashFunc)
+	if !ok {
+		return nil, false
+	}
+	right, ok := rangeRoot(start, end, leafHashes, nodeStart+k, n-k, hashes, hashFunc)
+	if !ok {
+		return nil, false
+	}
+	return HashInternalNodes(left, right, hashFunc), true
+}
@@ -0,0 +1,103 @@
+package merkle
+
+import (
+	"bytes"
+	"fmt"
+	"math/bits"
+	"testing"
+)
+
+// leafDataRange returns the data of the test leaves in [start, end), see buildTestTree.
+func leafDataRange(start, end int) [][]byte {
+	var data [][]byte
+	for i := start; i < end; i++ {
+		data = append(data, []byte(fmt.Sprintf("leaf%d", i)))
+	}
+	return data
+}
+
+func TestRangeProof_AllRanges(t *testing.T) {
+	const n = 13
+	tree := buildTestTree(t, n)
+	root := tree.RootHash()
+	maxHashes := 2 * bits.Len(uint(n-1))
+
+	for start := 0; start < n; start++ {
+		for end := start + 1; end <= n; end++ {
+			proof, err := tree.GenerateRangeProof(start, end)
+			if err != nil {
+				t.Fatalf("GenerateRangeProof(%d, %d) unexpected error: %v", start, end, err)
+			}
+			if !VerifyRangeProof(leafDataRange(start, end), proof, root, nil) {
+				t.Errorf("VerifyRangeProof() for [%d, %d) = false, want true", start, end)
+			}
+			if len(proof.Hashes) > maxHashes {
+				t.Errorf("range [%d, %d) proof has %d hashes, want at most %d", start, end, len(proof.Hashes), maxHashes)
+			}
+		}
+	}
+}
+
+func TestRangeProof_HistoricRoot(t *testing.T) {
+	tree := buildTestTree(t, 13)
+	oldRoot := tree.RootHash()
+	proof, err := tree.GenerateRangeProof(6, 10)
+	if err != nil {
+		t.Fatalf("GenerateRangeProof() unexpected error: %v", err)
+	}
+	if err := tree.Append([]byte("leaf13")); err != nil {
+		t.Fatalf("Append() unexpected error: %v", err)
+	}
+
+	if !VerifyRangeProof(leafDataRange(6, 10), proof, oldRoot, nil) {
+		t.Errorf("proof generated at size 13 doesn't verify against the root of size 13 after an append")
+	}
+	if VerifyRangeProof(leafDataRange(6, 10), proof, tree.RootHash(), nil) {
+		t.Errorf("proof generated at size 13 verifies against the root of size 14")
+	}
+}
+
+func TestVerifyRangeProof_Rejects(t *testing.T) {
+	tree := buildTestTree(t, 13)
+	root := tree.RootHash()
+	proof, err := tree.GenerateRangeProof(7, 9) // spans the boundary of the left 8-leaf subtree
+	if err != nil {
+		t.Fatalf("GenerateRangeProof() unexpected error: %v", err)
+	}
+	data := leafDataRange(7, 9)
+
+	tests := []struct {
+		name  string
+		data  [][]byte
+		proof *RangeProof
+		root  []byte
+	}{
+		{"nil proof", data, nil, root},
+		{"tampered leaf", [][]byte{data[0], []byte("tampered")}, proof, root},
+		{"swapped leaves", [][]byte{data[1], data[0]}, proof, root},
+		{"missing leaf", data[:1], proof, root},
+		{"shifted range", data, &RangeProof{Start: 8, End: 10, TreeSize: 13, Hashes: proof.Hashes}, root},
+		{"wrong tree size", data, &RangeProof{Start: 7, End: 9, TreeSize: 12, Hashes: proof.Hashes}, root},
+		{"missing hash", data, &RangeProof{Start: 7, End: 9, TreeSize: 13, Hashes: proof.Hashes[1:]}, root},
+		{"extra hash", data, &RangeProof{Start: 7, End: 9, TreeSize: 13, Hashes: append(append([][]byte(nil), proof.Hashes...), proof.Hashes[0])}, root},
+		{"empty range", nil, &RangeProof{Start: 7, End: 7, TreeSize: 13}, root},
+		{"wrong root", data, proof, bytes.Repeat([]byte{0xff}, 32)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if VerifyRangeProof(tt.data, tt.proof, tt.root, nil) {
+				t.Errorf("VerifyRangeProof() = true, want false")
+			}
+		})
+	}
+}
+
+func TestGenerateRangeProof_InvalidRange(t *testing.T) {
+	tree := buildTestTree(t, 5)
+	for _, r := range [][2]int{{-1, 2}, {3, 3}, {4, 2}, {0, 6}} {
+		if _, err := tree.GenerateRangeProof(r[0], r[1]); err == nil {
+			t.Errorf("GenerateRangeProof(%d, %d) expected error, got nil", r[0], r[1])
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package merkle
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+// Sorted makes the tree a sorted set: NewTree sorts the data and rejects duplicates, and Append only accepts data greater than the last leaf. It implies KeepData, as the leaf data is needed to bracket absent values in non-membership proofs.
+func Sorted() Option {
+	return func(t *Tree) {
+		t.sorted = true
+		t.keepData = true
+	}
+}
+
+// NonMembershipProof proves that a value is absent from a sorted tree by proving the inclusion of the two adjacent leaves bracketing it. Left is nil if the value sorts before the first leaf, and Right is nil if it sorts after the last leaf.
+type NonMembershipProof struct {
+	Left      *InclusionProof // Inclusion proof of the greatest leaf below the value
+	LeftData  []byte          // Data of the left bracketing leaf
+	Right     *InclusionProof // Inclusion proof of the smallest leaf above the value
+	RightData []byte          // Data of the right bracketing leaf
+}
+
+// GenerateNonMembershipProof generates a proof that the data is not a leaf of the tree. It only works for trees created with the Sorted option and returns an error if the data is present.
+func (t *Tree) GenerateNonMembershipProof(data []byte) (*NonMembershipProof, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if !t.sorted {
+		return nil, errors.New("non-membership proofs require a sorted tree")
+	}
+	if len(t.leaves) == 0 {
+		return nil, errors.New("tree is empty")
+	}
+
+	pos, found := slices.BinarySearchFunc(t.leaves, data, func(leaf *Node, target []byte) int {
+		return bytes.Compare(leaf.Data, target)
+	})
+	if found {
+		return nil, errors.New("data is a member of the tree")
+	}
+
+	proof := &NonMembershipProof{}
+	if pos > 0 { // there is a leaf below the data
+		left, err := t.generateInclusionProofLocked(pos - 1)
+		if err != nil {
+			return nil, err
+		}
+		proof.Left, proof.LeftData = left, bytes.Clone(t.leaves[pos-1].Data)
+	}
+	if pos < len(t.leaves) { // there is a leaf above the data
+		right, err := t.generateInclusionProofLocked(pos)
+		if err != nil {
+			return nil, err
+		}
+		proof.Right, proof.RightData = right, bytes.Clone(t.leaves[pos].Data)
+	}
+	return proof, nil
+}
+
+// VerifyNonMembershipProof verifies that the data is absent from the sorted tree with the given root hash. It checks that the bracketing leaves are adjacent and strictly enclose the data, or that a single bracketing leaf is the first or last leaf of the tree, and that their inclusion proofs verify against the root.
+func VerifyNonMembershipProof(data []byte, proof *NonMembershipProof, rootHash []byte, hashFunc hash.Func) bool {
+	if proof == nil || (proof.Left == nil && proof.Right == nil) {
+		return false
+	}
+
+	treeSize := 0
+	if proof.Left != nil {
+		treeSize = proof.Left.ValidAtSize() // binds the claimed leaf index and tree size to the path shape
+		if treeSize == 0 || bytes.Compare(proof.LeftData, data) >= 0 {
+			return false
+		}
+		if !VerifyInclusionProof(proof.LeftData, proof.Left, rootHash, hashFunc) {
+			return false
+		}
+	}
+	if proof.Right != nil {
+		size := proof.Right.ValidAtSize()
+		if size == 0 || (treeSize != 0 && size != treeSize) || bytes.Compare(data, proof.RightData) >= 0 {
+			return false
+		}
+		if !VerifyInclusionProof(proof.RightData, proof.Right, rootHash, hashFunc) {
+			return false
+		}
+		treeSize = size
+	}
+
+	switch {
+	case proof.Left == nil: // data sorts before the first leaf
+		return proof.Right.LeafIndex == 0
+	case proof.Right == nil: // data sorts after the last leaf
+		return proof.Left.LeafIndex == treeSize-1
+	default:
+		return proof.Left.LeafIndex+1 == proof.Right.LeafIndex
+	}
+}
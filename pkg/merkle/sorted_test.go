@@ -0,0 +1,128 @@
+package merkle
+
+import (
+	"bytes"
+	"testing"
+)
+
+func newSortedTestTree(t *testing.T) *Tree {
+	t.Helper()
+	tree, err := NewTree([][]byte{[]byte("delta"), []byte("bravo"), []byte("foxtrot"), []byte("charlie"), []byte("echo")}, nil, Sorted())
+	if err != nil {
+		t.Fatalf("Failed to create sorted tree: %v", err)
+	}
+	return tree
+}
+
+func TestSorted_OrdersLeaves(t *testing.T) {
+	tree := newSortedTestTree(t)
+	want := []string{"bravo", "charlie", "delta", "echo", "foxtrot"}
+
+	for i, w := range want {
+		got, err := tree.LeafData(i)
+		if err != nil {
+			t.Fatalf("LeafData(%d) unexpected error: %v", i, err)
+		}
+		if string(got) != w {
+			t.Errorf("LeafData(%d) = %q, want %q", i, got, w)
+		}
+	}
+}
+
+func TestSorted_RejectsDisorder(t *testing.T) {
+	if _, err := NewTree([][]byte{[]byte("a"), []byte("b"), []byte("a")}, nil, Sorted()); err == nil {
+		t.Errorf("NewTree() expected error for duplicate leaves, got nil")
+	}
+
+	tree := newSortedTestTree(t)
+	root := tree.RootHash()
+	for _, data := range []string{"alpha", "foxtrot"} {
+		if err := tree.Append([]byte(data)); err == nil {
+			t.Errorf("Append(%q) expected error, got nil", data)
+		}
+	}
+	if !bytes.Equal(tree.RootHash(), root) {
+		t.Errorf("RootHash() changed after rejected Append")
+	}
+	if err := tree.Append([]byte("golf")); err != nil {
+		t.Errorf("Append(%q) unexpected error: %v", "golf", err)
+	}
+}
+
+func TestNonMembershipProof(t *testing.T) {
+	tree := newSortedTestTree(t)
+
+	tests := []struct {
+		name      string
+		data      string
+		wantLeft  bool
+		wantRight bool
+	}{
+		{"between leaves", "cobalt", true, true},
+		{"before first leaf", "alpha", false, true},
+		{"after last leaf", "zulu", true, false},
+		{"prefix of a leaf", "ech", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proof, err := tree.GenerateNonMembershipProof([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("GenerateNonMembershipProof() unexpected error: %v", err)
+			}
+			if (proof.Left != nil) != tt.wantLeft || (proof.Right != nil) != tt.wantRight {
+				t.Errorf("GenerateNonMembershipProof() left = %v, right = %v, want %v, %v", proof.Left != nil, proof.Right != nil, tt.wantLeft, tt.wantRight)
+			}
+			if !VerifyNonMembershipProof([]byte(tt.data), proof, tree.RootHash(), nil) {
+				t.Errorf("VerifyNonMembershipProof() = false, want true")
+			}
+		})
+	}
+}
+
+func TestNonMembershipProof_Errors(t *testing.T) {
+	tree := newSortedTestTree(t)
+	if _, err := tree.GenerateNonMembershipProof([]byte("charlie")); err == nil {
+		t.Errorf("GenerateNonMembershipProof() expected error for member, got nil")
+	}
+
+	unsorted, _ := NewTree([][]byte{[]byte("a"), []byte("c")}, nil)
+	if _, err := unsorted.GenerateNonMembershipProof([]byte("b")); err == nil {
+		t.Errorf("GenerateNonMembershipProof() expected error for unsorted tree, got nil")
+	}
+}
+
+func TestVerifyNonMembershipProof_Rejects(t *testing.T) {
+	tree := newSortedTestTree(t)
+	proof, _ := tree.GenerateNonMembershipProof([]byte("cobalt")) // brackets: charlie (1), delta (2)
+	first, _ := tree.GenerateNonMembershipProof([]byte("alpha"))
+	last, _ := tree.GenerateNonMembershipProof([]byte("zulu"))
+	bravo, _ := tree.GenerateInclusionProof(0)
+	echo, _ := tree.GenerateInclusionProof(3)
+
+	tests := []struct {
+		name  string
+		data  string
+		proof *NonMembershipProof
+	}{
+		{"nil proof", "cobalt", nil},
+		{"empty proof", "cobalt", &NonMembershipProof{}},
+		{"data outside bracket", "echo-ish", proof},
+		{"data equal to bracket", "charlie", proof},
+		{"non-adjacent leaves", "cobalt", &NonMembershipProof{Left: bravo, LeftData: []byte("bravo"), Right: proof.Right, RightData: proof.RightData}},
+		{"skips a member", "cz", &NonMembershipProof{Left: proof.Left, LeftData: proof.LeftData, Right: echo, RightData: []byte("echo")}},
+		{"right alone is not the first leaf", "cobalt", &NonMembershipProof{Right: proof.Right, RightData: proof.RightData}},
+		{"left alone is not the last leaf", "cobalt", &NonMembershipProof{Left: proof.Left, LeftData: proof.LeftData}},
+		{"wrong bracket data", "cobalt", &NonMembershipProof{Left: proof.Left, LeftData: []byte("cherry"), Right: proof.Right, RightData: proof.RightData}},
+		{"before first with wrong data", "bz", first},
+		{"after last with wrong data", "a", last},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if VerifyNonMembershipProof([]byte(tt.data), tt.proof, tree.RootHash(), nil) {
+				t.Errorf("VerifyNonMembershipProof() = true, want false")
+			}
+		})
+	}
+}
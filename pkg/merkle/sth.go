@@ -0,0 +1,52 @@
+package merkle
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+)
+
+// sthContext prefixes the signed payload of a tree head, so the signature can't be confused with a signature over other data made with the same key.
+var sthContext = []byte("dp-teals/merkle/sth/v1\x00")
+
+// SignedTreeHead is a root hash committed to by the log operator: an Ed25519 signature over the tree size, the time of signing and the root hash.
+type SignedTreeHead struct {
+	TreeSize  int    `json:"tree_size"`
+	Timestamp int64  `json:"timestamp"` // Unix time in milliseconds, chosen by the signer
+	RootHash  []byte `json:"root_hash"`
+	Signature []byte `json:"signature"`
+}
+
+// SignRoot signs the root hash of a tree of the given size at the given timestamp with the Ed25519 private key.
+func SignRoot(priv ed25519.PrivateKey, size int, root []byte, timestamp int64) (*SignedTreeHead, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid Ed25519 private key")
+	}
+	if size < 0 {
+		return nil, errors.New("invalid tree size")
+	}
+	if len(root) == 0 || len(root) > 0xffff {
+		return nil, errors.New("root hash must not be empty or longer than 65535 bytes")
+	}
+
+	sth := &SignedTreeHead{TreeSize: size, Timestamp: timestamp, RootHash: append([]byte(nil), root...)}
+	sth.Signature = ed25519.Sign(priv, sth.signedPayload())
+	return sth, nil
+}
+
+// VerifySTH reports whether the signature of the tree head is valid for its size, timestamp and root hash under the Ed25519 public key.
+func VerifySTH(pub ed25519.PublicKey, sth *SignedTreeHead) bool {
+	if sth == nil || len(pub) != ed25519.PublicKeySize || sth.TreeSize < 0 || len(sth.RootHash) == 0 || len(sth.RootHash) > 0xffff {
+		return false
+	}
+	return ed25519.Verify(pub, sth.signedPayload(), sth.Signature)
+}
+
+// signedPayload returns the canonical encoding of the signed fields: the context string, followed by the big-endian uint64 tree size, the big-endian int64 timestamp, and the root hash prefixed by its big-endian uint16 length.
+func (sth *SignedTreeHead) signedPayload() []byte {
+	buf := append([]byte(nil), sthContext...)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(sth.TreeSize))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(sth.Timestamp))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(sth.RootHash)))
+	return append(buf, sth.RootHash...)
+}
@@ -0,0 +1,83 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"testing"
+)
+
+func TestSignRootVerifySTH(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey() unexpected error: %v", err)
+	}
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey() unexpected error: %v", err)
+	}
+
+	tree := buildTestTree(t, 5)
+	sth, err := SignRoot(priv, tree.Size(), tree.RootHash(), 1700000000000)
+	if err != nil {
+		t.Fatalf("SignRoot() unexpected error: %v", err)
+	}
+	if !VerifySTH(pub, sth) {
+		t.Fatal("VerifySTH() = false for an untampered tree head, want true")
+	}
+
+	tests := []struct {
+		name   string
+		pub    ed25519.PublicKey
+		tamper func(sth *SignedTreeHead)
+	}{
+		{"tree size", pub, func(sth *SignedTreeHead) { sth.TreeSize++ }},
+		{"timestamp", pub, func(sth *SignedTreeHead) { sth.Timestamp++ }},
+		{"root hash", pub, func(sth *SignedTreeHead) { sth.RootHash[0] ^= 0xff }},
+		{"truncated root hash", pub, func(sth *SignedTreeHead) { sth.RootHash = sth.RootHash[:16] }},
+		{"signature", pub, func(sth *SignedTreeHead) { sth.Signature[0] ^= 0xff }},
+		{"other key", otherPub, func(sth *SignedTreeHead) {}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tampered := *sth
+			tampered.RootHash = bytes.Clone(sth.RootHash)
+			tampered.Signature = bytes.Clone(sth.Signature)
+			tt.tamper(&tampered)
+
+			if VerifySTH(tt.pub, &tampered) {
+				t.Errorf("VerifySTH() = true after tampering with the %s, want false", tt.name)
+			}
+		})
+	}
+
+	if VerifySTH(pub, nil) {
+		t.Errorf("VerifySTH() = true for nil tree head, want false")
+	}
+}
+
+func TestSignRoot_Errors(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		priv ed25519.PrivateKey
+		size int
+		root []byte
+	}{
+		{"invalid key", priv[:10], 1, []byte("root")},
+		{"negative size", priv, -1, []byte("root")},
+		{"empty root", priv, 1, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := SignRoot(tt.priv, tt.size, tt.root, 0); err == nil {
+				t.Errorf("SignRoot() expected error, got nil")
+			}
+		})
+	}
+}
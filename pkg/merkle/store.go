@@ -0,0 +1,36 @@
+package merkle
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+// NodeStore resolves node hashes from a content-addressable store, e.g. IPFS, by their reference.
+type NodeStore interface {
+	// Get returns the node hash stored under the given reference, or an error if it can't be resolved.
+	Get(hash []byte) ([]byte, error)
+}
+
+// VerifyInclusionProofViaStore verifies an inclusion proof whose siblings are given as references into a node store instead of inline hashes. Each reference is resolved from the store and the resulting path is verified like VerifyInclusionProof. A store failure is returned as an error, while a proof that doesn't match the root yields false with a nil error.
+func VerifyInclusionProofViaStore(leafData []byte, siblingRefs [][]byte, left []bool, root []byte, store NodeStore, hashFunc hash.Func) (bool, error) {
+	if store == nil {
+		return false, errors.New("no node store provided")
+	}
+	if len(siblingRefs) != len(left) {
+		return false, errors.New("sibling references and directions length mismatch")
+	}
+
+	siblings := make([][]byte, len(siblingRefs))
+	for i, ref := range siblingRefs {
+		sibling, err := store.Get(ref)
+		if err != nil {
+			return false, fmt.Errorf("resolve sibling %d: %w", i, err)
+		}
+		siblings[i] = sibling
+	}
+
+	proof := &InclusionProof{Siblings: siblings, Left: left}
+	return VerifyInclusionProof(leafData, proof, root, hashFunc), nil
+}
@@ -0,0 +1,77 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+var errNodeNotFound = errors.New("node not found")
+
+// memoryStore is an in-memory NodeStore addressing each node hash by its SHA-256 digest.
+type memoryStore map[string][]byte
+
+func (s memoryStore) put(node []byte) []byte {
+	ref := sha256.Sum256(node)
+	s[hex.EncodeToString(ref[:])] = node
+	return ref[:]
+}
+
+func (s memoryStore) Get(hash []byte) ([]byte, error) {
+	node, ok := s[hex.EncodeToString(hash)]
+	if !ok {
+		return nil, errNodeNotFound
+	}
+	return node, nil
+}
+
+func TestVerifyInclusionProofViaStore(t *testing.T) {
+	tree := buildTestTree(t, 7)
+	leafData := []byte("leaf3")
+
+	proof, err := tree.GenerateInclusionProof(3)
+	if err != nil {
+		t.Fatalf("GenerateInclusionProof() unexpected error: %v", err)
+	}
+
+	store := memoryStore{}
+	refs := make([][]byte, len(proof.Siblings))
+	for i, sibling := range proof.Siblings {
+		refs[i] = store.put(sibling)
+	}
+
+	tests := []struct {
+		name      string
+		leafData  []byte
+		refs      [][]byte
+		left      []bool
+		root      []byte
+		store     NodeStore
+		want      bool
+		expectErr bool
+		errIs     error
+	}{
+		{"valid proof", leafData, refs, proof.Left, tree.RootHash(), store, true, false, nil},
+		{"wrong leaf data", []byte("leaf4"), refs, proof.Left, tree.RootHash(), store, false, false, nil},
+		{"wrong root", leafData, refs, proof.Left, make([]byte, 32), store, false, false, nil},
+		{"missing node", leafData, [][]byte{refs[0], make([]byte, 32), refs[2]}, proof.Left, tree.RootHash(), store, false, true, errNodeNotFound},
+		{"length mismatch", leafData, refs, proof.Left[:1], tree.RootHash(), store, false, true, nil},
+		{"nil store", leafData, refs, proof.Left, tree.RootHash(), nil, false, true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifyInclusionProofViaStore(tt.leafData, tt.refs, tt.left, tt.root, tt.store, nil)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("VerifyInclusionProofViaStore() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if tt.errIs != nil && !errors.Is(err, tt.errIs) {
+				t.Errorf("VerifyInclusionProofViaStore() error = %v, want %v", err, tt.errIs)
+			}
+			if got != tt.want {
+				t.Errorf("VerifyInclusionProofViaStore() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package merkle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"slices"
+)
+
+// UpdateLeaf replaces the data of the leaf at the given index and rehashes only the path from that leaf to the root.
+// This breaks the append-only semantics of the log: the root changes for every size that includes the leaf, so inclusion and consistency proofs generated before the update no longer verify against the new root. It is meant for trees committing to a mutable set, not for audit logs.
+func (t *Tree) UpdateLeaf(index int, data []byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if index < 0 || index >= len(t.leaves) {
+		return errors.New("invalid index")
+	}
+	if !t.allowEmpty && len(data) == 0 {
+		return errors.New("empty leaf not allowed")
+	}
+	if t.sorted {
+		if index > 0 && bytes.Compare(data, t.leaves[index-1].Data) <= 0 ||
+			index < len(t.leaves)-1 && bytes.Compare(data, t.leaves[index+1].Data) >= 0 {
+			return errors.New("sorted tree requires leaves in strictly ascending order")
+		}
+	}
+
+	leaf := t.leaves[index]
+	t.removeIndexLocked(leaf.Hash, index)
+
+	// assign new slices rather than overwriting the hashes in place, which may be shared with proofs handed out earlier
+	leaf.Hash = t.domain.HashLeaf(data, t.hashFunc)
+	if t.keepData {
+		leaf.Data = bytes.Clone(data)
+	}
+
+	hashHex := hex.EncodeToString(leaf.Hash)
+	pos, _ := slices.BinarySearch(t.indexMap[hashHex], index) // keep the indices of duplicates in ascending order
+	t.indexMap[hashHex] = slices.Insert(t.indexMap[hashHex], pos, index)
+
+	for node := leaf.Parent; node != nil; node = node.Parent {
+		node.Hash = t.domain.HashNode(node.Left.Hash, node.Right.Hash, t.hashFunc)
+	}
+	t.setRootLocked(t.root)
+	return nil
+}
+
+// removeIndexLocked removes the index from the index map entry of the leaf hash, dropping the entry once it is empty. It assumes the caller holds the write lock.
+func (t *Tree) removeIndexLocked(leafHash []byte, index int) {
+	hashHex := hex.EncodeToString(leafHash)
+	indices := slices.DeleteFunc(t.indexMap[hashHex], func(i int) bool { return i == index })
+	if len(indices) == 0 {
+		delete(t.indexMap, hashHex)
+		return
+	}
+	t.indexMap[hashHex] = indices
+}
@@ -0,0 +1,105 @@
+package merkle
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestUpdateLeaf(t *testing.T) {
+	for _, n := range []int{1, 2, 5, 8} {
+		for index := 0; index < n; index++ {
+			t.Run(fmt.Sprintf("size %d index %d", n, index), func(t *testing.T) {
+				tree := buildTestTree(t, n)
+				oldProof, err := tree.GenerateInclusionProof(index)
+				if err != nil {
+					t.Fatalf("GenerateInclusionProof() unexpected error: %v", err)
+				}
+
+				updated := []byte("updated")
+				if err := tree.UpdateLeaf(index, updated); err != nil {
+					t.Fatalf("UpdateLeaf() unexpected error: %v", err)
+				}
+
+				data := make([][]byte, n)
+				for i := range data {
+					data[i] = []byte(fmt.Sprintf("leaf%d", i))
+				}
+				data[index] = updated
+				want, err := NewTree(data, nil)
+				if err != nil {
+					t.Fatalf("NewTree() unexpected error: %v", err)
+				}
+				if !bytes.Equal(tree.RootHash(), want.RootHash()) {
+					t.Errorf("RootHash() after update = %x, want %x", tree.RootHash(), want.RootHash())
+				}
+
+				if VerifyInclusionProof([]byte(fmt.Sprintf("leaf%d", index)), oldProof, tree.RootHash(), nil) {
+					t.Errorf("proof of the replaced leaf still verifies against the new root")
+				}
+				proof, err := tree.GenerateInclusionProofByData(updated)
+				if err != nil {
+					t.Fatalf("GenerateInclusionProofByData() unexpected error: %v", err)
+				}
+				if proof.LeafIndex != index || !VerifyInclusionProof(updated, proof, tree.RootHash(), nil) {
+					t.Errorf("proof of the updated leaf has index %d or fails to verify, want index %d", proof.LeafIndex, index)
+				}
+				if _, err := tree.GenerateInclusionProofByData([]byte(fmt.Sprintf("leaf%d", index))); err == nil {
+					t.Errorf("replaced data is still found in the index map")
+				}
+			})
+		}
+	}
+}
+
+func TestUpdateLeaf_Errors(t *testing.T) {
+	tree := buildTestTree(t, 3)
+	root := tree.RootHash()
+
+	sorted, err := NewTree([][]byte{[]byte("a"), []byte("c"), []byte("e")}, nil, Sorted())
+	if err != nil {
+		t.Fatalf("NewTree() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		tree  *Tree
+		index int
+		data  []byte
+	}{
+		{"negative index", tree, -1, []byte("x")},
+		{"index out of range", tree, 3, []byte("x")},
+		{"empty data", tree, 0, nil},
+		{"sorted order broken", sorted, 1, []byte("f")},
+		{"sorted duplicate", sorted, 1, []byte("a")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.tree.UpdateLeaf(tt.index, tt.data); err == nil {
+				t.Errorf("UpdateLeaf() expected error, got nil")
+			}
+		})
+	}
+	if !bytes.Equal(tree.RootHash(), root) {
+		t.Errorf("RootHash() changed after failed updates")
+	}
+}
+
+func TestUpdateLeaf_Duplicates(t *testing.T) {
+	tree, err := NewTree([][]byte{[]byte("x"), []byte("y"), []byte("x")}, nil)
+	if err != nil {
+		t.Fatalf("NewTree() unexpected error: %v", err)
+	}
+	if err := tree.UpdateLeaf(0, []byte("z")); err != nil {
+		t.Fatalf("UpdateLeaf() unexpected error: %v", err)
+	}
+
+	proof, err := tree.GenerateInclusionProofByData([]byte("x"))
+	if err != nil {
+		t.Fatalf("GenerateInclusionProofByData() unexpected error: %v", err)
+	}
+	if proof.LeafIndex != 2 {
+		t.Errorf("LeafIndex of the remaining duplicate = %d, want 2", proof.LeafIndex)
+	}
+}
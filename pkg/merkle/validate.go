@@ -0,0 +1,92 @@
+package merkle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// Validate checks the integrity of the tree, e.g. after loading it or in a long-running process. It recomputes every internal node hash from its children bottom-up and checks it against the stored hash, checks that the nodes form the RFC 6962 shape over t.leaves with consistent Parent pointers, that the cached root hash matches the root, and that the index map agrees with the leaves. It returns an error describing the first inconsistency found.
+func (t *Tree) Validate() error {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.root == nil {
+		if len(t.leaves) != 0 {
+			return fmt.Errorf("tree has %d leaves but no root", len(t.leaves))
+		}
+		return nil
+	}
+	if t.root.Parent != nil {
+		return errors.New("root has a parent")
+	}
+	if len(t.leaves) == 0 {
+		return errors.New("tree has a root but no leaves")
+	}
+	if err := t.validateNode(t.root, 0, len(t.leaves)); err != nil {
+		return err
+	}
+	if !bytes.Equal(t.rootHash, t.root.Hash) {
+		return errors.New("cached root hash does not match the root node")
+	}
+	return t.validateIndexMap()
+}
+
+// validateNode checks the subtree rooted at n, which must span the leaves [start, start+size), and returns an error describing the first inconsistency.
+func (t *Tree) validateNode(n *Node, start int, size int) error {
+	if n == nil {
+		return fmt.Errorf("missing node over leaves [%d, %d)", start, start+size)
+	}
+	if size == 1 {
+		if n != t.leaves[start] {
+			return fmt.Errorf("leaf %d is not reachable from the root", start)
+		}
+		if n.Left != nil || n.Right != nil {
+			return fmt.Errorf("leaf %d has children", start)
+		}
+		return nil
+	}
+
+	if n.Left == nil || n.Right == nil {
+		return fmt.Errorf("internal node over leaves [%d, %d) is missing a child", start, start+size)
+	}
+	if n.Left.Parent != n || n.Right.Parent != n {
+		return fmt.Errorf("children of the node over leaves [%d, %d) don't point back to it", start, start+size)
+	}
+
+	k := largestPowerOfTwoLessThan(size)
+	if err := t.validateNode(n.Left, start, k); err != nil {
+		return err
+	}
+	if err := t.validateNode(n.Right, start+k, size-k); err != nil {
+		return err
+	}
+	if !bytes.Equal(n.Hash, t.domain.HashNode(n.Left.Hash, n.Right.Hash, t.hashFunc)) {
+		return fmt.Errorf("hash mismatch at the node over leaves [%d, %d)", start, start+size)
+	}
+	return nil
+}
+
+// validateIndexMap checks that the index map holds exactly the index of every leaf under its hash, in ascending order.
+func (t *Tree) validateIndexMap() error {
+	entries := 0
+	for hashHex, indices := range t.indexMap {
+		for j, i := range indices {
+			if i < 0 || i >= len(t.leaves) {
+				return fmt.Errorf("index map entry %s refers to invalid leaf %d", hashHex, i)
+			}
+			if hex.EncodeToString(t.leaves[i].Hash) != hashHex {
+				return fmt.Errorf("index map entry %s refers to leaf %d with a different hash", hashHex, i)
+			}
+			if j > 0 && indices[j-1] >= i {
+				return fmt.Errorf("index map entry %s is not in ascending order", hashHex)
+			}
+		}
+		entries += len(indices)
+	}
+	if entries != len(t.leaves) {
+		return fmt.Errorf("index map has %d entries, want %d", entries, len(t.leaves))
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package merkle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(tree *Tree)
+		wantErr bool
+	}{
+		{"intact tree", func(tree *Tree) {}, false},
+		{"leaf hash flipped", func(tree *Tree) { tree.leaves[2].Hash = bytes.Repeat([]byte{0xff}, 32) }, true},
+		{"internal hash flipped", func(tree *Tree) { tree.root.Left.Hash = bytes.Repeat([]byte{0xff}, 32) }, true},
+		{"broken parent pointer", func(tree *Tree) { tree.leaves[1].Parent = tree.root }, true},
+		{"leaves out of order", func(tree *Tree) { tree.leaves[0], tree.leaves[1] = tree.leaves[1], tree.leaves[0] }, true},
+		{"stale cached root", func(tree *Tree) { tree.rootHash = bytes.Repeat([]byte{0xff}, 32) }, true},
+		{"missing index map entry", func(tree *Tree) { delete(tree.indexMap, hex.EncodeToString(tree.leaves[3].Hash)) }, true},
+		{"wrong index map entry", func(tree *Tree) {
+			tree.indexMap[hex.EncodeToString(tree.leaves[3].Hash)] = []int{4}
+		}, true},
+		{"extra leaf", func(tree *Tree) { tree.leaves = append(tree.leaves, &Node{Hash: []byte{1}}) }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := buildTestTree(t, 5)
+			tt.corrupt(tree)
+
+			err := tree.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_AfterMutations(t *testing.T) {
+	tree := buildTestTree(t, 3)
+	if _, err := tree.AppendBatch([][]byte{[]byte("a"), []byte("b"), []byte("a")}); err != nil {
+		t.Fatalf("AppendBatch() unexpected error: %v", err)
+	}
+	if err := tree.UpdateLeaf(1, []byte("b")); err != nil {
+		t.Fatalf("UpdateLeaf() unexpected error: %v", err)
+	}
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Validate() unexpected error: %v", err)
+	}
+	if err := tree.Clone().Validate(); err != nil {
+		t.Errorf("Validate() of clone unexpected error: %v", err)
+	}
+
+	var empty Tree
+	if err := empty.Validate(); err != nil {
+		t.Errorf("Validate() of empty tree unexpected error: %v", err)
+	}
+}
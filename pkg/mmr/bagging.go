@@ -0,0 +1,64 @@
+package mmr
+
+import "github.com/andrlikjirka/dp-teals/pkg/hash"
+
+// BaggingOrder selects how the peaks of an MMR are combined (bagged) into its root hash. Different ecosystems bag in different orders, so the same leaves produce different roots and proofs under each order, and a verifier has to use the order of the MMR the proof was generated from.
+type BaggingOrder int
+
+const (
+	// BagRightToLeft folds the peaks starting from the rightmost one, root = H(p0, H(p1, H(p2, p3))). It is the default.
+	BagRightToLeft BaggingOrder = iota
+	// BagLeftToRight folds the peaks starting from the leftmost one, root = H(H(H(p0, p1), p2), p3), similar to the bagging used by Grin.
+	BagLeftToRight
+)
+
+// Option configures optional behavior of an MMR created by NewMMR.
+type Option func(*MMR)
+
+// WithBaggingOrder makes the MMR bag its peaks in the given order. The root hash, inclusion proofs and consistency proofs all follow it; the proofs record the order, so VerifyInclusionProofAtSize and VerifyConsistencyProof check them against the matching root.
+func WithBaggingOrder(order BaggingOrder) Option {
+	return func(m *MMR) {
+		m.bagging = order
+	}
+}
+
+// BagPeaks combines the peak hashes into the root hash the way RootHash does by default (BagRightToLeft), so the root of an MMR can be reproduced from its Peaks without the MMR itself. The peaks are expected in the order Peaks returns them, from right to left. It uses the default hash function if hashFunc is nil, and returns nil for no peaks. MMRs created with WithBaggingOrder(BagLeftToRight) bag their peaks differently.
+func BagPeaks(peaks [][]byte, hashFunc hash.Func) []byte {
+	if hashFunc == nil {
+		hashFunc = hash.DefaultHashFunc
+	}
+	leftToRight := make([][]byte, len(peaks))
+	for i, peak := range peaks {
+		leftToRight[len(peaks)-1-i] = peak
+	}
+	return bagPeakHashes(leftToRight, BagRightToLeft, hashFunc)
+}
+
+// bagPeakHashes combines the peak hashes (left to right) into a single hash in the given order. It returns nil for no peaks.
+func bagPeakHashes(peaks [][]byte, order BaggingOrder, hashFunc hash.Func) []byte {
+	if len(peaks) == 0 {
+		return nil
+	}
+	if order == BagLeftToRight {
+		root := peaks[0]
+		for _, peak := range peaks[1:] {
+			root = HashInternalNodes(root, peak, hashFunc)
+		}
+		return root
+	}
+
+	root := peaks[len(peaks)-1] // start with the rightmost peak
+	for i := len(peaks) - 2; i >= 0; i-- {
+		root = HashInternalNodes(peaks[i], root, hashFunc)
+	}
+	return root
+}
+
+// peakHashes returns the hashes of the given peak nodes.
+func peakHashes(peaks []*Node) [][]byte {
+	hashes := make([][]byte, len(peaks))
+	for i, p := range peaks {
+		hashes[i] = p.Hash
+	}
+	return hashes
+}
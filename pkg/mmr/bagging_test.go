@@ -0,0 +1,118 @@
+package mmr
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+// buildMMRWithOrder appends n leaves "leaf0".."leaf<n-1>" to an MMR bagging in the given order.
+func buildMMRWithOrder(t *testing.T, n int, order BaggingOrder) *MMR {
+	t.Helper()
+	m := NewMMR(nil, WithBaggingOrder(order))
+	for i := 0; i < n; i++ {
+		if err := m.Append([]byte(fmt.Sprintf("leaf%d", i))); err != nil {
+			t.Fatalf("Append() unexpected error: %v", err)
+		}
+	}
+	return m
+}
+
+func TestBaggingOrder_Roots(t *testing.T) {
+	rtl := buildMMRWithOrder(t, 7, BagRightToLeft) // peaks of 4, 2 and 1 leaves
+	ltr := buildMMRWithOrder(t, 7, BagLeftToRight)
+	p := rtl.Peaks() // right to left
+	left, middle, right := p[2], p[1], p[0]
+
+	wantRTL := HashInternalNodes(left, HashInternalNodes(middle, right, hash.DefaultHashFunc), hash.DefaultHashFunc)
+	wantLTR := HashInternalNodes(HashInternalNodes(left, middle, hash.DefaultHashFunc), right, hash.DefaultHashFunc)
+
+	if !bytes.Equal(rtl.RootHash(), wantRTL) {
+		t.Errorf("right-to-left RootHash() = %x, want %x", rtl.RootHash(), wantRTL)
+	}
+	if !bytes.Equal(ltr.RootHash(), wantLTR) {
+		t.Errorf("left-to-right RootHash() = %x, want %x", ltr.RootHash(), wantLTR)
+	}
+	if bytes.Equal(rtl.RootHash(), ltr.RootHash()) {
+		t.Error("both bagging orders produce the same root for 3 peaks")
+	}
+	if !bytes.Equal(NewMMR(nil).RootHash(), NewMMR(nil, WithBaggingOrder(BagRightToLeft)).RootHash()) {
+		t.Error("right-to-left is not the default bagging order")
+	}
+}
+
+func TestBaggingOrder_ProofsVerifyInternally(t *testing.T) {
+	for _, order := range []BaggingOrder{BagRightToLeft, BagLeftToRight} {
+		for size := 1; size <= 13; size++ {
+			m := buildMMRWithOrder(t, size, order)
+			root := m.RootHash()
+
+			for i := 0; i < size; i++ {
+				proof, err := m.GenerateInclusionProof(i)
+				if err != nil {
+					t.Fatalf("order %d, size %d: GenerateInclusionProof(%d) unexpected error: %v", order, size, i, err)
+				}
+				if err := VerifyInclusionProofAtSize([]byte(fmt.Sprintf("leaf%d", i)), proof, root, size, nil); err != nil {
+					t.Errorf("order %d, size %d: VerifyInclusionProofAtSize(%d) error = %v", order, size, i, err)
+				}
+			}
+
+			for oldSize := 1; oldSize < size; oldSize++ {
+				oldRoot := buildMMRWithOrder(t, oldSize, order).RootHash()
+				proof, err := m.GenerateConsistencyProof(oldSize, size)
+				if err != nil {
+					t.Fatalf("order %d: GenerateConsistencyProof(%d, %d) unexpected error: %v", order, oldSize, size, err)
+				}
+				if !VerifyConsistencyProof(proof, oldRoot, root, nil) {
+					t.Errorf("order %d: VerifyConsistencyProof(%d, %d) = false, want true", order, oldSize, size)
+				}
+			}
+		}
+	}
+}
+
+func TestBaggingOrder_ProofsDontVerifyAcrossOrders(t *testing.T) {
+	rtl := buildMMRWithOrder(t, 7, BagRightToLeft)
+	ltr := buildMMRWithOrder(t, 7, BagLeftToRight)
+
+	proof, err := ltr.GenerateInclusionProof(4) // leaf under the middle peak
+	if err != nil {
+		t.Fatalf("GenerateInclusionProof() unexpected error: %v", err)
+	}
+	if VerifyInclusionProof([]byte("leaf4"), proof, rtl.RootHash(), nil) {
+		t.Error("left-to-right proof verifies against the right-to-left root")
+	}
+
+	proof.Bagging = BagRightToLeft // claim the other order
+	if err := VerifyInclusionProofAtSize([]byte("leaf4"), proof, ltr.RootHash(), 7, nil); err != ErrProofBadBagging {
+		t.Errorf("VerifyInclusionProofAtSize() with a mislabelled order error = %v, want %v", err, ErrProofBadBagging)
+	}
+
+	consistency, err := ltr.GenerateConsistencyProof(3, 7)
+	if err != nil {
+		t.Fatalf("GenerateConsistencyProof() unexpected error: %v", err)
+	}
+	if VerifyConsistencyProof(consistency, buildMMRWithOrder(t, 3, BagRightToLeft).RootHash(), rtl.RootHash(), nil) {
+		t.Error("left-to-right consistency proof verifies against right-to-left roots")
+	}
+}
+
+func TestBagPeaks(t *testing.T) {
+	if got := BagPeaks(nil, nil); got != nil {
+		t.Errorf("BagPeaks(nil) = %x, want nil", got)
+	}
+
+	for _, hashFunc := range []hash.Func{hash.SHA256HashFunc, hash.SHA3HashFunc} {
+		m := NewMMR(hashFunc)
+		for n := 1; n <= 16; n++ {
+			if err := m.Append([]byte(fmt.Sprintf("leaf%d", n))); err != nil {
+				t.Fatalf("Append() unexpected error: %v", err)
+			}
+			if got, want := BagPeaks(m.Peaks(), hashFunc), m.RootHash(); !bytes.Equal(got, want) {
+				t.Errorf("size %d: BagPeaks(Peaks()) = %x, want RootHash() %x", n, got, want)
+			}
+		}
+	}
+}
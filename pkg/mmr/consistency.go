@@ -22,6 +22,7 @@ type ConsistencyProof struct {
 	OldPeaksHashes   [][]byte           // Hashes of the old peaks (for verification)
 	ConsistencyPaths []*ConsistencyPath // Inclusion paths from old peaks to new peaks
 	RightPeaks       [][]byte           // Additional peaks completing NewSize
+	Bagging          BaggingOrder       // Bagging order of the MMR the proof was generated from
 }
 
 // GenerateConsistencyProof proves that treeSize1 is a prefix of treeSize2.
@@ -43,14 +44,21 @@ func (m *MMR) GenerateConsistencyProof(treeSize1, treeSize2 int) (*ConsistencyPr
 		OldPeaksHashes:   make([][]byte, 0),
 		ConsistencyPaths: make([]*ConsistencyPath, 0),
 		RightPeaks:       make([][]byte, 0),
+		Bagging:          m.bagging,
 	}
 
 	if treeSize1 == treeSize2 {
 		return proof, nil // Trivial case
 	}
 
-	oldPeaks := m.getPeaksAtSize(treeSize1)
-	newPeaks := m.getPeaksAtSize(treeSize2)
+	oldPeaks, err := m.getPeaksAtSize(treeSize1)
+	if err != nil {
+		return nil, err
+	}
+	newPeaks, err := m.getPeaksAtSize(treeSize2)
+	if err != nil {
+		return nil, err
+	}
 
 	// Collect the hashes of the old peaks for the proof
 	for _, peak := range oldPeaks {
@@ -111,14 +119,14 @@ func (m *MMR) GenerateConsistencyProof(treeSize1, treeSize2 int) (*ConsistencyPr
 }
 
 // getPeaksAtSize retrieves the peak nodes as they existed when the MMR had exactly 'size' leaves.
-// This is done by analyzing the binary representation of given MMR size to determine which peaks would have existed at that point in time.
-func (m *MMR) getPeaksAtSize(size int) []*Node {
+// This is done by analyzing the binary representation of given MMR size to determine which peaks would have existed at that point in time. Each historic peak is found by descending from the current peak covering its leaves, which fails with ErrPruned if it lies below a pruned peak.
+func (m *MMR) getPeaksAtSize(size int) ([]*Node, error) {
 	// ensure size is within bounds
 	if size > m.size {
 		size = m.size
 	}
 	if size <= 0 {
-		return nil
+		return nil, nil
 	}
 
 	var peaks []*Node
@@ -129,21 +137,45 @@ func (m *MMR) getPeaksAtSize(size int) []*Node {
 	for bit := bitLen - 1; bit >= 0; bit-- {
 		if (size & (1 << bit)) != 0 {
 			// find the root of the 2^bit subtree starting at 'offset'
-			node := m.Leaves[offset]
-			for i := 0; i < bit; i++ {
-				if node.Parent != nil {
-					node = node.Parent
-				}
+			node, err := m.subtreeRootLocked(offset, bit)
+			if err != nil {
+				return nil, err
 			}
 			peaks = append(peaks, node)
 			offset += 1 << bit
 		}
 	}
-	return peaks
+	return peaks, nil
+}
+
+// subtreeRootLocked returns the node at the given height whose leftmost leaf has the index offset. It assumes the caller holds the lock.
+func (m *MMR) subtreeRootLocked(offset int, height int) (*Node, error) {
+	start := 0 // index of the first leaf under the current peak
+	for _, peak := range m.peaks {
+		if offset >= start+1<<peak.Height {
+			start += 1 << peak.Height
+			continue
+		}
+
+		node := peak
+		for node.Height > height {
+			if node.Left == nil {
+				return nil, ErrPruned
+			}
+			half := 1 << (node.Height - 1)
+			if offset < start+half {
+				node = node.Left
+			} else {
+				node, start = node.Right, start+half
+			}
+		}
+		return node, nil
+	}
+	return nil, errors.New("internal state error: no peak covers the leaf")
 }
 
 // VerifyConsistencyProof checks if old peaks legally transition into newRoot.
-// It verifies that the old peaks match the old root and that following the consistency paths from the old peaks leads to the new peaks, which then combine to form the new root.
+// It verifies that the old peaks match the old root and that following the consistency paths from the old peaks leads to the new peaks, which then combine to form the new root. Both roots are bagged in the proof's bagging order.
 func VerifyConsistencyProof(proof *ConsistencyProof, oldRoot []byte, newRoot []byte, hashFunc hash.Func) bool {
 	if hashFunc == nil {
 		hashFunc = hash.DefaultHashFunc
@@ -163,12 +195,8 @@ func VerifyConsistencyProof(proof *ConsistencyProof, oldRoot []byte, newRoot []b
 			return false
 		}
 
-		calculatedOldRoot := proof.OldPeaksHashes[len(proof.OldPeaksHashes)-1]
-		for i := len(proof.OldPeaksHashes) - 2; i >= 0; i-- {
-			calculatedOldRoot = HashInternalNodes(proof.OldPeaksHashes[i], calculatedOldRoot, hashFunc)
-		}
-
-		if !bytes.Equal(calculatedOldRoot, oldRoot) {
+		calculatedOldRoot := bagPeakHashes(proof.OldPeaksHashes, proof.Bagging, hashFunc)
+		if !rootsEqual(calculatedOldRoot, oldRoot) {
 			return false
 		}
 	}
@@ -205,10 +233,6 @@ func VerifyConsistencyProof(proof *ConsistencyProof, oldRoot []byte, newRoot []b
 	}
 
 	// 4. Combine all the new peaks to calculate the new root and compare it with the provided new root.
-	calculatedRoot := newPeaksHashes[len(newPeaksHashes)-1]
-	for i := len(newPeaksHashes) - 2; i >= 0; i-- {
-		calculatedRoot = HashInternalNodes(newPeaksHashes[i], calculatedRoot, hashFunc)
-	}
-
-	return bytes.Equal(calculatedRoot, newRoot)
+	calculatedRoot := bagPeakHashes(newPeaksHashes, proof.Bagging, hashFunc)
+	return rootsEqual(calculatedRoot, newRoot)
 }
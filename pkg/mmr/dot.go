@@ -0,0 +1,57 @@
+package mmr
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteDOT writes the MMR structure to w as a Graphviz digraph, e.g. for rendering with `dot -Tsvg`. Each mountain is drawn as a separate tree whose nodes are labeled with their truncated hash, with edges to their children; leaves are drawn as boxes and peaks with a double outline.
+func (m *MMR) WriteDOT(w io.Writer) error {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	var b strings.Builder
+	b.WriteString("digraph mmr {\n")
+	id := 0
+	for _, peak := range m.peaks {
+		writeDOTNode(&b, peak, &id, true)
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeDOTNode writes the node and its subtree to b in pre-order, numbering the nodes with the counter id, and returns the identifier of the node.
+func writeDOTNode(b *strings.Builder, n *Node, id *int, isPeak bool) string {
+	name := fmt.Sprintf("n%d", *id)
+	*id++
+
+	shape := "ellipse"
+	if n.Height == 0 {
+		shape = "box"
+	}
+	peripheries := 1
+	if isPeak {
+		peripheries = 2
+	}
+	fmt.Fprintf(b, "\t%s [label=%q, shape=%s, peripheries=%d];\n", name, dotLabel(n.Hash), shape, peripheries)
+
+	for _, child := range []*Node{n.Left, n.Right} {
+		if child != nil {
+			fmt.Fprintf(b, "\t%s -> %s;\n", name, writeDOTNode(b, child, id, false))
+		}
+	}
+	return name
+}
+
+// dotLabel returns the first 8 hex characters of the hash.
+func dotLabel(h []byte) string {
+	s := hex.EncodeToString(h)
+	if len(s) > 8 {
+		s = s[:8]
+	}
+	return s
+}
@@ -0,0 +1,33 @@
+package mmr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMMRWriteDOT(t *testing.T) {
+	// 7 leaves form mountains of 4, 2 and 1 leaves
+	m := buildMMRFromLeaves(t, [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e"), []byte("f"), []byte("g")})
+
+	var b strings.Builder
+	if err := m.WriteDOT(&b); err != nil {
+		t.Fatalf("WriteDOT() unexpected error: %v", err)
+	}
+	out := b.String()
+
+	if !strings.HasPrefix(out, "digraph mmr {\n") || !strings.HasSuffix(out, "}\n") {
+		t.Errorf("WriteDOT() = %q, want a digraph", out)
+	}
+	if got := strings.Count(out, "[label="); got != 11 {
+		t.Errorf("node count = %d, want 11", got)
+	}
+	if got := strings.Count(out, "shape=box"); got != 7 {
+		t.Errorf("leaf count = %d, want 7", got)
+	}
+	if got := strings.Count(out, "peripheries=2"); got != 3 {
+		t.Errorf("peak count = %d, want 3", got)
+	}
+	if got := strings.Count(out, " -> "); got != 8 {
+		t.Errorf("edge count = %d, want 8", got)
+	}
+}
@@ -1,15 +1,29 @@
 package mmr
 
-import "github.com/andrlikjirka/dp-teals/pkg/hash"
+import (
+	"crypto/subtle"
 
-// HashLeafData computes the hash of the leaf data by prefixing it with 0x00 and applying the hash function.
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+// HashLeafData computes the hash of the leaf data by prefixing it with 0x00 and applying the hash function. The input slice is never modified.
 func HashLeafData(data []byte, hashFunc hash.Func) []byte {
-	prefix := []byte{0x00}
-	return hashFunc(append(prefix, data...))
+	buf := make([]byte, 1+len(data))
+	buf[0] = 0x00
+	copy(buf[1:], data)
+	return hashFunc(buf)
 }
 
-// HashInternalNodes computes the hash of the internal nodes by prefixing the concatenated left and right child hashes with 0x01 and applying the hash function.
+// HashInternalNodes computes the hash of the internal nodes by prefixing the concatenated left and right child hashes with 0x01 and applying the hash function. The input slices are never modified, even when they have spare capacity.
 func HashInternalNodes(left, right []byte, hashFunc hash.Func) []byte {
-	prefix := []byte{0x01}
-	return hashFunc(append(prefix, append(left, right...)...))
+	buf := make([]byte, 1+len(left)+len(right))
+	buf[0] = 0x01
+	copy(buf[1:], left)
+	copy(buf[1+len(left):], right)
+	return hashFunc(buf)
+}
+
+// rootsEqual reports whether two root hashes are equal in constant time, so a verifier doesn't leak through its timing how much of a forged root matches.
+func rootsEqual(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
 }
@@ -160,3 +160,28 @@ func TestHashPrefixDifference(t *testing.T) {
 		})
 	}
 }
+
+func TestHashInternalNodes_DoesNotModifyInputs(t *testing.T) {
+	backing := make([]byte, 32, 64) // left has spare capacity that a naive append would write into
+	copy(backing, bytes.Repeat([]byte{0xaa}, 32))
+	left := backing[:32]
+	right := bytes.Repeat([]byte{0xbb}, 32)
+	spare := backing[32:64]
+
+	first := HashInternalNodes(left, right, hash.SHA256HashFunc)
+	if !bytes.Equal(spare, make([]byte, 32)) {
+		t.Fatalf("HashInternalNodes() wrote into the spare capacity of left: %x", spare)
+	}
+
+	// a second call over the same backing array must produce the same hash
+	other := bytes.Repeat([]byte{0xcc}, 32)
+	_ = HashInternalNodes(left, other, hash.SHA256HashFunc)
+	if second := HashInternalNodes(left, right, hash.SHA256HashFunc); !bytes.Equal(first, second) {
+		t.Errorf("HashInternalNodes() = %x, want %x", second, first)
+	}
+
+	expected := sha256Bytes(append([]byte{0x01}, append(bytes.Repeat([]byte{0xaa}, 32), right...)...))
+	if !bytes.Equal(first, expected) {
+		t.Errorf("HashInternalNodes() = %x, want %x", first, expected)
+	}
+}
@@ -4,14 +4,27 @@ import (
 	"bytes"
 	"encoding/hex"
 	"errors"
+	"math/bits"
 
 	"github.com/andrlikjirka/dp-teals/pkg/hash"
 )
 
+// Errors returned by VerifyInclusionProofAtSize describing why a proof was rejected.
+var (
+	ErrProofNil          = errors.New("proof is nil")
+	ErrProofMalformed    = errors.New("proof siblings and directions differ in length")
+	ErrProofMissingInput = errors.New("leaf data and root hash must not be empty")
+	ErrProofWrongSize    = errors.New("leaf index is outside the MMR size")
+	ErrProofBadBagging   = errors.New("proof structure does not match the peaks of the MMR size")
+	ErrProofRootMismatch = errors.New("computed root does not match the expected root")
+)
+
 // InclusionProof represents the proof that a leaf is included in the MMR. It consists of the sibling hashes along the path from the leaf to its peak, and the direction (left/right) of each sibling.
 type InclusionProof struct {
-	Siblings [][]byte
-	Left     []bool
+	LeafIndex int // Index of the proven leaf
+	Siblings  [][]byte
+	Left      []bool
+	Bagging   BaggingOrder // Bagging order of the MMR the proof was generated from
 }
 
 // GenerateInclusionProof generates the proof for a leaf in the MMR by its index.
@@ -38,11 +51,11 @@ func (m *MMR) GenerateInclusionProofByData(data []byte) (*InclusionProof, error)
 
 // generateInclusionProofLocked is the internal method that generates the inclusion proof for a leaf at a given index. It assumes the caller has already acquired the read lock.
 func (m *MMR) generateInclusionProofLocked(index int) (*InclusionProof, error) {
-	if index < 0 || index >= len(m.Leaves) {
-		return nil, errors.New("invalid index")
+	current, err := m.leafLocked(index)
+	if err != nil {
+		return nil, err
 	}
 
-	current := m.Leaves[index]
 	var siblings [][]byte
 	var left []bool
 
@@ -72,37 +85,34 @@ func (m *MMR) generateInclusionProofLocked(index int) (*InclusionProof, error) {
 	}
 
 	// PHASE 2: Peak Bagging
-	// combine all peaks to the RIGHT into a single hash, add as RIGHT sibling
-	if peakIdx < len(m.peaks)-1 {
-		rightBag := m.bagPeaksRightToLeft(m.peaks[peakIdx+1:])
-		siblings = append(siblings, rightBag)
-		left = append(left, false) // sibling is on the right
-	}
-	// Add all peaks to the LEFT sequentially as LEFT siblings
-	for i := peakIdx - 1; i >= 0; i-- {
-		siblings = append(siblings, m.peaks[i].Hash)
-		left = append(left, true) // sibling is on the left
+	if m.bagging == BagLeftToRight {
+		// combine all peaks to the LEFT into a single hash, add as LEFT sibling
+		if peakIdx > 0 {
+			siblings = append(siblings, bagPeakHashes(peakHashes(m.peaks[:peakIdx]), BagLeftToRight, m.hashFunc))
+			left = append(left, true) // sibling is on the left
+		}
+		// Add all peaks to the RIGHT sequentially as RIGHT siblings
+		for i := peakIdx + 1; i < len(m.peaks); i++ {
+			siblings = append(siblings, m.peaks[i].Hash)
+			left = append(left, false) // sibling is on the right
+		}
+	} else {
+		// combine all peaks to the RIGHT into a single hash, add as RIGHT sibling
+		if peakIdx < len(m.peaks)-1 {
+			siblings = append(siblings, bagPeakHashes(peakHashes(m.peaks[peakIdx+1:]), BagRightToLeft, m.hashFunc))
+			left = append(left, false) // sibling is on the right
+		}
+		// Add all peaks to the LEFT sequentially as LEFT siblings
+		for i := peakIdx - 1; i >= 0; i-- {
+			siblings = append(siblings, m.peaks[i].Hash)
+			left = append(left, true) // sibling is on the left
+		}
 	}
 
-	proof := &InclusionProof{Siblings: siblings, Left: left}
+	proof := &InclusionProof{LeafIndex: index, Siblings: siblings, Left: left, Bagging: m.bagging}
 	return proof, nil
 }
 
-// bagPeaksRightToLeft is a helper method that takes a slice of peaks and combines them into a single hash by hashing from right to left.
-func (m *MMR) bagPeaksRightToLeft(peaks []*Node) []byte {
-	if len(peaks) == 0 {
-		return nil
-	}
-	if len(peaks) == 1 {
-		return peaks[0].Hash
-	}
-	root := peaks[len(peaks)-1].Hash
-	for i := len(peaks) - 2; i >= 0; i-- {
-		root = HashInternalNodes(peaks[i].Hash, root, m.hashFunc)
-	}
-	return root
-}
-
 // VerifyInclusionProof verifies the inclusion proof for a given leaf data against the MMR root hash using the provided hash function.
 func VerifyInclusionProof(leafData []byte, proof *InclusionProof, rootHash []byte, hashFunc hash.Func) bool {
 	// 1. Validate the proof structure
@@ -126,11 +136,86 @@ func VerifyInclusionProof(leafData []byte, proof *InclusionProof, rootHash []byt
 	// 3. Traverse the path
 	for i, siblingHash := range proof.Siblings {
 		if proof.Left[i] {
-			h = hashFunc(append([]byte{0x01}, append(siblingHash, h...)...))
+			h = HashInternalNodes(siblingHash, h, hashFunc)
 		} else {
-			h = hashFunc(append([]byte{0x01}, append(h, siblingHash...)...))
+			h = HashInternalNodes(h, siblingHash, hashFunc)
 		}
 	}
 
-	return bytes.Equal(h, rootHash)
+	return rootsEqual(h, rootHash)
+}
+
+// VerifyInclusionProofAtSize verifies the inclusion proof for a given leaf data against the MMR root hash of an MMR with the given number of leaves. Because the MMR root depends on its size, the proof structure is checked against the peaks implied by the size and the proof's bagging order before the root is recomputed. A proof generated at size N keeps verifying against the root captured at size N regardless of later appends.
+// It returns ErrProofNil for a nil proof, ErrProofMalformed if the proof has a different number of siblings and directions, ErrProofMissingInput for empty leaf data or root hash, ErrProofWrongSize if the leaf index doesn't fit the size, ErrProofBadBagging if the sibling path doesn't match the peak structure, and ErrProofRootMismatch if the computed root differs from the expected root.
+func VerifyInclusionProofAtSize(leafData []byte, proof *InclusionProof, rootHash []byte, size int, hashFunc hash.Func) error {
+	if proof == nil {
+		return ErrProofNil
+	}
+	if len(proof.Siblings) != len(proof.Left) {
+		return ErrProofMalformed
+	}
+	if len(leafData) == 0 || len(rootHash) == 0 {
+		return ErrProofMissingInput
+	}
+	if proof.LeafIndex < 0 || proof.LeafIndex >= size {
+		return ErrProofWrongSize
+	}
+
+	// derive the expected proof shape from the peaks at this size
+	peakIdx, peakCount, height, offset := locatePeak(proof.LeafIndex, size)
+	expectedLeft := make([]bool, 0, height+peakCount)
+	for level := 0; level < height; level++ { // intra-mountain path, the sibling is on the left when the node is a right child
+		expectedLeft = append(expectedLeft, (proof.LeafIndex-offset)&(1<<level) != 0)
+	}
+	if proof.Bagging == BagLeftToRight {
+		if peakIdx > 0 { // bag of all peaks to the left
+			expectedLeft = append(expectedLeft, true)
+		}
+		for i := peakIdx + 1; i < peakCount; i++ { // each peak to the right
+			expectedLeft = append(expectedLeft, false)
+		}
+	} else {
+		if peakIdx < peakCount-1 { // bag of all peaks to the right
+			expectedLeft = append(expectedLeft, false)
+		}
+		for i := 0; i < peakIdx; i++ { // each peak to the left
+			expectedLeft = append(expectedLeft, true)
+		}
+	}
+	if len(expectedLeft) != len(proof.Left) {
+		return ErrProofBadBagging
+	}
+	for i, l := range expectedLeft {
+		if proof.Left[i] != l {
+			return ErrProofBadBagging
+		}
+	}
+
+	if !VerifyInclusionProof(leafData, proof, rootHash, hashFunc) {
+		return ErrProofRootMismatch
+	}
+	return nil
+}
+
+// locatePeak finds the peak of an MMR with the given size that contains the leaf at leafIndex. It returns the index of the peak (left to right), the number of peaks, the height of the peak, and the index of the first leaf under the peak.
+func locatePeak(leafIndex int, size int) (peakIdx int, peakCount int, height int, offset int) {
+	peakIdx = -1
+	for bit := bits.Len(uint(size)) - 1; bit >= 0; bit-- { // iterate through the bits of size from MSB to LSB
+		if size&(1<<bit) == 0 {
+			continue
+		}
+		if peakIdx == -1 && leafIndex < offset+(1<<bit) {
+			peakIdx, height = peakCount, bit
+		}
+		if peakIdx == -1 {
+			offset += 1 << bit
+		}
+		peakCount++
+	}
+	return peakIdx, peakCount, height, offset
+}
+
+// VerifyInclusionProofWithSize reports whether the inclusion proof for a given leaf data is valid against the root hash of an MMR with the given number of leaves. It is the boolean form of VerifyInclusionProofAtSize; a proof generated at size N keeps verifying against the root captured at size N regardless of later appends.
+func VerifyInclusionProofWithSize(leafData []byte, proof *InclusionProof, rootHash []byte, size int, hashFunc hash.Func) bool {
+	return VerifyInclusionProofAtSize(leafData, proof, rootHash, size, hashFunc) == nil
 }
@@ -2,6 +2,8 @@ package mmr
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"testing"
 )
 
@@ -502,3 +504,111 @@ func TestInclusionProofByDataWithDuplicates(t *testing.T) {
 		t.Fatalf("proof for duplicate leaf should verify")
 	}
 }
+
+func TestGenerateInclusionProof_EveryIndexUpTo16Leaves(t *testing.T) {
+	for size := 1; size <= 16; size++ {
+		var leaves [][]byte
+		for i := 0; i < size; i++ {
+			leaves = append(leaves, []byte(fmt.Sprintf("leaf-%d", i)))
+		}
+		m := buildMMRFromLeaves(t, leaves)
+		root := m.RootHash()
+
+		for index := 0; index < size; index++ {
+			proof, err := m.GenerateInclusionProof(index)
+			if err != nil {
+				t.Fatalf("size %d: GenerateInclusionProof(%d) unexpected error: %v", size, index, err)
+			}
+			if !VerifyInclusionProof(leaves[index], proof, root, nil) {
+				t.Errorf("size %d: proof for index %d does not verify", size, index)
+			}
+		}
+
+		for _, index := range []int{-1, size} {
+			if _, err := m.GenerateInclusionProof(index); err == nil {
+				t.Errorf("size %d: GenerateInclusionProof(%d) expected error, got nil", size, index)
+			}
+		}
+	}
+}
+
+func TestVerifyInclusionProofAtSize(t *testing.T) {
+	for size := 1; size <= 16; size++ {
+		var leaves [][]byte
+		for i := 0; i < size; i++ {
+			leaves = append(leaves, []byte(fmt.Sprintf("leaf-%d", i)))
+		}
+		m := buildMMRFromLeaves(t, leaves)
+		root := m.RootHash()
+
+		for index := 0; index < size; index++ {
+			proof, _ := m.GenerateInclusionProof(index)
+			if err := VerifyInclusionProofAtSize(leaves[index], proof, root, size, nil); err != nil {
+				t.Errorf("size %d: proof for index %d rejected: %v", size, index, err)
+			}
+		}
+	}
+}
+
+func TestVerifyInclusionProofAtSize_Errors(t *testing.T) {
+	leaves := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e"), []byte("f")}
+	m := buildMMRFromLeaves(t, leaves)
+	root := m.RootHash()
+	proof, _ := m.GenerateInclusionProof(4)
+
+	malformed := &InclusionProof{LeafIndex: proof.LeafIndex, Siblings: proof.Siblings, Left: proof.Left[1:], Bagging: proof.Bagging}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		proof   *InclusionProof
+		root    []byte
+		size    int
+		wantErr error
+	}{
+		{"nil proof", []byte("e"), nil, root, 6, ErrProofNil},
+		{"siblings and directions differ", []byte("e"), malformed, root, 6, ErrProofMalformed},
+		{"empty leaf data", nil, proof, root, 6, ErrProofMissingInput},
+		{"empty root", []byte("e"), proof, nil, 6, ErrProofMissingInput},
+		{"wrong size with different peaks", []byte("e"), proof, root, 7, ErrProofBadBagging},
+		{"size smaller than leaf index", []byte("e"), proof, root, 4, ErrProofWrongSize},
+		{"data mismatch", []byte("x"), proof, root, 6, ErrProofRootMismatch},
+		{"root mismatch", []byte("e"), proof, HashLeafData([]byte("other"), sha256Bytes), 6, ErrProofRootMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyInclusionProofAtSize(tt.data, tt.proof, tt.root, tt.size, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyInclusionProofAtSize() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyInclusionProofWithSize_AfterAppends(t *testing.T) {
+	leaves := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")}
+	m := buildMMRFromLeaves(t, leaves)
+
+	size := 5
+	rootAtSize := m.RootHash()
+	proofs := make([]*InclusionProof, size)
+	for i := range proofs {
+		proofs[i], _ = m.GenerateInclusionProof(i)
+	}
+
+	for _, extra := range [][]byte{[]byte("f"), []byte("g"), []byte("h")} {
+		if err := m.Append(extra); err != nil {
+			t.Fatalf("append failed: %v", err)
+		}
+	}
+
+	for i, proof := range proofs {
+		if !VerifyInclusionProofWithSize(leaves[i], proof, rootAtSize, size, nil) {
+			t.Errorf("proof for index %d generated at size %d no longer verifies against its root", i, size)
+		}
+		if VerifyInclusionProofWithSize(leaves[i], proof, m.RootHash(), m.size, nil) {
+			t.Errorf("proof for index %d generated at size %d verified against the root at size %d", i, size, m.size)
+		}
+	}
+}
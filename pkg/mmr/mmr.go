@@ -1,9 +1,15 @@
 package mmr
 
 import (
+	"bytes"
+	"context"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"math/bits"
+	"os"
+	"strings"
 	"sync"
 
 	"github.com/andrlikjirka/dp-teals/pkg/hash"
@@ -21,48 +27,86 @@ type Node struct {
 // MMR (Merkle Mountain Range) is a data structure that maintains a dynamic collection of leaves and their corresponding peaks. It allows for efficient appending of new leaves and provides methods to compute the root hash, generate inclusion proofs, and consistency proofs. The MMR maintains an index map to track the positions of leaf hashes for quick proof generation. It uses a mutex to ensure thread-safe operations when modifying the structure.
 type MMR struct {
 	peaks    []*Node
-	Leaves   []*Node
+	Leaves   []*Node          // Leaves appended since the last Prune, all leaves if never pruned
 	indexMap map[string][]int // hash → indices
 	hashFunc hash.Func
-	size     int // Number of leaves appended
+	size     int          // Number of leaves appended
+	bagging  BaggingOrder // order in which the peaks are bagged into the root
+	pruned   int          // Number of leading leaves dropped by Prune, Leaves starts after them
+	root     []byte       // cached bagged root, nil until computed after the last append
 	lock     sync.RWMutex
 }
 
 // NewMMR initializes a new MMR instance with an optional custom hash function. If no hash function is provided, it defaults to the standard hash function defined in the hash package. The MMR starts with empty peaks and leaves, and an empty index map for tracking leaf hashes.
-func NewMMR(hashFunc hash.Func) *MMR {
+func NewMMR(hashFunc hash.Func, opts ...Option) *MMR {
 	if hashFunc == nil {
 		hashFunc = hash.DefaultHashFunc
 	}
-	return &MMR{
+	m := &MMR{
 		peaks:    make([]*Node, 0),
 		Leaves:   make([]*Node, 0),
 		indexMap: make(map[string][]int),
 		hashFunc: hashFunc,
 		size:     0,
 	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
 }
 
 // Append adds a new leaf to the MMR with the given data.
 // It computes the hash of the new leaf, creates a new node, and appends it to the list of leaves. The method then checks if the new node can be merged with existing peaks (if they have the same height) and merges them accordingly, updating the peaks list. The index map is updated to track the new leaf's hash and its index for future proof generation. The method returns an error if an attempt is made to append an empty leaf.
 func (m *MMR) Append(data []byte) error {
+	return m.AppendContext(context.Background(), data)
+}
+
+// AppendContext adds a new leaf to the MMR like Append, but returns the context error without modifying the MMR if the context is cancelled before the leaf is added, e.g. while waiting for the lock during a large batch ingestion.
+func (m *MMR) AppendContext(ctx context.Context, data []byte) error {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if len(data) == 0 {
 		return errors.New("empty leaf not allowed")
 	}
 
-	leafHash := HashLeafData(data, m.hashFunc)
+	m.appendLeafHashLocked(HashLeafData(data, m.hashFunc))
+	return nil
+}
+
+// AppendBatch adds the given items to the MMR as consecutive leaves under a single lock, merging peaks as each leaf is added. The whole batch is rejected without modifying the MMR if any item is empty.
+func (m *MMR) AppendBatch(items [][]byte) error {
+	for i, data := range items {
+		if len(data) == 0 {
+			return fmt.Errorf("item %d: empty leaf not allowed", i)
+		}
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, data := range items {
+		m.appendLeafHashLocked(HashLeafData(data, m.hashFunc))
+	}
+	return nil
+}
+
+// appendLeafHashLocked adds a leaf with the given hash to the MMR and merges it with existing peaks of the same height. It assumes the caller holds the write lock.
+func (m *MMR) appendLeafHashLocked(leafHash []byte) {
 	newNode := &Node{
 		Hash:   leafHash,
 		Height: 0,
 	}
 	m.Leaves = append(m.Leaves, newNode)
-	m.size++ // update the MMR size to reflect the new leaf
+	m.size++     // update the MMR size to reflect the new leaf
+	m.root = nil // the peaks change, invalidate the cached root
 
 	// Update indexMap to track this leaf's hash to its index
 	hashHex := hex.EncodeToString(leafHash)
-	m.indexMap[hashHex] = append(m.indexMap[hashHex], len(m.Leaves)-1)
+	m.indexMap[hashHex] = append(m.indexMap[hashHex], m.size-1)
 
 	// check if we can merge with existing peaks
 	for len(m.peaks) > 0 {
@@ -84,82 +128,185 @@ func (m *MMR) Append(data []byte) error {
 		rightChild.Parent = newNode
 	}
 	m.peaks = append(m.peaks, newNode) // push the resulting mountain peak back onto the list
-
-	return nil
 }
 
 // RootHash computes the root hash of the MMR by combining all peaks (peak bagging). The order of peaks is important for consistency.
-// The MMR root is the hash of all current peaks combined from right to left.
+// By default the MMR root is the hash of all current peaks combined from right to left, see WithBaggingOrder.
+// The root is cached until the next append, so repeated calls between appends don't rehash the peaks.
 func (m *MMR) RootHash() []byte {
+	m.lock.RLock()
+	root := m.root
+	m.lock.RUnlock()
+	if root != nil {
+		return bytes.Clone(root)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.root == nil { // another goroutine may have filled the cache while the lock was released
+		m.root = m.rootHashLocked()
+	}
+	return bytes.Clone(m.root)
+}
+
+// rootHashLocked returns the cached root, or bags the peaks into the root hash if it isn't cached. It doesn't fill the cache, so the caller may hold either lock.
+func (m *MMR) rootHashLocked() []byte {
+	if m.root != nil {
+		return m.root
+	}
+	return bagPeakHashes(peakHashes(m.peaks), m.bagging, m.hashFunc)
+}
+
+// Size returns the number of leaves appended to the MMR.
+func (m *MMR) Size() int {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.size
+}
+
+// Peaks returns copies of the peak hashes ordered from right (the smallest, most recent mountain) to left, the order in which they are bagged into the root by default, see BagRightToLeft. Modifying the returned hashes doesn't affect the MMR.
+func (m *MMR) Peaks() [][]byte {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	peaks := make([][]byte, 0, len(m.peaks))
+	for i := len(m.peaks) - 1; i >= 0; i-- {
+		peaks = append(peaks, bytes.Clone(m.peaks[i].Hash))
+	}
+	return peaks
+}
+
+// LeafIndexToPosition returns the flat position of the leaf with the given index. Positions number every node of the MMR, leaves and internal nodes alike, from 0 in the order the nodes are created by appends (post-order), the convention used by Grin and other MMR implementations. For a 7-leaf MMR:
+//
+//	         6
+//	     /       \
+//	   2           5           9
+//	 /   \       /   \       /   \
+//	0     1     3     4     7     8    10
+//
+// Every leaf index i is preceded by i leaves and by one internal node for each completed merge, so its position is 2*i minus the number of set bits of i.
+func LeafIndexToPosition(leafIndex int) int {
+	return 2*leafIndex - bits.OnesCount(uint(leafIndex))
+}
+
+// NodeAt returns a copy of the hash of the node at the given flat position, see LeafIndexToPosition for the numbering. It returns an error if the position is outside the MMR, or ErrPruned if the node was dropped by Prune.
+func (m *MMR) NodeAt(position int) ([]byte, error) {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
 
-	if len(m.peaks) == 0 {
-		return nil
+	if position < 0 {
+		return nil, errors.New("invalid position")
 	}
 
-	root := m.peaks[len(m.peaks)-1].Hash // start with the rightmost peak
-	for i := len(m.peaks) - 2; i >= 0; i-- {
-		root = HashInternalNodes(m.peaks[i].Hash, root, m.hashFunc) // combine peaks from right to left
+	start := 0 // position of the first node under the current peak
+	for _, peak := range m.peaks {
+		count := 1<<(peak.Height+1) - 1 // number of nodes in a perfect mountain
+		if position >= start+count {
+			start += count
+			continue
+		}
+
+		// descend from the peak, the root of a mountain is its last position and its left subtree precedes the right one
+		node, offset := peak, position-start
+		for offset != count-1 {
+			if node.Left == nil {
+				return nil, ErrPruned
+			}
+			count >>= 1 // both subtrees have (count-1)/2 nodes
+			if offset < count {
+				node = node.Left
+			} else {
+				node, offset = node.Right, offset-count
+			}
+		}
+		return bytes.Clone(node.Hash), nil
 	}
-	return root
+	return nil, errors.New("invalid position")
 }
 
 // ============ Debugging and Visualization Methods ============
 
-// PrintSummary provides a concise overview of the MMR's current state, including the number of leaves, the number of peaks, and the current root hash.
-// This is useful for quickly assessing the MMR's status without delving into the full tree structure.
+// PrintSummary writes the summary of the MMR to standard output, see FprintSummary.
 func (m *MMR) PrintSummary() {
+	m.FprintSummary(os.Stdout)
+}
+
+// FprintSummary writes a concise overview of the MMR's current state to w, including the number of leaves, the number of peaks, and the current root hash.
+// This is useful for quickly assessing the MMR's status without delving into the full tree structure.
+func (m *MMR) FprintSummary(w io.Writer) error {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
 
-	fmt.Println("=========== MMR Summary ===========")
-	fmt.Printf("Size (leaves): %d\n", m.size)
-	fmt.Printf("Number of peaks: %d\n", len(m.peaks))
+	var b strings.Builder
+	b.WriteString("=========== MMR Summary ===========\n")
+	fmt.Fprintf(&b, "Size (leaves): %d\n", m.size)
+	fmt.Fprintf(&b, "Number of peaks: %d\n", len(m.peaks))
 
-	root := m.RootHash()
+	root := m.rootHashLocked()
 	if root == nil {
-		fmt.Println("Root: <nil>")
+		b.WriteString("Root: <nil>\n")
 	} else {
-		fmt.Printf("Root: %s\n", hex.EncodeToString(root))
+		fmt.Fprintf(&b, "Root: %s\n", hex.EncodeToString(root))
 	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
 }
 
-// PrintPeaks displays the current peaks in the MMR, showing their height and a truncated hash for easy visualization.
+// PrintPeaks writes the peaks of the MMR to standard output, see FprintPeaks.
 func (m *MMR) PrintPeaks() {
+	m.FprintPeaks(os.Stdout)
+}
+
+// FprintPeaks writes the current peaks in the MMR to w, showing their height and a truncated hash for easy visualization.
+func (m *MMR) FprintPeaks(w io.Writer) error {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
 
-	fmt.Println("----------- Peaks -----------")
+	var b strings.Builder
+	b.WriteString("----------- Peaks -----------\n")
 	for i, peak := range m.peaks {
 		hashStr := hex.EncodeToString(peak.Hash)
-		fmt.Printf("Peak %d | Height: %d | Hash: %s\n",
+		fmt.Fprintf(&b, "Peak %d | Height: %d | Hash: %s\n",
 			i,
 			peak.Height,
 			hashStr[:8],
 		)
 	}
-	fmt.Println("-----------------------------")
+	b.WriteString("-----------------------------\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
 }
 
-// PrintTree visualizes the MMR structure in a tree-like format, showing the relationships between peaks and their hashes. It uses indentation to represent the tree structure, with the rightmost peak at the top and leftmost at the bottom.
+// PrintTree writes the MMR structure to standard output, see FprintTree.
 func (m *MMR) PrintTree() {
+	m.FprintTree(os.Stdout)
+}
+
+// FprintTree writes the MMR structure to w in a tree-like format, showing the relationships between peaks and their hashes. It uses indentation to represent the tree structure, with the right subtree of each node printed above its left subtree.
+func (m *MMR) FprintTree(w io.Writer) error {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
 
-	fmt.Println("============= MMR Tree =============")
+	var b strings.Builder
+	b.WriteString("============= MMR Tree =============\n")
 
 	for i, peak := range m.peaks {
-		fmt.Printf("Peak %d (height %d):\n", i, peak.Height)
-		printNodeRecursive(peak, "", true)
-		fmt.Println()
+		fmt.Fprintf(&b, "Peak %d (height %d):\n", i, peak.Height)
+		printNodeRecursive(&b, peak, "", true)
+		b.WriteString("\n")
 	}
 
-	fmt.Println("=====================================")
+	b.WriteString("=====================================\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
 }
 
-// printNodeRecursive is a helper function to recursively print the tree structure of the MMR. It uses indentation and special characters to visually represent the tree hierarchy. The right subtree is printed first to make the tree grow upwards visually.
-func printNodeRecursive(n *Node, prefix string, isTail bool) {
+// printNodeRecursive is a helper function to recursively print the tree structure of the MMR to b. It uses indentation and special characters to visually represent the tree hierarchy. The right subtree is printed first to make the tree grow upwards visually.
+func printNodeRecursive(b *strings.Builder, n *Node, prefix string, isTail bool) {
 	if n == nil {
 		return
 	}
@@ -173,16 +320,16 @@ func printNodeRecursive(n *Node, prefix string, isTail bool) {
 		} else {
 			newPrefix += "    "
 		}
-		printNodeRecursive(n.Right, newPrefix, false)
+		printNodeRecursive(b, n.Right, newPrefix, false)
 	}
 
-	fmt.Printf("%s", prefix)
+	b.WriteString(prefix)
 	if isTail {
-		fmt.Printf("└── ")
+		b.WriteString("└── ")
 	} else {
-		fmt.Printf("┌── ")
+		b.WriteString("┌── ")
 	}
-	fmt.Printf("%s\n", hashStr[:8])
+	b.WriteString(hashStr[:8] + "\n")
 
 	if n.Left != nil {
 		newPrefix := prefix
@@ -191,6 +338,6 @@ func printNodeRecursive(n *Node, prefix string, isTail bool) {
 		} else {
 			newPrefix += "│   "
 		}
-		printNodeRecursive(n.Left, newPrefix, true)
+		printNodeRecursive(b, n.Left, newPrefix, true)
 	}
 }
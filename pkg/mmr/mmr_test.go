@@ -2,7 +2,15 @@ package mmr
 
 import (
 	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strings"
 	"testing"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
 )
 
 func buildMMRFromLeaves(t *testing.T, leaves [][]byte) *MMR {
@@ -167,6 +175,30 @@ func TestMMRRootHashEmptyIsNil(t *testing.T) {
 	}
 }
 
+func TestMMRRootHashCache(t *testing.T) {
+	m := NewMMR(nil)
+	for i := 0; i < 7; i++ {
+		if err := m.Append([]byte(fmt.Sprintf("leaf%d", i))); err != nil {
+			t.Fatalf("Append() unexpected error: %v", err)
+		}
+		want := bagPeakHashes(peakHashes(m.peaks), m.bagging, m.hashFunc)
+		for range 2 { // the second call is served from the cache
+			got := m.RootHash()
+			if !bytes.Equal(got, want) {
+				t.Fatalf("size %d: RootHash() = %x, want %x", i+1, got, want)
+			}
+			got[0] ^= 0xff // the caller owns the returned slice
+		}
+	}
+
+	if err := m.AppendBatch([][]byte{[]byte("a"), []byte("b")}); err != nil {
+		t.Fatalf("AppendBatch() unexpected error: %v", err)
+	}
+	if want := bagPeakHashes(peakHashes(m.peaks), m.bagging, m.hashFunc); !bytes.Equal(m.RootHash(), want) {
+		t.Errorf("RootHash() after AppendBatch = %x, want %x", m.RootHash(), want)
+	}
+}
+
 func TestMMRParentLinksAfterMerge(t *testing.T) {
 	m := buildMMRFromLeaves(t, [][]byte{[]byte("a"), []byte("b")})
 	if len(m.peaks) != 1 {
@@ -184,3 +216,266 @@ func TestMMRParentLinksAfterMerge(t *testing.T) {
 		t.Fatalf("right child parent pointer not set to root")
 	}
 }
+
+func TestMMRProofs_Blake2b256(t *testing.T) {
+	m := NewMMR(hash.Blake2b256HashFunc)
+	old := NewMMR(hash.Blake2b256HashFunc)
+	for i := 0; i < 11; i++ {
+		leaf := []byte(fmt.Sprintf("leaf%d", i))
+		if err := m.Append(leaf); err != nil {
+			t.Fatalf("append failed: %v", err)
+		}
+		if i < 6 {
+			if err := old.Append(leaf); err != nil {
+				t.Fatalf("append failed: %v", err)
+			}
+		}
+	}
+
+	for i := 0; i < 11; i++ {
+		proof, err := m.GenerateInclusionProof(i)
+		if err != nil {
+			t.Fatalf("GenerateInclusionProof(%d) unexpected error: %v", i, err)
+		}
+		if !VerifyInclusionProof([]byte(fmt.Sprintf("leaf%d", i)), proof, m.RootHash(), hash.Blake2b256HashFunc) {
+			t.Errorf("VerifyInclusionProof() failed for leaf %d", i)
+		}
+	}
+
+	proof, err := m.GenerateConsistencyProof(6, 11)
+	if err != nil {
+		t.Fatalf("GenerateConsistencyProof() unexpected error: %v", err)
+	}
+	if !VerifyConsistencyProof(proof, old.RootHash(), m.RootHash(), hash.Blake2b256HashFunc) {
+		t.Errorf("VerifyConsistencyProof() failed")
+	}
+}
+
+func TestMMRAppendContext(t *testing.T) {
+	m := buildMMRFromLeaves(t, [][]byte{[]byte("a"), []byte("b"), []byte("c")})
+	root := m.RootHash()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := m.AppendContext(ctx, []byte("d")); !errors.Is(err, context.Canceled) {
+		t.Errorf("AppendContext() error = %v, want %v", err, context.Canceled)
+	}
+	if len(m.Leaves) != 3 || !bytes.Equal(m.RootHash(), root) {
+		t.Errorf("MMR changed after cancelled AppendContext")
+	}
+
+	if err := m.AppendContext(context.Background(), []byte("d")); err != nil {
+		t.Fatalf("AppendContext() unexpected error: %v", err)
+	}
+	want := buildMMRFromLeaves(t, [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d")})
+	if !bytes.Equal(m.RootHash(), want.RootHash()) {
+		t.Errorf("RootHash() = %x, want %x", m.RootHash(), want.RootHash())
+	}
+}
+
+func TestMMRSizeAndPeaks(t *testing.T) {
+	m := NewMMR(nil)
+	if m.Size() != 0 || len(m.Peaks()) != 0 {
+		t.Fatalf("empty MMR: Size() = %d, Peaks() = %d, want 0, 0", m.Size(), len(m.Peaks()))
+	}
+
+	for i := 1; i <= 11; i++ {
+		if err := m.Append([]byte(fmt.Sprintf("leaf%d", i))); err != nil {
+			t.Fatalf("append failed: %v", err)
+		}
+		if m.Size() != i {
+			t.Errorf("Size() = %d, want %d", m.Size(), i)
+		}
+
+		peaks := m.Peaks()
+		if len(peaks) != bits.OnesCount(uint(i)) {
+			t.Errorf("size %d: len(Peaks()) = %d, want %d", i, len(peaks), bits.OnesCount(uint(i)))
+		}
+
+		root := peaks[0] // bagging the peaks from right to left reproduces the root
+		for _, peak := range peaks[1:] {
+			root = HashInternalNodes(peak, root, hash.DefaultHashFunc)
+		}
+		if !bytes.Equal(root, m.RootHash()) {
+			t.Errorf("size %d: bagged Peaks() = %x, want %x", i, root, m.RootHash())
+		}
+	}
+}
+
+func TestMMRPeaks_DefensiveCopy(t *testing.T) {
+	m := buildMMRFromLeaves(t, [][]byte{[]byte("a"), []byte("b"), []byte("c")})
+	root := m.RootHash()
+
+	peaks := m.Peaks()
+	for _, peak := range peaks {
+		peak[0] ^= 0xff
+	}
+	if !bytes.Equal(m.RootHash(), root) {
+		t.Errorf("modifying Peaks() changed the root: %x, want %x", m.RootHash(), root)
+	}
+}
+
+func TestMMRAppendBatch_MatchesSingleAppends(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 5, 8, 13} {
+		t.Run(fmt.Sprintf("%d items", n), func(t *testing.T) {
+			items := make([][]byte, n)
+			for i := range items {
+				items[i] = []byte(fmt.Sprintf("leaf%d", i))
+			}
+
+			batched := buildMMRFromLeaves(t, [][]byte{[]byte("existing")})
+			if err := batched.AppendBatch(items); err != nil {
+				t.Fatalf("AppendBatch() unexpected error: %v", err)
+			}
+			looped := buildMMRFromLeaves(t, append([][]byte{[]byte("existing")}, items...))
+
+			if batched.Size() != looped.Size() {
+				t.Errorf("Size() = %d, want %d", batched.Size(), looped.Size())
+			}
+			if !bytes.Equal(batched.RootHash(), looped.RootHash()) {
+				t.Errorf("RootHash() = %x, want %x", batched.RootHash(), looped.RootHash())
+			}
+			for i, item := range items {
+				proof, err := batched.GenerateInclusionProofByData(item)
+				if err != nil {
+					t.Fatalf("GenerateInclusionProofByData(%q) unexpected error: %v", item, err)
+				}
+				if proof.LeafIndex != i+1 {
+					t.Errorf("LeafIndex = %d, want %d", proof.LeafIndex, i+1)
+				}
+				if !VerifyInclusionProof(item, proof, batched.RootHash(), nil) {
+					t.Errorf("proof for item %d failed to verify", i)
+				}
+			}
+		})
+	}
+}
+
+func TestMMRAppendBatch_RejectsEmptyItem(t *testing.T) {
+	m := buildMMRFromLeaves(t, [][]byte{[]byte("a"), []byte("b")})
+	root := m.RootHash()
+
+	err := m.AppendBatch([][]byte{[]byte("c"), nil, []byte("d")})
+	if err == nil {
+		t.Fatal("AppendBatch() expected error for empty item, got nil")
+	}
+	if m.Size() != 2 {
+		t.Errorf("Size() after rejected batch = %d, want 2", m.Size())
+	}
+	if !bytes.Equal(m.RootHash(), root) {
+		t.Errorf("RootHash() changed after rejected batch")
+	}
+}
+
+func TestLeafIndexToPosition(t *testing.T) {
+	want := []int{0, 1, 3, 4, 7, 8, 10, 11, 15}
+	for i, pos := range want {
+		if got := LeafIndexToPosition(i); got != pos {
+			t.Errorf("LeafIndexToPosition(%d) = %d, want %d", i, got, pos)
+		}
+	}
+}
+
+func TestMMRNodeAt(t *testing.T) {
+	var leaves [][]byte
+	for i := 0; i < 7; i++ {
+		leaves = append(leaves, []byte(fmt.Sprintf("leaf%d", i)))
+	}
+	m := buildMMRFromLeaves(t, leaves)
+
+	l := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		l[i] = HashLeafData(leaf, hash.DefaultHashFunc)
+	}
+	h01 := HashInternalNodes(l[0], l[1], hash.DefaultHashFunc)
+	h23 := HashInternalNodes(l[2], l[3], hash.DefaultHashFunc)
+	h45 := HashInternalNodes(l[4], l[5], hash.DefaultHashFunc)
+	want := [][]byte{
+		l[0], l[1], h01,
+		l[2], l[3], h23,
+		HashInternalNodes(h01, h23, hash.DefaultHashFunc),
+		l[4], l[5], h45,
+		l[6],
+	}
+
+	for pos, w := range want {
+		got, err := m.NodeAt(pos)
+		if err != nil {
+			t.Fatalf("NodeAt(%d) unexpected error: %v", pos, err)
+		}
+		if !bytes.Equal(got, w) {
+			t.Errorf("NodeAt(%d) = %x, want %x", pos, got, w)
+		}
+	}
+
+	for i := range leaves {
+		got, err := m.NodeAt(LeafIndexToPosition(i))
+		if err != nil || !bytes.Equal(got, l[i]) {
+			t.Errorf("NodeAt(LeafIndexToPosition(%d)) = %x, %v, want leaf hash %x", i, got, err, l[i])
+		}
+	}
+
+	for _, pos := range []int{-1, len(want), 100} {
+		if _, err := m.NodeAt(pos); err == nil {
+			t.Errorf("NodeAt(%d) expected error, got nil", pos)
+		}
+	}
+}
+
+func TestMMRFprintTree(t *testing.T) {
+	m := buildMMRFromLeaves(t, [][]byte{[]byte("a"), []byte("b"), []byte("c")})
+
+	short := func(h []byte) string { return hex.EncodeToString(h)[:8] }
+	la := HashLeafData([]byte("a"), hash.DefaultHashFunc)
+	lb := HashLeafData([]byte("b"), hash.DefaultHashFunc)
+	lc := HashLeafData([]byte("c"), hash.DefaultHashFunc)
+	hab := HashInternalNodes(la, lb, hash.DefaultHashFunc)
+
+	want := "============= MMR Tree =============\n" +
+		"Peak 0 (height 1):\n" +
+		"│   ┌── " + short(lb) + "\n" +
+		"└── " + short(hab) + "\n" +
+		"    └── " + short(la) + "\n" +
+		"\n" +
+		"Peak 1 (height 0):\n" +
+		"└── " + short(lc) + "\n" +
+		"\n" +
+		"=====================================\n"
+
+	var b strings.Builder
+	if err := m.FprintTree(&b); err != nil {
+		t.Fatalf("FprintTree() unexpected error: %v", err)
+	}
+	if b.String() != want {
+		t.Errorf("FprintTree() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestMMRFprintSummary(t *testing.T) {
+	m := buildMMRFromLeaves(t, [][]byte{[]byte("a"), []byte("b"), []byte("c")})
+
+	var b strings.Builder
+	if err := m.FprintSummary(&b); err != nil {
+		t.Fatalf("FprintSummary() unexpected error: %v", err)
+	}
+	for _, line := range []string{"Size (leaves): 3", "Number of peaks: 2", "Root: " + hex.EncodeToString(m.RootHash())} {
+		if !strings.Contains(b.String(), line) {
+			t.Errorf("FprintSummary() = %q, missing %q", b.String(), line)
+		}
+	}
+}
+
+func BenchmarkMMRRootHash(b *testing.B) {
+	for _, size := range []int{1 << 10, 1<<16 - 1} { // a single peak and 16 peaks
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			m := NewMMR(nil)
+			for i := 0; i < size; i++ {
+				m.appendLeafHashLocked(HashLeafData([]byte(fmt.Sprintf("leaf-%d", i)), m.hashFunc))
+			}
+
+			for b.Loop() {
+				m.RootHash()
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package mmr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+// marshalMagic identifies a serialized MMR, followed by the format version.
+var marshalMagic = []byte("MMRB")
+
+const (
+	marshalVersion = 3
+
+	// maxMarshalledHashSize bounds the length of a single hash accepted by UnmarshalMMR, so a corrupt length can't trigger a huge allocation.
+	maxMarshalledHashSize = 1 << 10
+)
+
+// MarshalBinary encodes the MMR so it can be restored by UnmarshalMMR. The format is the magic "MMRB" and a version byte, followed by the bagging order byte, the uvarint size, the uvarint number of pruned leaves and the hashes of the peaks at that size (left to right) prefixed by their lengths, each leaf hash appended since the prune prefixed by its uvarint length, the uvarint peak count, and the peak hashes (left to right) prefixed by their lengths.
+// The leaf hashes are stored alongside the peaks because inclusion and consistency proofs for leaves appended before the MMR was serialized need the inner nodes, which are rebuilt from them on restore. The peaks serve as a checksum of the rebuilt structure. A pruned MMR is restored from the peaks at the time of pruning, so it stays pruned.
+func (m *MMR) MarshalBinary() ([]byte, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	prunedPeaks, err := m.getPeaksAtSize(m.pruned)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := append([]byte(nil), marshalMagic...)
+	buf = append(buf, marshalVersion, byte(m.bagging))
+	buf = binary.AppendUvarint(buf, uint64(m.size))
+	buf = binary.AppendUvarint(buf, uint64(m.pruned))
+	for _, peak := range prunedPeaks {
+		buf = appendLengthPrefixed(buf, peak.Hash)
+	}
+	for _, leaf := range m.Leaves {
+		buf = appendLengthPrefixed(buf, leaf.Hash)
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(m.peaks)))
+	for _, peak := range m.peaks {
+		buf = appendLengthPrefixed(buf, peak.Hash)
+	}
+	return buf, nil
+}
+
+// appendLengthPrefixed appends b prefixed by its uvarint length to buf.
+func appendLengthPrefixed(buf []byte, b []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
+
+// UnmarshalMMR restores an MMR encoded by MarshalBinary, rebuilding its nodes with the given hash function and options. An MMR created with WithBaggingOrder must be restored with the same option, as it fails if the bagging order of the options differs from the encoded one. Version 1 encodings don't record the bagging order and are restored with the order of the options, and versions before 3 can't encode a pruned MMR. It fails if the rebuilt peaks don't match the encoded peaks, e.g. because the data is corrupt or was produced with a different hash function. The restored MMR can be appended to and produces the same roots and proofs as the original.
+func UnmarshalMMR(data []byte, hashFunc hash.Func, opts ...Option) (*MMR, error) {
+	if !bytes.HasPrefix(data, marshalMagic) || len(data) < len(marshalMagic)+1 {
+		return nil, errors.New("invalid serialized MMR: bad magic")
+	}
+	version := data[len(marshalMagic)]
+	if version < 1 || version > marshalVersion {
+		return nil, fmt.Errorf("unsupported serialized MMR version %d", version)
+	}
+	r := bytes.NewReader(data[len(marshalMagic)+1:])
+
+	m := NewMMR(hashFunc, opts...)
+	if version >= 2 {
+		bagging, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("read bagging order: %w", err)
+		}
+		if BaggingOrder(bagging) != m.bagging {
+			return nil, fmt.Errorf("serialized MMR uses bagging order %d, options use %d", bagging, m.bagging)
+		}
+	}
+
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("read size: %w", err)
+	}
+	if size > math.MaxInt {
+		return nil, errors.New("invalid serialized MMR: size exceeds limit")
+	}
+
+	var pruned uint64
+	if version >= 3 {
+		if pruned, err = binary.ReadUvarint(r); err != nil {
+			return nil, fmt.Errorf("read pruned size: %w", err)
+		}
+		if pruned > size {
+			return nil, errors.New("invalid serialized MMR: pruned size exceeds size")
+		}
+		// restore the pruned peaks without their children, as Prune leaves them
+		for height := bits.Len64(pruned) - 1; height >= 0; height-- {
+			if pruned&(1<<height) == 0 {
+				continue
+			}
+			peakHash, err := readLengthPrefixed(r)
+			if err != nil {
+				return nil, fmt.Errorf("read pruned peak of height %d: %w", height, err)
+			}
+			m.peaks = append(m.peaks, &Node{Hash: peakHash, Height: height})
+		}
+		m.size, m.pruned = int(pruned), int(pruned)
+	}
+	if size-pruned > uint64(r.Len()) { // every leaf takes at least one byte, don't trust the size for the allocation
+		return nil, errors.New("invalid serialized MMR: size exceeds data length")
+	}
+
+	m.Leaves = make([]*Node, 0, size-pruned)
+	for i := pruned; i < size; i++ {
+		leafHash, err := readLengthPrefixed(r)
+		if err != nil {
+			return nil, fmt.Errorf("read leaf %d: %w", i, err)
+		}
+		m.appendLeafHashLocked(leafHash) // m is not shared yet, no lock needed
+	}
+
+	peakCount, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("read peak count: %w", err)
+	}
+	if peakCount != uint64(len(m.peaks)) {
+		return nil, fmt.Errorf("invalid serialized MMR: %d peaks, want %d for size %d", peakCount, len(m.peaks), size)
+	}
+	for i, peak := range m.peaks {
+		peakHash, err := readLengthPrefixed(r)
+		if err != nil {
+			return nil, fmt.Errorf("read peak %d: %w", i, err)
+		}
+		if !bytes.Equal(peakHash, peak.Hash) {
+			return nil, fmt.Errorf("invalid serialized MMR: peak %d does not match the leaves", i)
+		}
+	}
+	if r.Len() != 0 {
+		return nil, errors.New("invalid serialized MMR: trailing data")
+	}
+
+	return m, nil
+}
+
+// readLengthPrefixed reads a byte slice prefixed by its uvarint length.
+func readLengthPrefixed(r *bytes.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxMarshalledHashSize || size > uint64(r.Len()) {
+		return nil, fmt.Errorf("hash length %d exceeds limit", size)
+	}
+
+	b := make([]byte, size)
+	if _, err := r.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,180 @@
+package mmr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+func TestMarshalUnmarshal_RoundTripThenAppend(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 7, 8, 13} {
+		t.Run(fmt.Sprintf("size %d", n), func(t *testing.T) {
+			original := NewMMR(nil)
+			for i := 0; i < n; i++ {
+				if err := original.Append([]byte(fmt.Sprintf("leaf%d", i))); err != nil {
+					t.Fatalf("Append() unexpected error: %v", err)
+				}
+			}
+
+			data, err := original.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary() unexpected error: %v", err)
+			}
+			restored, err := UnmarshalMMR(data, nil)
+			if err != nil {
+				t.Fatalf("UnmarshalMMR() unexpected error: %v", err)
+			}
+			if restored.Size() != n {
+				t.Errorf("Size() = %d, want %d", restored.Size(), n)
+			}
+			if !bytes.Equal(restored.RootHash(), original.RootHash()) {
+				t.Errorf("RootHash() = %x, want %x", restored.RootHash(), original.RootHash())
+			}
+
+			next := []byte("next")
+			if err := original.Append(next); err != nil {
+				t.Fatalf("Append() unexpected error: %v", err)
+			}
+			if err := restored.Append(next); err != nil {
+				t.Fatalf("Append() on restored MMR unexpected error: %v", err)
+			}
+			if !bytes.Equal(restored.RootHash(), original.RootHash()) {
+				t.Errorf("RootHash() after append = %x, want %x", restored.RootHash(), original.RootHash())
+			}
+
+			// proofs for leaves from before the round trip still work
+			for i := 0; i < n; i++ {
+				proof, err := restored.GenerateInclusionProof(i)
+				if err != nil {
+					t.Fatalf("GenerateInclusionProof(%d) unexpected error: %v", i, err)
+				}
+				if !VerifyInclusionProof([]byte(fmt.Sprintf("leaf%d", i)), proof, original.RootHash(), nil) {
+					t.Errorf("proof for leaf %d from restored MMR failed to verify", i)
+				}
+			}
+		})
+	}
+}
+
+func TestMarshalUnmarshal_Pruned(t *testing.T) {
+	for _, tt := range []struct{ pruneAt, after int }{{1, 0}, {7, 3}, {8, 0}, {13, 6}} {
+		t.Run(fmt.Sprintf("prune at %d, %d after", tt.pruneAt, tt.after), func(t *testing.T) {
+			original := buildMMRWithOrder(t, tt.pruneAt, BagRightToLeft)
+			original.Prune()
+			for i := tt.pruneAt; i < tt.pruneAt+tt.after; i++ {
+				if err := original.Append([]byte(fmt.Sprintf("leaf%d", i))); err != nil {
+					t.Fatalf("Append() unexpected error: %v", err)
+				}
+			}
+
+			data, err := original.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary() unexpected error: %v", err)
+			}
+			restored, err := UnmarshalMMR(data, nil)
+			if err != nil {
+				t.Fatalf("UnmarshalMMR() unexpected error: %v", err)
+			}
+			if restored.Size() != original.Size() {
+				t.Errorf("Size() = %d, want %d", restored.Size(), original.Size())
+			}
+			if !bytes.Equal(restored.RootHash(), original.RootHash()) {
+				t.Errorf("RootHash() = %x, want %x", restored.RootHash(), original.RootHash())
+			}
+
+			next := []byte("next")
+			if err := original.Append(next); err != nil {
+				t.Fatalf("Append() unexpected error: %v", err)
+			}
+			if err := restored.Append(next); err != nil {
+				t.Fatalf("Append() on restored MMR unexpected error: %v", err)
+			}
+			if !bytes.Equal(restored.RootHash(), original.RootHash()) {
+				t.Errorf("RootHash() after append = %x, want %x", restored.RootHash(), original.RootHash())
+			}
+			unpruned := buildMMRWithOrder(t, tt.pruneAt+tt.after, BagRightToLeft)
+			if err := unpruned.Append(next); err != nil {
+				t.Fatalf("Append() unexpected error: %v", err)
+			}
+			if !bytes.Equal(restored.RootHash(), unpruned.RootHash()) {
+				t.Errorf("RootHash() after append = %x, want the root of the unpruned MMR %x", restored.RootHash(), unpruned.RootHash())
+			}
+
+			if _, err := restored.GenerateInclusionProof(tt.pruneAt - 1); !errors.Is(err, ErrPruned) {
+				t.Errorf("GenerateInclusionProof(%d) error = %v, want %v", tt.pruneAt-1, err, ErrPruned)
+			}
+			last := restored.Size() - 1
+			proof, err := restored.GenerateInclusionProof(last)
+			if err != nil {
+				t.Fatalf("GenerateInclusionProof(%d) unexpected error: %v", last, err)
+			}
+			if !VerifyInclusionProof(next, proof, original.RootHash(), nil) {
+				t.Errorf("proof for leaf %d from restored MMR failed to verify", last)
+			}
+		})
+	}
+}
+
+func TestUnmarshalMMR_Errors(t *testing.T) {
+	m := buildMMRFromLeaves(t, [][]byte{[]byte("a"), []byte("b"), []byte("c")})
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() unexpected error: %v", err)
+	}
+
+	corrupted := bytes.Clone(data)
+	corrupted[10] ^= 0xff // flip a byte of the first leaf hash
+
+	badVersion := bytes.Clone(data)
+	badVersion[4] = 99
+
+	tests := []struct {
+		name     string
+		data     []byte
+		hashFunc hash.Func
+	}{
+		{"empty input", nil, nil},
+		{"bad magic", append([]byte("XXXX"), data[4:]...), nil},
+		{"unsupported version", badVersion, nil},
+		{"truncated", data[:len(data)-5], nil},
+		{"trailing data", append(bytes.Clone(data), 0), nil},
+		{"corrupted leaf", corrupted, nil},
+		{"different hash function", data, hash.SHA3HashFunc},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := UnmarshalMMR(tt.data, tt.hashFunc); err == nil {
+				t.Errorf("UnmarshalMMR() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestUnmarshalMMR_BaggingOrder(t *testing.T) {
+	m := buildMMRWithOrder(t, 5, BagLeftToRight)
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() unexpected error: %v", err)
+	}
+
+	restored, err := UnmarshalMMR(data, nil, WithBaggingOrder(BagLeftToRight))
+	if err != nil {
+		t.Fatalf("UnmarshalMMR() unexpected error: %v", err)
+	}
+	if !bytes.Equal(restored.RootHash(), m.RootHash()) {
+		t.Errorf("RootHash() = %x, want %x", restored.RootHash(), m.RootHash())
+	}
+	if _, err := UnmarshalMMR(data, nil); err == nil {
+		t.Error("UnmarshalMMR() expected error for a different bagging order, got nil")
+	}
+
+	v1 := append([]byte("MMRB"), 1, data[6]) // version 1 has no bagging order byte or pruned size
+	v1 = append(v1, data[8:]...)
+	if _, err := UnmarshalMMR(v1, nil, WithBaggingOrder(BagLeftToRight)); err != nil {
+		t.Errorf("UnmarshalMMR() unexpected error for a version 1 encoding: %v", err)
+	}
+}
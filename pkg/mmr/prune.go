@@ -0,0 +1,31 @@
+package mmr
+
+import "errors"
+
+// ErrPruned is returned when a proof or node lookup needs inner nodes or leaves that were dropped by Prune.
+var ErrPruned = errors.New("node was pruned from the MMR")
+
+// Prune drops every node below the current peaks to bound the memory of a long-running MMR, keeping only the peak hashes and the size. The root hash is unchanged and appends continue to work, merging new leaves with the pruned peaks.
+// The tradeoff is that the pruned leaves can no longer be proven: inclusion proofs for leaves appended before the call and consistency proofs from a size below the prune point return ErrPruned, as does NodeAt for a pruned position. Leaves appended afterwards can still be proven, and consistency proofs from the size at the time of pruning still work since its peaks are kept. Leaves and the by-data index only cover the leaves appended since the last prune. A pruned MMR is marshalled with the peaks at the time of pruning and stays pruned when restored.
+func (m *MMR) Prune() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, peak := range m.peaks {
+		peak.Left, peak.Right = nil, nil
+	}
+	m.Leaves = make([]*Node, 0)
+	m.indexMap = make(map[string][]int)
+	m.pruned = m.size
+}
+
+// leafLocked returns the leaf node with the given index, or ErrPruned if it was pruned. It assumes the caller holds the lock.
+func (m *MMR) leafLocked(index int) (*Node, error) {
+	if index < 0 || index >= m.size {
+		return nil, errors.New("invalid index")
+	}
+	if index < m.pruned {
+		return nil, ErrPruned
+	}
+	return m.Leaves[index-m.pruned], nil
+}
@@ -0,0 +1,85 @@
+package mmr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestPrune_RootUnchanged(t *testing.T) {
+	for _, size := range []int{1, 2, 7, 8, 13} {
+		m := buildMMRWithOrder(t, size, BagRightToLeft)
+		root := m.RootHash()
+		peaks := m.Peaks()
+
+		m.Prune()
+
+		if !bytes.Equal(m.RootHash(), root) {
+			t.Errorf("size %d: RootHash() after Prune() = %x, want %x", size, m.RootHash(), root)
+		}
+		if m.Size() != size {
+			t.Errorf("size %d: Size() after Prune() = %d", size, m.Size())
+		}
+		if got := m.Peaks(); len(got) != len(peaks) {
+			t.Errorf("size %d: %d peaks after Prune(), want %d", size, len(got), len(peaks))
+		}
+		if len(m.Leaves) != 0 {
+			t.Errorf("size %d: %d leaves retained after Prune(), want 0", size, len(m.Leaves))
+		}
+	}
+}
+
+func TestPrune_AppendAndProveAfterwards(t *testing.T) {
+	const pruneAt, size = 5, 13
+	m := buildMMRWithOrder(t, pruneAt, BagRightToLeft)
+	m.Prune()
+	for i := pruneAt; i < size; i++ {
+		if err := m.Append([]byte(fmt.Sprintf("leaf%d", i))); err != nil {
+			t.Fatalf("Append() after Prune() unexpected error: %v", err)
+		}
+	}
+
+	full := buildMMRWithOrder(t, size, BagRightToLeft)
+	if !bytes.Equal(m.RootHash(), full.RootHash()) {
+		t.Fatalf("RootHash() after Prune() and appends = %x, want %x", m.RootHash(), full.RootHash())
+	}
+
+	for i := 0; i < size; i++ {
+		proof, err := m.GenerateInclusionProof(i)
+		if i < pruneAt {
+			if !errors.Is(err, ErrPruned) {
+				t.Errorf("GenerateInclusionProof(%d) of a pruned leaf error = %v, want %v", i, err, ErrPruned)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("GenerateInclusionProof(%d) unexpected error: %v", i, err)
+		}
+		if err := VerifyInclusionProofAtSize([]byte(fmt.Sprintf("leaf%d", i)), proof, m.RootHash(), size, nil); err != nil {
+			t.Errorf("VerifyInclusionProofAtSize(%d) error = %v", i, err)
+		}
+	}
+
+	proof, err := m.GenerateInclusionProofByData([]byte("leaf9"))
+	if err != nil || proof.LeafIndex != 9 {
+		t.Errorf("GenerateInclusionProofByData() = %v, %v, want index 9", proof, err)
+	}
+	if _, err := m.GenerateInclusionProofByData([]byte("leaf2")); err == nil {
+		t.Error("GenerateInclusionProofByData() of a pruned leaf expected error, got nil")
+	}
+
+	consistency, err := m.GenerateConsistencyProof(pruneAt, size) // the peaks at the prune point are kept
+	if err != nil {
+		t.Fatalf("GenerateConsistencyProof(%d, %d) unexpected error: %v", pruneAt, size, err)
+	}
+	if !VerifyConsistencyProof(consistency, buildMMRWithOrder(t, pruneAt, BagRightToLeft).RootHash(), m.RootHash(), nil) {
+		t.Errorf("consistency proof from the prune point does not verify")
+	}
+	if _, err := m.GenerateConsistencyProof(3, size); !errors.Is(err, ErrPruned) {
+		t.Errorf("GenerateConsistencyProof(3, %d) error = %v, want %v", size, err, ErrPruned)
+	}
+	if _, err := m.NodeAt(0); !errors.Is(err, ErrPruned) {
+		t.Errorf("NodeAt(0) error = %v, want %v", err, ErrPruned)
+	}
+}
@@ -33,7 +33,15 @@ func run() error {
 		fmt.Printf("env file error: %v\n", err)
 		return err
 	}
-	config, err := bootstrap.LoadConfig()
+	var (
+		config bootstrap.Config
+		err    error
+	)
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		config, err = bootstrap.LoadConfigFile(path)
+	} else {
+		config, err = bootstrap.LoadConfig()
+	}
 	if err != nil {
 		fmt.Printf("failed to load config: %v\n", err)
 		return err
@@ -46,6 +54,12 @@ func run() error {
 		return err
 	}
 
+	hashFunc, err := bootstrap.NewHashFunc(config)
+	if err != nil {
+		log.Error("failed to resolve ledger hash function", "error", err)
+		return err
+	}
+
 	dbCtx, dbCancel := context.WithTimeout(context.Background(), config.DBConnectTimeout)
 	defer dbCancel()
 	pool, err := bootstrap.NewPgxPool(dbCtx, config.DatabaseURL)
@@ -63,7 +77,7 @@ func run() error {
 
 	// Infrastructure
 	jcsSerializer := serializer.NewJcsSerializer()
-	txProvider := repository.NewTransactionProvider(pool)
+	txProvider := repository.NewTransactionProvider(pool, hashFunc)
 	keyRepo := repository.NewProducerKeyRepository(pool)
 	protect, err := protector.NewAesGcmProtector(masterKEK)
 	if err != nil {
@@ -105,8 +119,11 @@ func run() error {
 		return server.Run()
 	})
 
-	g.Go(func() error {
-		return cpWorker.Start(ctx)
+	// Background tasks are bound to the server lifecycle and are stopped by server.Stop
+	server.Go(func(ctx context.Context) {
+		if err := cpWorker.Start(ctx); err != nil {
+			log.Error("checkpoint worker failed", "error", err)
+		}
 	})
 
 	// 4. Listen for shutdown signals in a separate goroutine
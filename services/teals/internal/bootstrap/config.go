@@ -1,27 +1,45 @@
 package bootstrap
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"reflect"
 	"time"
 
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
 	"github.com/caarlos0/env/v10"
 	"github.com/go-playground/validator/v10"
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
 const defaultDotEnvPath = ".env"
 
 // Config holds the server configuration loaded from environment variables.
 type Config struct {
-	Env                 string        `env:"ENV" envDefault:"development"`
-	Port                int           `env:"PORT" validate:"required"`
-	EnableReflection    bool          `env:"ENABLE_REFLECTION" envDefault:"false"`
-	DatabaseURL         string        `env:"POSTGRES_URL" validate:"required"`
-	DBConnectTimeout    time.Duration `env:"DB_CONNECT_TIMEOUT" envDefault:"10s"`
-	ShutdownTimeout     time.Duration `env:"SHUTDOWN_TIMEOUT"   envDefault:"30s"`
-	ServerPrivateKeyB64 string        `env:"SERVER_PRIVATE_KEY_B64" validate:"required"`
-	CheckpointInterval  time.Duration `env:"CHECKPOINT_INTERVAL" envDefault:"10s"`
-	MasterKEKB64        string        `env:"MASTER_KEK_B64" validate:"required"`
+	Env                 string        `env:"ENV" envDefault:"development" yaml:"env"`
+	Port                int           `env:"PORT" validate:"required" yaml:"port"`
+	EnableReflection    bool          `env:"ENABLE_REFLECTION" envDefault:"false" yaml:"enable_reflection"`
+	DatabaseURL         string        `env:"POSTGRES_URL" validate:"required" yaml:"postgres_url"`
+	DBConnectTimeout    time.Duration `env:"DB_CONNECT_TIMEOUT" envDefault:"10s" yaml:"db_connect_timeout"`
+	ShutdownTimeout     time.Duration `env:"SHUTDOWN_TIMEOUT"   envDefault:"30s" yaml:"shutdown_timeout"`
+	ServerPrivateKeyB64 string        `env:"SERVER_PRIVATE_KEY_B64" validate:"required" yaml:"server_private_key_b64"`
+	CheckpointInterval  time.Duration `env:"CHECKPOINT_INTERVAL" envDefault:"10s" yaml:"checkpoint_interval"`
+	MasterKEKB64        string        `env:"MASTER_KEK_B64" validate:"required" yaml:"master_kek_b64"`
+	RateLimitRPS        float64       `env:"RATE_LIMIT_RPS" envDefault:"0" validate:"gte=0" yaml:"rate_limit_rps"` // requests per second per client IP, 0 disables rate limiting
+	RateLimitBurst      int           `env:"RATE_LIMIT_BURST" envDefault:"20" validate:"gte=1" yaml:"rate_limit_burst"`
+	HashAlgo            string        `env:"HASH_ALGO" envDefault:"sha3-256" yaml:"hash_algo"` // registered name of the ledger hash function, see hash.ByName
+}
+
+// NewHashFunc resolves the ledger hash function configured by HashAlgo. The algorithm can't be changed for an existing ledger, as the stored node hashes were computed with it.
+func NewHashFunc(cfg Config) (hash.Func, error) {
+	hashFunc, err := hash.ByName(cfg.HashAlgo)
+	if err != nil {
+		return nil, fmt.Errorf("invalid HASH_ALGO: %w", err)
+	}
+	return hashFunc, nil
 }
 
 // LoadEnvFile loads environment variables from the specified .env file.
@@ -49,3 +67,46 @@ func LoadConfig() (Config, error) {
 	}
 	return cfg, nil
 }
+
+// LoadConfigFile loads the configuration from a YAML or JSON file (JSON being valid YAML), whose keys are the lowercased environment variable names, e.g. postgres_url. Environment variables that are set, including those loaded from a .env file by LoadEnvFile, override the file values, and defaults apply to fields set by neither. Durations are written as strings like "10s". The result is validated like in LoadConfig.
+func LoadConfigFile(path string) (Config, error) {
+	cfg := Config{}
+	if err := env.ParseWithOptions(&cfg, env.Options{Environment: map[string]string{}}); err != nil { // defaults only
+		return cfg, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to open config file %q: %w", path, err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return cfg, fmt.Errorf("failed to parse config file %q: %w", path, err)
+	}
+
+	fromEnv := Config{}
+	if err := env.Parse(&fromEnv); err != nil {
+		return cfg, err
+	}
+	overrideFromEnv(&cfg, fromEnv)
+
+	v := validator.New()
+	if err := v.Struct(&cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// overrideFromEnv copies the fields of fromEnv whose environment variable is set to a non-empty value into cfg.
+func overrideFromEnv(cfg *Config, fromEnv Config) {
+	dst, src := reflect.ValueOf(cfg).Elem(), reflect.ValueOf(fromEnv)
+	for i := 0; i < dst.NumField(); i++ {
+		key := dst.Type().Field(i).Tag.Get("env")
+		if value, ok := os.LookupEnv(key); ok && value != "" {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package bootstrap
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+)
+
+// writeConfigFile writes the content to a config file with the given name in a temporary directory and returns its path.
+func writeConfigFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+// clearConfigEnv sets every environment variable read by Config to an empty value for the duration of the test, so values from the ambient environment don't override the config file.
+func clearConfigEnv(t *testing.T) {
+	t.Helper()
+	fields := reflect.TypeOf(Config{})
+	for i := 0; i < fields.NumField(); i++ {
+		if key := fields.Field(i).Tag.Get("env"); key != "" {
+			t.Setenv(key, "")
+		}
+	}
+}
+
+func TestLoadConfigFile_YAMLWithEnvOverride(t *testing.T) {
+	clearConfigEnv(t)
+	path := writeConfigFile(t, "config.yaml", `
+port: 8080
+postgres_url: postgres://file
+server_private_key_b64: file-key
+master_kek_b64: file-kek
+checkpoint_interval: 1m
+`)
+	t.Setenv("PORT", "9090")
+
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFile() unexpected error: %v", err)
+	}
+
+	if cfg.Port != 9090 {
+		t.Errorf("Port = %d, want the env override 9090", cfg.Port)
+	}
+	if cfg.DatabaseURL != "postgres://file" {
+		t.Errorf("DatabaseURL = %q, want the file value", cfg.DatabaseURL)
+	}
+	if cfg.CheckpointInterval != time.Minute {
+		t.Errorf("CheckpointInterval = %v, want the file value 1m", cfg.CheckpointInterval)
+	}
+	if cfg.ShutdownTimeout != 30*time.Second || cfg.Env != "development" {
+		t.Errorf("ShutdownTimeout = %v, Env = %q, want the defaults 30s and development", cfg.ShutdownTimeout, cfg.Env)
+	}
+}
+
+func TestLoadConfigFile_JSON(t *testing.T) {
+	clearConfigEnv(t)
+	path := writeConfigFile(t, "config.json", `{
+	"port": 8080,
+	"postgres_url": "postgres://file",
+	"server_private_key_b64": "file-key",
+	"master_kek_b64": "file-kek",
+	"rate_limit_rps": 5
+}`)
+
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFile() unexpected error: %v", err)
+	}
+	if cfg.Port != 8080 || cfg.RateLimitRPS != 5 || cfg.RateLimitBurst != 20 {
+		t.Errorf("Port = %d, RateLimitRPS = %v, RateLimitBurst = %d, want 8080, 5 and the default 20", cfg.Port, cfg.RateLimitRPS, cfg.RateLimitBurst)
+	}
+}
+
+func TestLoadConfigFile_Errors(t *testing.T) {
+	clearConfigEnv(t)
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"missing required field", "port: 8080\n"},
+		{"unknown field", "port: 8080\npostgres_url: x\nserver_private_key_b64: k\nmaster_kek_b64: k\nprot: 1\n"},
+		{"invalid syntax", "port: [\n"},
+		{"validation failure", "port: 8080\npostgres_url: x\nserver_private_key_b64: k\nmaster_kek_b64: k\nrate_limit_burst: 0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadConfigFile(writeConfigFile(t, "config.yaml", tt.content)); err == nil {
+				t.Errorf("LoadConfigFile() expected error, got nil")
+			}
+		})
+	}
+
+	if _, err := LoadConfigFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("LoadConfigFile() of a missing file expected error, got nil")
+	}
+}
+
+func TestNewHashFunc(t *testing.T) {
+	tests := []struct {
+		name     string
+		hashAlgo string
+		wantErr  bool
+	}{
+		{"default sha3-256", "sha3-256", false},
+		{"sha256", "sha256", false},
+		{"blake2b-256", "blake2b-256", false},
+		{"unknown", "md5", true},
+		{"empty", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hashFunc, err := NewHashFunc(Config{HashAlgo: tt.hashAlgo})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewHashFunc() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && hash.NameOf(hashFunc) != tt.hashAlgo {
+				t.Errorf("NewHashFunc() resolved %q, want %q", hash.NameOf(hashFunc), tt.hashAlgo)
+			}
+		})
+	}
+}
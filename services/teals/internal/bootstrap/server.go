@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net"
+	"sync"
 
 	"buf.build/go/protovalidate"
 	auditv1 "github.com/andrlikjirka/dp-teals/gen/audit/v1"
@@ -17,6 +18,9 @@ import (
 	"google.golang.org/grpc/reflection"
 )
 
+// LivenessService is the health service name reporting liveness: it is serving as long as the process runs and the server hasn't been stopped. The overall status, the empty service name, reports readiness and is only serving while the server accepts requests.
+const LivenessService = "liveness"
+
 // Server encapsulates the gRPC server and its dependencies.
 type Server struct {
 	grpcSrv      *grpc.Server
@@ -24,6 +28,10 @@ type Server struct {
 	logger       *slog.Logger
 	healthServer *health.Server
 	config       Config
+
+	bgCtx    context.Context    // cancelled on Stop to signal background tasks to exit
+	bgCancel context.CancelFunc // cancels bgCtx
+	wg       sync.WaitGroup     // tracks background tasks started via Go
 }
 
 // NewServer creates a new Server instance with the given configuration
@@ -44,13 +52,20 @@ func NewServer(cfg Config, log *logger.Logger, ingestor auditv1.IngestionService
 		return nil, fmt.Errorf("failed to create protovalidate validator: %w", err)
 	}
 
+	recovery := interceptor.NewRecoveryInterceptor(log)
 	jws := interceptor.NewSignatureInterceptor(log)
-	grpcSrv := grpc.NewServer(
-		grpc.ChainUnaryInterceptor(
-			jws.UnaryInterceptor,
-			protovalidatemiddleware.UnaryServerInterceptor(validator),
-		),
+	interceptors := []grpc.UnaryServerInterceptor{
+		recovery.UnaryInterceptor, // outermost, so panics in the other interceptors are recovered too
+	}
+	if cfg.RateLimitRPS > 0 {
+		rateLimit := interceptor.NewRateLimitInterceptor(cfg.RateLimitRPS, cfg.RateLimitBurst, log)
+		interceptors = append(interceptors, rateLimit.UnaryInterceptor)
+	}
+	interceptors = append(interceptors,
+		jws.UnaryInterceptor,
+		protovalidatemiddleware.UnaryServerInterceptor(validator),
 	)
+	grpcSrv := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...))
 
 	auditv1.RegisterIngestionServiceServer(grpcSrv, ingestor)
 	auditv1.RegisterKeyRegistrationServiceServer(grpcSrv, keys)
@@ -59,6 +74,8 @@ func NewServer(cfg Config, log *logger.Logger, ingestor auditv1.IngestionService
 	auditv1.RegisterDataSubjectServiceServer(grpcSrv, subject)
 
 	healthServer := health.NewServer()
+	healthServer.SetServingStatus(LivenessService, grpc_health_v1.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING) // not ready until Run starts serving
 	grpc_health_v1.RegisterHealthServer(grpcSrv, healthServer)
 	// go checkDatabaseHealth -> 1 sec interval -> health server status update
 
@@ -66,21 +83,34 @@ func NewServer(cfg Config, log *logger.Logger, ingestor auditv1.IngestionService
 		reflection.Register(grpcSrv)
 	}
 
+	bgCtx, bgCancel := context.WithCancel(context.Background())
 	return &Server{
 		grpcSrv:      grpcSrv,
 		listener:     listener,
 		config:       cfg,
 		logger:       log.Logger,
 		healthServer: healthServer,
+		bgCtx:        bgCtx,
+		bgCancel:     bgCancel,
 	}, nil
 }
 
+// Go runs the task in a background goroutine bound to the server lifecycle. The task receives a context that is cancelled when Stop is called, and Stop waits for the task to return before completing.
+func (s *Server) Go(task func(ctx context.Context)) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		task(s.bgCtx)
+	}()
+}
+
 // Run starts the gRPC server and listens for incoming requests.
 func (s *Server) Run() error {
 	if s.listener == nil {
 		return fmt.Errorf("server listener is not initialized")
 	}
 	s.logger.Info("Server listening", slog.Int("port", s.config.Port))
+	s.healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
 
 	if err := s.grpcSrv.Serve(s.listener); err != nil {
 		return fmt.Errorf("failed to serve: %w", err)
@@ -93,12 +123,15 @@ func (s *Server) Run() error {
 func (s *Server) Stop(ctx context.Context) error {
 	s.logger.Info("Initiating graceful shutdown...")
 
-	s.healthServer.Shutdown()
+	s.healthServer.Shutdown() // report not serving for readiness and liveness while draining
+
+	s.bgCancel() // signal background tasks to exit
 
-	// Create a channel to signal when GracefulStop is done
+	// Create a channel to signal when GracefulStop and all background tasks are done
 	done := make(chan struct{})
 	go func() {
 		s.grpcSrv.GracefulStop()
+		s.wg.Wait()
 		close(done)
 	}()
 
@@ -0,0 +1,187 @@
+package bootstrap
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	auditv1 "github.com/andrlikjirka/dp-teals/gen/audit/v1"
+	"github.com/andrlikjirka/dp-teals/pkg/logger"
+	"github.com/andrlikjirka/dp-teals/services/teals/internal/service/model"
+	"github.com/andrlikjirka/dp-teals/services/teals/internal/transport/worker"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// newTestServer creates a Server listening on a random free port with unimplemented gRPC services.
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	srv, err := NewServer(
+		Config{Port: 0},
+		logger.New("test"),
+		auditv1.UnimplementedIngestionServiceServer{},
+		auditv1.UnimplementedKeyRegistrationServiceServer{},
+		auditv1.UnimplementedProofServiceServer{},
+		auditv1.UnimplementedQueryServiceServer{},
+		auditv1.UnimplementedDataSubjectServiceServer{},
+	)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	return srv
+}
+
+func TestServer_StopWaitsForBackgroundTasks(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	srv := newTestServer(t)
+	runErr := make(chan error, 1)
+	go func() { runErr <- srv.Run() }()
+
+	feedExited := make(chan struct{})
+	selfCheckExited := make(chan struct{})
+	srv.Go(func(ctx context.Context) { // feed consumer
+		defer close(feedExited)
+		<-ctx.Done()
+	})
+	srv.Go(func(ctx context.Context) { // periodic self-check
+		defer close(selfCheckExited)
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				time.Sleep(20 * time.Millisecond) // simulate cleanup work Stop must wait for
+				return
+			}
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Stop(ctx); err != nil {
+		t.Fatalf("Stop returned unexpected error: %v", err)
+	}
+
+	for name, exited := range map[string]chan struct{}{"feed consumer": feedExited, "self-check": selfCheckExited} {
+		select {
+		case <-exited:
+		default:
+			t.Errorf("%s goroutine still running after Stop returned", name)
+		}
+	}
+
+	<-runErr // Run may race with Stop, only its return matters here
+
+	// allow the runtime to reap goroutines that are finishing their return path
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := runtime.NumGoroutine(); got > baseline {
+		t.Errorf("goroutine leak: %d goroutines after Stop, want at most %d", got, baseline)
+	}
+}
+
+// slowCheckpointCreator is a service.CheckpointCreator whose in-flight checkpoint only finishes some time after its context is cancelled.
+type slowCheckpointCreator struct {
+	started chan struct{}
+	calls   int
+}
+
+func (c *slowCheckpointCreator) CreateCheckpoint(ctx context.Context) (*model.SignedCheckpoint, error) {
+	if c.calls++; c.calls == 1 {
+		close(c.started)
+	}
+	<-ctx.Done()
+	time.Sleep(20 * time.Millisecond) // simulate a transaction rolling back
+	return nil, ctx.Err()
+}
+
+func TestServer_StopDrainsCheckpointWorker(t *testing.T) {
+	srv := newTestServer(t)
+	go func() { _ = srv.Run() }()
+
+	creator := &slowCheckpointCreator{started: make(chan struct{})}
+	cpWorker := worker.NewCheckpointWorker(creator, time.Millisecond, logger.New("test"))
+	workerExited := make(chan struct{})
+	srv.Go(func(ctx context.Context) {
+		defer close(workerExited)
+		_ = cpWorker.Start(ctx)
+	})
+	<-creator.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Stop(ctx); err != nil {
+		t.Fatalf("Stop returned unexpected error: %v", err)
+	}
+
+	select {
+	case <-workerExited:
+	default:
+		t.Error("Stop returned before the checkpoint worker exited")
+	}
+}
+
+func TestServer_StopTimesOutOnStuckTask(t *testing.T) {
+	srv := newTestServer(t)
+	go func() { _ = srv.Run() }()
+
+	release := make(chan struct{})
+	defer close(release)
+	srv.Go(func(ctx context.Context) {
+		<-release // ignores cancellation
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := srv.Stop(ctx); err == nil {
+		t.Error("expected Stop to return a timeout error for a stuck background task")
+	}
+}
+
+func TestServer_HealthStatus(t *testing.T) {
+	srv := newTestServer(t)
+
+	status := func(service string) grpc_health_v1.HealthCheckResponse_ServingStatus {
+		t.Helper()
+		resp, err := srv.healthServer.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatalf("health check of %q failed: %v", service, err)
+		}
+		return resp.GetStatus()
+	}
+
+	if got := status(LivenessService); got != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Errorf("liveness before Run = %v, want SERVING", got)
+	}
+	if got := status(""); got != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("readiness before Run = %v, want NOT_SERVING", got)
+	}
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- srv.Run() }()
+
+	deadline := time.Now().Add(time.Second)
+	for status("") != grpc_health_v1.HealthCheckResponse_SERVING && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := status(""); got != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Errorf("readiness after Run = %v, want SERVING", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Stop(ctx); err != nil {
+		t.Fatalf("Stop returned unexpected error: %v", err)
+	}
+	<-runErr
+
+	for _, service := range []string{"", LivenessService} {
+		if got := status(service); got != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+			t.Errorf("status of %q after Stop = %v, want NOT_SERVING", service, got)
+		}
+	}
+}
@@ -236,7 +236,7 @@ func (r *LedgerRepository) GenerateInclusionProof(ctx context.Context, leafIndex
 		LedgerSize: size,
 		LeafHash:   path[0].Hash,
 		RootHash:   rootHash,
-		Proof:      &mmr.InclusionProof{Siblings: siblings, Left: siblingLeft},
+		Proof:      &mmr.InclusionProof{LeafIndex: int(leafIndex), Siblings: siblings, Left: siblingLeft},
 	}, nil
 }
 
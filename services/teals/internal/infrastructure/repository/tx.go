@@ -12,20 +12,22 @@ import (
 
 // TransactionProvider provides a way to execute multiple repository operations within a single database transaction.
 type TransactionProvider struct {
-	pool *pgxpool.Pool
+	pool     *pgxpool.Pool
+	hashFunc hash.Func
 }
 
-// NewTransactionProvider creates a new TransactionProvider with the given database connection pool.
-func NewTransactionProvider(pool *pgxpool.Pool) *TransactionProvider {
+// NewTransactionProvider creates a new TransactionProvider with the given database connection pool. The hash function is passed to the ledger repository to hash the MMR nodes.
+func NewTransactionProvider(pool *pgxpool.Pool, hashFunc hash.Func) *TransactionProvider {
 	return &TransactionProvider{
-		pool: pool,
+		pool:     pool,
+		hashFunc: hashFunc,
 	}
 }
 
 // Transact executes the given function within a database transaction. It provides a set of repositories that use the same transaction context. If the function returns an error, the transaction is rolled back; otherwise, it is committed.
 func (tp *TransactionProvider) Transact(ctx context.Context, txFunc func(ports.Repositories) error) error {
 	return runInTransaction(ctx, tp.pool, func(tx pgx.Tx) error {
-		ledgerRepo := NewLedgerRepository(tx, hash.SHA3HashFunc)
+		ledgerRepo := NewLedgerRepository(tx, tp.hashFunc)
 		subjectSecretRepo := NewSubjectSecretRepository(tx)
 
 		r := ports.Repositories{
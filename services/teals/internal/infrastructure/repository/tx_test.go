@@ -5,6 +5,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
 	"github.com/andrlikjirka/dp-teals/services/teals/internal/service/ports"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -15,7 +16,7 @@ import (
 
 func TestTransactionProvider_Transact(t *testing.T) {
 	ctx := context.Background()
-	tp := repository.NewTransactionProvider(testPool)
+	tp := repository.NewTransactionProvider(testPool, hash.SHA3HashFunc)
 
 	// Create a standalone repo to verify data outside the transaction context
 	verifierRepo := repository.NewSubjectSecretRepository(testPool)
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"expvar"
 	"fmt"
 
 	"github.com/andrlikjirka/dp-teals/pkg/logger"
@@ -11,6 +12,9 @@ import (
 	"github.com/andrlikjirka/dp-teals/services/teals/internal/service/ports"
 )
 
+// verificationFailures counts audit events rejected due to an invalid signature. A spike indicates either a misbehaving producer or an attack probing the log.
+var verificationFailures = expvar.NewInt("verification_failures_total")
+
 // AuditIngestor defines the interface for ingesting audit events.
 type AuditIngestor interface {
 	IngestAuditEvent(ctx context.Context, event *model.AuditEvent, signature string) (*model.IngestAuditEventResult, error)
@@ -99,7 +103,8 @@ func (s *AuditService) verifyEventSignature(ctx context.Context, event *model.Au
 	// 2. Verify the JWS signature against the canonical payload. KID is extracted from the token's protected header by the verifier.
 	kid, err := s.verifier.Verify(ctx, sigToken, payloadBytes)
 	if err != nil {
-		s.logger.Warn("audit event rejected: invalid signature", "event_id", event.ID, "error", err)
+		verificationFailures.Add(1)
+		s.logger.Warn("audit event rejected: invalid signature", "event_id", event.ID, "reason", svcerrors.ErrInvalidSignature.Error(), "error", err)
 		return "", svcerrors.ErrInvalidSignature
 	}
 	s.logger.Info("signature verified successfully", "event_id", event.ID, "kid", kid)
@@ -1,12 +1,16 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"log/slog"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/andrlikjirka/dp-teals/pkg/logger"
 	svcerrors "github.com/andrlikjirka/dp-teals/services/teals/internal/service/errors"
 	svcmodel "github.com/andrlikjirka/dp-teals/services/teals/internal/service/model"
 	"github.com/andrlikjirka/dp-teals/services/teals/internal/service/model/enum"
@@ -257,3 +261,34 @@ func TestAuditService_IngestAuditEvent_Errors(t *testing.T) {
 		})
 	}
 }
+
+func TestAuditService_IngestAuditEvent_InvalidSignatureIsCountedAndLogged(t *testing.T) {
+	var buf bytes.Buffer
+	log := &logger.Logger{Logger: slog.New(slog.NewTextHandler(&buf, nil))}
+
+	svc := NewAuditService(
+		&mockTx{repos: defaultRepos()},
+		&mockSerializer{},
+		&mockVerifier{
+			VerifyFunc: func(_ context.Context, _ string, _ []byte) (string, error) {
+				return "", errors.New("bad signature")
+			},
+		},
+		&mockProtector{},
+		log,
+	)
+
+	before := verificationFailures.Value()
+	_, err := svc.IngestAuditEvent(context.Background(), newTestAuditEvent(t), "token")
+	if !errors.Is(err, svcerrors.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+
+	if got := verificationFailures.Value() - before; got != 1 {
+		t.Errorf("verification_failures_total increased by %d, want 1", got)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "level=WARN") || !strings.Contains(out, "reason=") {
+		t.Errorf("expected a warn log with a reason, got %q", out)
+	}
+}
@@ -0,0 +1,126 @@
+package interceptor
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/andrlikjirka/dp-teals/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// RetryAfterHeaderKey is the response header set on rate-limited requests, holding the number of whole seconds the client should wait before retrying.
+const RetryAfterHeaderKey = "retry-after"
+
+// maxTrackedClients bounds the number of client buckets kept in memory; beyond it, buckets of idle clients are dropped, and if none are idle, the least recently used one.
+const maxTrackedClients = 10000
+
+// RateLimitInterceptor is a gRPC interceptor that limits the request rate of each client IP with a token bucket.
+type RateLimitInterceptor struct {
+	logger  *logger.Logger
+	rps     float64
+	burst   float64
+	now     func() time.Time
+	mu      sync.Mutex
+	buckets map[string]*list.Element // client → element of lru holding its *tokenBucket
+	lru     *list.List               // buckets from the most to the least recently used
+}
+
+// tokenBucket holds the tokens left for a client and the time they were last refilled.
+type tokenBucket struct {
+	client string
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimitInterceptor creates a new RateLimitInterceptor allowing each client IP rps requests per second on average and bursts of up to burst requests. rps must be positive; the server doesn't install the interceptor when RATE_LIMIT_RPS is 0, its default.
+func NewRateLimitInterceptor(rps float64, burst int, log *logger.Logger) *RateLimitInterceptor {
+	return &RateLimitInterceptor{
+		logger:  log,
+		rps:     rps,
+		burst:   float64(max(burst, 1)),
+		now:     time.Now,
+		buckets: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// UnaryInterceptor is a gRPC unary interceptor that takes a token from the bucket of the calling client IP. If the bucket is empty, the request is rejected with a ResourceExhausted status and a retry-after header telling the client how many seconds to wait.
+func (i *RateLimitInterceptor) UnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	client := clientIP(ctx)
+	wait := i.take(client)
+	if wait > 0 {
+		retryAfter := strconv.Itoa(int(math.Ceil(wait.Seconds())))
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RetryAfterHeaderKey, retryAfter))
+		i.logger.Warn("request rejected: rate limit exceeded", "method", info.FullMethod, "client", client)
+		return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+	return handler(ctx, req)
+}
+
+// take refills the bucket of the client and takes a token from it. It returns zero if a token was taken, or how long the client has to wait for the next token.
+func (i *RateLimitInterceptor) take(client string) time.Duration {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := i.now()
+	var b *tokenBucket
+	if e, ok := i.buckets[client]; ok {
+		i.lru.MoveToFront(e)
+		b = e.Value.(*tokenBucket)
+	} else {
+		if len(i.buckets) >= maxTrackedClients {
+			i.evictIdleLocked(now)
+		}
+		if len(i.buckets) >= maxTrackedClients {
+			i.removeLocked(i.lru.Back())
+		}
+		b = &tokenBucket{client: client, tokens: i.burst, last: now}
+		i.buckets[client] = i.lru.PushFront(b)
+	}
+
+	b.tokens = min(i.burst, b.tokens+now.Sub(b.last).Seconds()*i.rps)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / i.rps * float64(time.Second))
+}
+
+// evictIdleLocked drops the buckets that have refilled completely, as they behave like new ones, walking from the least recently used one and stopping at the first bucket that isn't idle. Every bucket it visits but the last is dropped, so the cost stays proportional to the evicted buckets even when a flood of new clients keeps the map full. It assumes the caller holds the mutex.
+func (i *RateLimitInterceptor) evictIdleLocked(now time.Time) {
+	for e := i.lru.Back(); e != nil; e = i.lru.Back() {
+		if b := e.Value.(*tokenBucket); b.tokens+now.Sub(b.last).Seconds()*i.rps < i.burst {
+			return
+		}
+		i.removeLocked(e)
+	}
+}
+
+// removeLocked drops the bucket held by the element of lru. It assumes the caller holds the mutex.
+func (i *RateLimitInterceptor) removeLocked(e *list.Element) {
+	delete(i.buckets, e.Value.(*tokenBucket).client)
+	i.lru.Remove(e)
+}
+
+// clientIP returns the IP address of the calling peer, or an empty string if it is unknown.
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
@@ -0,0 +1,166 @@
+package interceptor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// peerCtx builds a context carrying a gRPC peer with the given address.
+func peerCtx(addr string) context.Context {
+	tcpAddr, _ := net.ResolveTCPAddr("tcp", addr)
+	return peer.NewContext(context.Background(), &peer.Peer{Addr: tcpAddr})
+}
+
+func TestRateLimitInterceptor_RejectsOverBurst(t *testing.T) {
+	const burst = 5
+	i := NewRateLimitInterceptor(1, burst, newTestLogger())
+	now := time.Now()
+	i.now = func() time.Time { return now }
+	info := &grpc.UnaryServerInfo{FullMethod: "/audit.v1.ProofService/GetInclusionProof"}
+
+	calls := 0
+	handler := func(ctx context.Context, req any) (any, error) {
+		calls++
+		return nil, nil
+	}
+
+	for n := 0; n < burst; n++ {
+		if _, err := i.UnaryInterceptor(peerCtx("10.0.0.1:1234"), nil, info, handler); err != nil {
+			t.Fatalf("request %d: unexpected error: %v", n+1, err)
+		}
+	}
+
+	_, err := i.UnaryInterceptor(peerCtx("10.0.0.1:5678"), nil, info, neverCalledHandler(t))
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expected ResourceExhausted for request %d, got %v", burst+1, err)
+	}
+	if calls != burst {
+		t.Errorf("expected handler to be called %d times, got %d", burst, calls)
+	}
+
+	// another client has its own bucket
+	if _, err := i.UnaryInterceptor(peerCtx("10.0.0.2:1234"), nil, info, handler); err != nil {
+		t.Errorf("other client: unexpected error: %v", err)
+	}
+
+	// a token is refilled after 1/rps seconds
+	now = now.Add(time.Second)
+	if _, err := i.UnaryInterceptor(peerCtx("10.0.0.1:1234"), nil, info, handler); err != nil {
+		t.Errorf("after refill: unexpected error: %v", err)
+	}
+}
+
+func TestRateLimitInterceptor_RetryAfter(t *testing.T) {
+	i := NewRateLimitInterceptor(0.5, 1, newTestLogger())
+	now := time.Now()
+	i.now = func() time.Time { return now }
+
+	if wait := i.take("10.0.0.1"); wait != 0 {
+		t.Fatalf("expected first request to pass, got wait %v", wait)
+	}
+	if wait := i.take("10.0.0.1"); wait != 2*time.Second {
+		t.Errorf("expected wait of 2s, got %v", wait)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if wait := i.take("10.0.0.1"); wait != 1500*time.Millisecond {
+		t.Errorf("expected wait of 1.5s, got %v", wait)
+	}
+}
+
+func TestRateLimitInterceptor_EvictsIdleClients(t *testing.T) {
+	i := NewRateLimitInterceptor(10, 1, newTestLogger())
+	now := time.Now()
+	i.now = func() time.Time { return now }
+
+	i.buckets["idle"] = i.lru.PushFront(&tokenBucket{client: "idle", tokens: 0, last: now.Add(-time.Minute)})
+	i.buckets["busy"] = i.lru.PushFront(&tokenBucket{client: "busy", tokens: 0, last: now})
+	i.buckets["recent"] = i.lru.PushFront(&tokenBucket{client: "recent", tokens: 1, last: now})
+	i.evictIdleLocked(now)
+
+	if _, ok := i.buckets["idle"]; ok {
+		t.Error("expected idle client bucket to be evicted")
+	}
+	if _, ok := i.buckets["busy"]; !ok {
+		t.Error("expected busy client bucket to be kept")
+	}
+	if _, ok := i.buckets["recent"]; !ok {
+		t.Error("expected the eviction to stop at the busy client, keeping the more recently used bucket")
+	}
+	if i.lru.Len() != len(i.buckets) {
+		t.Errorf("expected %d buckets in the LRU list, got %d", len(i.buckets), i.lru.Len())
+	}
+}
+
+func TestRateLimitInterceptor_EvictsLeastRecentlyUsedClient(t *testing.T) {
+	i := NewRateLimitInterceptor(1, 1, newTestLogger())
+	now := time.Now()
+	i.now = func() time.Time { return now }
+
+	// every client has spent its only token, so none is idle
+	for n := 0; n < maxTrackedClients; n++ {
+		i.take(fmt.Sprintf("10.0.%d.%d", n/256, n%256))
+	}
+	i.take("10.0.0.0") // touch the oldest client, making 10.0.0.1 the least recently used
+
+	i.take("192.168.0.1")
+	if len(i.buckets) != maxTrackedClients || i.lru.Len() != maxTrackedClients {
+		t.Errorf("expected %d tracked clients, got %d buckets and %d in the LRU list", maxTrackedClients, len(i.buckets), i.lru.Len())
+	}
+	if _, ok := i.buckets["10.0.0.1"]; ok {
+		t.Error("expected the least recently used client bucket to be evicted")
+	}
+	if _, ok := i.buckets["10.0.0.0"]; !ok {
+		t.Error("expected the recently used client bucket to be kept")
+	}
+}
+
+// headerStream is a grpc.ServerTransportStream recording the headers set by a handler.
+type headerStream struct {
+	header metadata.MD
+}
+
+func (s *headerStream) Method() string { return "/audit.v1.ProofService/GetInclusionProof" }
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *headerStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+
+func (s *headerStream) SetTrailer(metadata.MD) error { return nil }
+
+func TestRateLimitInterceptor_SetsRetryAfterHeader(t *testing.T) {
+	i := NewRateLimitInterceptor(0.5, 1, newTestLogger())
+	now := time.Now()
+	i.now = func() time.Time { return now }
+	info := &grpc.UnaryServerInfo{FullMethod: "/audit.v1.ProofService/GetInclusionProof"}
+	handler := func(ctx context.Context, req any) (any, error) { return nil, nil }
+
+	stream := &headerStream{}
+	ctx := grpc.NewContextWithServerTransportStream(peerCtx("10.0.0.1:1234"), stream)
+	if _, err := i.UnaryInterceptor(ctx, nil, info, handler); err != nil {
+		t.Fatalf("first request: unexpected error: %v", err)
+	}
+	if got := stream.header.Get(RetryAfterHeaderKey); len(got) != 0 {
+		t.Errorf("expected no %s header on an accepted request, got %v", RetryAfterHeaderKey, got)
+	}
+
+	_, err := i.UnaryInterceptor(ctx, nil, info, neverCalledHandler(t))
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+	if got := stream.header.Get(RetryAfterHeaderKey); len(got) != 1 || got[0] != "2" {
+		t.Errorf("expected %s header [2], got %v", RetryAfterHeaderKey, got)
+	}
+}
@@ -0,0 +1,33 @@
+package interceptor
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/andrlikjirka/dp-teals/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RecoveryInterceptor is a gRPC interceptor that recovers panics in handlers, so a single failing request doesn't crash the server.
+type RecoveryInterceptor struct {
+	logger *logger.Logger
+}
+
+// NewRecoveryInterceptor creates a new RecoveryInterceptor that logs recovered panics with the provided logger.
+func NewRecoveryInterceptor(log *logger.Logger) *RecoveryInterceptor {
+	return &RecoveryInterceptor{logger: log}
+}
+
+// UnaryInterceptor is a gRPC unary interceptor that recovers a panic in the downstream handler, logs it with the method and stack trace, and returns an Internal error status to the client instead of the panic details.
+func (i *RecoveryInterceptor) UnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			i.logger.Error("recovered from panic in handler", "method", info.FullMethod, "panic", r, "stack", string(debug.Stack()))
+			resp, err = nil, status.Error(codes.Internal, "internal server error")
+		}
+	}()
+
+	return handler(ctx, req)
+}
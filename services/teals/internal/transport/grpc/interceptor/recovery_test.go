@@ -0,0 +1,61 @@
+package interceptor
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/andrlikjirka/dp-teals/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRecoveryInterceptor_RecoversPanic(t *testing.T) {
+	var buf bytes.Buffer
+	log := &logger.Logger{Logger: slog.New(slog.NewJSONHandler(&buf, nil))}
+	i := NewRecoveryInterceptor(log)
+	info := &grpc.UnaryServerInfo{FullMethod: "/audit.v1.QueryService/GetAuditEvent"}
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		panic("boom")
+	}
+
+	resp, err := i.UnaryInterceptor(context.Background(), nil, info, handler)
+	if resp != nil {
+		t.Errorf("expected nil response, got %v", resp)
+	}
+	if status.Code(err) != codes.Internal {
+		t.Errorf("expected Internal, got %v", status.Code(err))
+	}
+	if strings.Contains(err.Error(), "boom") {
+		t.Errorf("panic value leaked to the client: %v", err)
+	}
+
+	logged := buf.String()
+	for _, want := range []string{"recovered from panic", "boom", info.FullMethod, "goroutine"} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("log entry missing %q: %s", want, logged)
+		}
+	}
+}
+
+func TestRecoveryInterceptor_PassesThrough(t *testing.T) {
+	i := NewRecoveryInterceptor(newTestLogger())
+	info := &grpc.UnaryServerInfo{FullMethod: "/audit.v1.QueryService/GetAuditEvent"}
+
+	wantErr := status.Error(codes.NotFound, "not found")
+	handler := func(ctx context.Context, req any) (any, error) {
+		return "response", wantErr
+	}
+
+	resp, err := i.UnaryInterceptor(context.Background(), nil, info, handler)
+	if resp != "response" {
+		t.Errorf("expected handler response, got %v", resp)
+	}
+	if err != wantErr {
+		t.Errorf("expected handler error %v, got %v", wantErr, err)
+	}
+}
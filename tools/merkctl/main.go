@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/andrlikjirka/dp-teals/pkg/hash"
+	"github.com/andrlikjirka/dp-teals/pkg/merkle"
+)
+
+const usage = `usage: merkctl <command> [flags]
+
+commands:
+  root    -leaves FILE [-hash NAME]                     print the root hash of the tree built from FILE
+  prove   -leaves FILE -index N [-hash NAME]            print the inclusion proof of leaf N as JSON
+  verify  -proof FILE -root HEX -leaf DATA [-hash NAME] verify an inclusion proof, exit 1 if invalid
+
+FILE contains one leaf per line.`
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "root":
+		runRoot(args)
+	case "prove":
+		runProve(args)
+	case "verify":
+		runVerify(args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s\n", cmd, usage)
+		os.Exit(2)
+	}
+}
+
+func runRoot(args []string) {
+	fs := flag.NewFlagSet("root", flag.ExitOnError)
+	leavesFile := fs.String("leaves", "", "path to the file with one leaf per line")
+	hashName := fs.String("hash", "sha256", "hash algorithm")
+	fs.Parse(args)
+
+	tree := buildTree(*leavesFile, hashFunc(*hashName))
+	fmt.Println(hex.EncodeToString(tree.RootHash()))
+}
+
+func runProve(args []string) {
+	fs := flag.NewFlagSet("prove", flag.ExitOnError)
+	leavesFile := fs.String("leaves", "", "path to the file with one leaf per line")
+	index := fs.Int("index", -1, "index of the leaf to prove")
+	hashName := fs.String("hash", "sha256", "hash algorithm")
+	fs.Parse(args)
+
+	tree := buildTree(*leavesFile, hashFunc(*hashName))
+	proof, err := tree.GenerateInclusionProof(*index)
+	if err != nil {
+		log.Fatalf("generate proof for leaf %d: %v", *index, err)
+	}
+
+	out, err := json.MarshalIndent(proof, "", "  ")
+	if err != nil {
+		log.Fatalf("encode proof: %v", err)
+	}
+	fmt.Println(string(out))
+}
+
+func runVerify(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	proofFile := fs.String("proof", "", "path to the JSON inclusion proof")
+	rootHex := fs.String("root", "", "trusted root hash (hex)")
+	leaf := fs.String("leaf", "", "leaf data")
+	hashName := fs.String("hash", "sha256", "hash algorithm")
+	fs.Parse(args)
+
+	if *proofFile == "" || *rootHex == "" || *leaf == "" {
+		log.Fatal("-proof, -root and -leaf are required")
+	}
+
+	raw, err := os.ReadFile(*proofFile)
+	if err != nil {
+		log.Fatalf("read proof file: %v", err)
+	}
+	var proof merkle.InclusionProof
+	if err := json.Unmarshal(raw, &proof); err != nil {
+		log.Fatalf("decode proof: %v", err)
+	}
+	root, err := hex.DecodeString(*rootHex)
+	if err != nil {
+		log.Fatalf("decode -root: %v", err)
+	}
+
+	if err := merkle.VerifyInclusionProofErr([]byte(*leaf), &proof, root, hashFunc(*hashName)); err != nil {
+		fmt.Printf("PROOF INVALID — %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("PROOF VALID — leaf is committed to this root")
+}
+
+func buildTree(path string, hashFunc hash.Func) *merkle.Tree {
+	if path == "" {
+		log.Fatal("-leaves is required")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("open leaves file: %v", err)
+	}
+	defer f.Close()
+
+	tree, err := merkle.NewTreeFromReader(f, hashFunc)
+	if err != nil {
+		log.Fatalf("build tree: %v", err)
+	}
+	return tree
+}
+
+func hashFunc(name string) hash.Func {
+	fn, err := hash.ByName(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return fn
+}
@@ -22,6 +22,7 @@ func main() {
 	// shared flags
 	mode := flag.String("mode", "inclusion", "verification mode: inclusion or consistency")
 	addr := flag.String("addr", "localhost:50051", "gRPC server address")
+	hashName := flag.String("hash", "sha3-256", "hash algorithm of the ledger, e.g. sha3-256 or sha256")
 
 	// inclusion flags
 	eventID := flag.String("event-id", "", "UUID of the audit event to verify")
@@ -36,6 +37,11 @@ func main() {
 	newRootB64 := flag.String("new-root", "", "trusted root hash at to-size (base64)")
 	flag.Parse()
 
+	hashFunc, err := hash.ByName(*hashName)
+	if err != nil {
+		log.Fatalf("-hash: %v", err)
+	}
+
 	conn, err := grpc.NewClient(*addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		log.Fatalf("dial %s: %v", *addr, err)
@@ -49,16 +55,16 @@ func main() {
 
 	switch *mode {
 	case "inclusion":
-		runInclusionProofVerification(ctx, client, *eventID, *payloadFile, *ledgerSize, *trustedRoot)
+		runInclusionProofVerification(ctx, client, hashFunc, *eventID, *payloadFile, *ledgerSize, *trustedRoot)
 	case "consistency":
-		runConsistencyProofVerification(ctx, client, *fromSize, *toSize, *oldRootB64, *newRootB64)
+		runConsistencyProofVerification(ctx, client, hashFunc, *fromSize, *toSize, *oldRootB64, *newRootB64)
 	default:
 		fmt.Printf("unknown mode %q — use inclusion or consistency\n", *mode)
 		os.Exit(1)
 	}
 }
 
-func runInclusionProofVerification(ctx context.Context, client auditv1.ProofServiceClient, eventID, payloadFile string, size int64, rootB64 string) {
+func runInclusionProofVerification(ctx context.Context, client auditv1.ProofServiceClient, hashFunc hash.Func, eventID, payloadFile string, size int64, rootB64 string) {
 	if eventID == "" || payloadFile == "" {
 		fmt.Printf("error: -event-id and -payload-file are required for inclusion mode")
 		flag.Usage()
@@ -92,8 +98,8 @@ func runInclusionProofVerification(ctx context.Context, client auditv1.ProofServ
 		}
 	}
 
-	valid := mmr.VerifyInclusionProof(canonical, proof, verifyRoot, hash.SHA3HashFunc)
-	canocicalHash := mmr.HashLeafData(canonical, hash.SHA3HashFunc)
+	valid := mmr.VerifyInclusionProof(canonical, proof, verifyRoot, hashFunc)
+	canocicalHash := mmr.HashLeafData(canonical, hashFunc)
 
 	fmt.Printf("event_id:    %s\n", resp.EventId)
 	fmt.Printf("leaf_index:  %d\n", resp.LeafIndex)
@@ -114,7 +120,7 @@ func runInclusionProofVerification(ctx context.Context, client auditv1.ProofServ
 	}
 }
 
-func runConsistencyProofVerification(ctx context.Context, client auditv1.ProofServiceClient, fromSize, toSize int64, oldRootB64, newRootB64 string) {
+func runConsistencyProofVerification(ctx context.Context, client auditv1.ProofServiceClient, hashFunc hash.Func, fromSize, toSize int64, oldRootB64, newRootB64 string) {
 	if fromSize <= 0 || toSize <= 0 || oldRootB64 == "" || newRootB64 == "" {
 		fmt.Printf("error: -from-size, -to-size, -old-root and -new-root are required for consistency mode")
 		flag.Usage()
@@ -150,7 +156,7 @@ func runConsistencyProofVerification(ctx context.Context, client auditv1.ProofSe
 		RightPeaks:       resp.Proof.RightPeaks,
 	}
 
-	valid := mmr.VerifyConsistencyProof(proof, oldRoot, newRoot, hash.SHA3HashFunc)
+	valid := mmr.VerifyConsistencyProof(proof, oldRoot, newRoot, hashFunc)
 
 	fmt.Printf("from_size:   %d\n", proof.OldSize)
 	fmt.Printf("to_size:     %d\n", proof.NewSize)